@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/auth"
+	"github.com/VladimirVereshchagin/scheduler/internal/config"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// commandServices - services available to command-line commands
+type commandServices struct {
+	Import services.ImportService
+	Export services.ExportService
+	Backup services.BackupService
+}
+
+// usage - list of the supported commands
+const usage = `usage: scheduler [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  import-ical [-dry-run] [-project ID] FILE   import tasks from an iCalendar file, "-" reads standard input
+  export [-format json|ndjson|csv|todotxt] [FILE]
+                                              export all tasks, to standard output without a file
+  import [-format json|ndjson|csv|todotxt|todoist|todoist-csv|trello] [-dry-run] [-project ID] [-column ATTRIBUTE=HEADER]... FILE
+                                              import tasks exported by another instance or application, "-" reads standard input
+  backup [FILE]                               back up the database while the server runs, into the backup directory
+                                              without a file, "-" writes to standard output
+  restore [-dry-run] FILE                     verify a backup and replace the database with it, "-" reads standard input;
+                                              the server must be stopped`
+
+// commandActor - actor recorded in the audit trail for changes made by commands
+const commandActor = "cli"
+
+// runCommand - runs the command given on the command line and prints its result to standard output
+func runCommand(args []string, svc commandServices) error {
+	switch args[0] {
+	case "import-ical":
+		return importICal(args[1:], svc.Import)
+	case "export":
+		return exportTasks(args[1:], svc.Export)
+	case "import":
+		return importTasks(args[1:], svc.Import)
+	case "backup":
+		return backupDatabase(args[1:], svc.Backup)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+// importICal - imports tasks from an iCalendar file and prints the import report as JSON
+func importICal(args []string, importService services.ImportService) error {
+	flags := flag.NewFlagSet("import-ical", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	projectID := flags.String("project", "", "ID of the project the tasks are added to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("import-ical expects a single file name")
+	}
+
+	r, err := openInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	report, err := importService.ImportICal(commandContext(), r, services.ImportOptions{
+		DryRun:    *dryRun,
+		ProjectID: *projectID,
+	})
+	if err != nil {
+		return err
+	}
+	return printReport(report)
+}
+
+// exportTasks - writes all tasks to a file, or to standard output without one
+func exportTasks(args []string, exportService services.ExportService) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", services.FormatJSON, "export format: json, ndjson, csv or todotxt")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("export expects at most one file name")
+	}
+
+	if flags.NArg() == 0 || flags.Arg(0) == "-" {
+		return exportService.Export(os.Stdout, *format)
+	}
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := exportService.Export(file, *format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// importTasks - imports tasks exported by another instance or application and prints the import report as JSON
+func importTasks(args []string, importService services.ImportService) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", services.FormatJSON, "import format: json, ndjson, csv, todotxt, todoist, todoist-csv or trello")
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	projectID := flags.String("project", "", "ID of the project the tasks are added to")
+	columns := make(columnFlag)
+	flags.Var(columns, "column", "CSV column of a task attribute as ATTRIBUTE=HEADER, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("import expects a single file name")
+	}
+
+	r, err := openInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	report, err := importService.ImportTasks(commandContext(), r, *format, services.ImportOptions{
+		DryRun:    *dryRun,
+		ProjectID: *projectID,
+		Columns:   columns,
+	})
+	if err != nil {
+		return err
+	}
+	return printReport(report)
+}
+
+// backupDatabase - writes a backup to a file or standard output, or stores it in the backup directory without a file
+func backupDatabase(args []string, backupService services.BackupService) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case flags.NArg() > 1:
+		return errors.New("backup expects at most one file name")
+	case flags.NArg() == 0:
+		info, err := backupService.Backup()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Backed up the database to", info.Name)
+		return nil
+	case flags.Arg(0) == "-":
+		return backupService.Write(os.Stdout)
+	}
+
+	// A failed backup must not leave a file that looks like one
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := backupService.Write(file); err != nil {
+		file.Close()
+		os.Remove(flags.Arg(0))
+		return err
+	}
+	return file.Close()
+}
+
+// restoreBackup - replaces the database with a verified backup, or only verifies it with -dry-run
+func restoreBackup(args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only verify the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("restore expects a single file name")
+	}
+
+	r, err := openInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := services.RestoreBackup(cfg.DBFile, r, cfg.BackupKey, *dryRun); err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Println("The backup is valid")
+	} else {
+		fmt.Println("Restored the database from", flags.Arg(0))
+	}
+	return nil
+}
+
+// columnFlag - CSV column mapping collected from repeated -column flags
+type columnFlag map[string]string
+
+// String - returns the mapping in flag syntax
+func (c columnFlag) String() string {
+	pairs := make([]string, 0, len(c))
+	for attribute, header := range c {
+		pairs = append(pairs, attribute+"="+header)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set - adds a single ATTRIBUTE=HEADER pair
+func (c columnFlag) Set(value string) error {
+	attribute, header, ok := strings.Cut(value, "=")
+	if !ok || attribute == "" || header == "" {
+		return errors.New("expected ATTRIBUTE=HEADER")
+	}
+	c[attribute] = header
+	return nil
+}
+
+// openInput - opens the named file, or standard input for "-"
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// commandContext - context carrying the actor of command-line changes
+func commandContext() context.Context {
+	return auth.WithActor(context.Background(), auth.Actor{Name: commandActor})
+}
+
+// printReport - prints an import report as indented JSON
+func printReport(report *services.ImportReport) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
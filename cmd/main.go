@@ -3,8 +3,10 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/VladimirVereshchagin/scheduler/internal/app"
+	"github.com/VladimirVereshchagin/scheduler/internal/backup"
 	"github.com/VladimirVereshchagin/scheduler/internal/config"
 	"github.com/VladimirVereshchagin/scheduler/internal/repository"
 	"github.com/VladimirVereshchagin/scheduler/internal/services"
@@ -16,8 +18,19 @@ func main() {
 	// Loading configuration
 	cfg := config.LoadConfig()
 
+	// Restoring a backup replaces the database file, so it has to happen before the database is opened
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		if err := restoreBackup(os.Args[2:], cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initializing the database
-	db, err := repository.NewDB(cfg.DBFile)
+	db, err := repository.NewDB(cfg.DBFile, repository.DBOptions{
+		BusyTimeout: cfg.DBBusyTimeout,
+		MaxReaders:  cfg.DBMaxReaders,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -25,10 +38,110 @@ func main() {
 
 	// Initializing repositories and services
 	taskRepo := repository.NewTaskRepository(db)
-	taskService := services.NewTaskService(taskRepo)
+	completionRepo := repository.NewCompletionRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	checklistRepo := repository.NewChecklistRepository(db)
+	dependencyRepo := repository.NewDependencyRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	fieldRepo := repository.NewFieldRepository(db)
+	noteRepo := repository.NewNoteRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	externalIDRepo := repository.NewExternalIDRepository(db)
+	attachmentStore := repository.NewBlobStore(db)
+	if cfg.AttachmentStorage == config.AttachmentStorageFS {
+		attachmentStore, err = repository.NewFileStore(cfg.AttachmentDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	transactor := repository.NewTransactor(db)
+
+	attachmentService := services.NewAttachmentService(taskRepo, attachmentRepo, attachmentStore, services.AttachmentLimits{
+		MaxSize: cfg.AttachmentMaxSize,
+		Types:   cfg.AttachmentTypes,
+	})
+	taskOptions := services.TaskOptions{DeleteOnDone: cfg.DeleteOnDone}
+	taskService := services.NewAttachmentCleaningTaskService(
+		services.NewTaskService(taskRepo, completionRepo, auditRepo, projectRepo, checklistRepo, dependencyRepo, fieldRepo, transactor, taskOptions),
+		attachmentService,
+	)
+	auditService := services.NewAuditService(auditRepo)
+	batchService := services.NewBatchService(transactor, taskOptions)
+	projectService := services.NewProjectService(projectRepo, transactor)
+	checklistService := services.NewChecklistService(taskRepo, checklistRepo)
+	dependencyService := services.NewDependencyService(taskRepo, dependencyRepo)
+	fieldService := services.NewFieldService(fieldRepo)
+	noteService := services.NewNoteService(taskRepo, noteRepo)
+	templateService := services.NewTemplateService(templateRepo, transactor, taskOptions)
+	quickAddService := services.NewQuickAddService(taskService)
+	calendarService := services.NewCalendarService(taskRepo)
+	importService := services.NewImportService(transactor, taskOptions, projectService, dependencyService, externalIDRepo)
+	exportService := services.NewExportService(taskRepo, projectRepo, checklistRepo, noteRepo, dependencyRepo, fieldRepo)
+	calDAVService := services.NewCalDAVService(taskService, externalIDRepo)
+	backupService := services.NewBackupService(repository.NewBackupRepository(db), services.BackupOptions{
+		Dir:     cfg.BackupDir,
+		Keep:    cfg.BackupKeep,
+		Options: backup.Options{Compress: cfg.BackupCompress, Key: cfg.BackupKey},
+	})
+
+	// Running a command instead of the server, e.g. "scheduler import-ical tasks.ics"
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], commandServices{Import: importService, Export: exportService, Backup: backupService}); err != nil {
+			db.Close()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Purging expired tasks from the trash
+	if cfg.TrashRetention > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		services.StartTrashCleanup(taskService, cfg.TrashRetention, stop)
+	}
+
+	// Syncing the tasks with a todo.txt file
+	if cfg.TodoTxtFile != "" {
+		stop := make(chan struct{})
+		defer close(stop)
+		services.StartTodoTxtSync(services.NewTodoTxtSync(cfg.TodoTxtFile, taskService, taskRepo), cfg.TodoTxtSyncInterval, stop)
+	}
+
+	// Syncing the tasks with a directory of Markdown files
+	if cfg.VaultDir != "" {
+		stop := make(chan struct{})
+		defer close(stop)
+		vault := services.NewVaultSync(cfg.VaultDir, cfg.VaultInbox, taskService, taskRepo, externalIDRepo)
+		services.StartVaultSync(vault, cfg.VaultSyncInterval, stop)
+	}
+
+	// Backing up the database on schedule
+	if cfg.BackupDir != "" && cfg.BackupInterval > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		services.StartBackups(backupService, cfg.BackupInterval, stop)
+	}
 
 	// Initializing the application
-	application := app.NewApp(taskService, cfg)
+	application := app.NewApp(app.Services{
+		Tasks:        taskService,
+		Audit:        auditService,
+		Batch:        batchService,
+		Projects:     projectService,
+		Checklists:   checklistService,
+		Dependencies: dependencyService,
+		Attachments:  attachmentService,
+		Fields:       fieldService,
+		Notes:        noteService,
+		Templates:    templateService,
+		QuickAdd:     quickAddService,
+		Calendar:     calendarService,
+		Import:       importService,
+		Export:       exportService,
+		CalDAV:       calDAVService,
+		Backup:       backupService,
+	}, cfg)
 
 	// Starting the server
 	log.Printf("Starting server on port %s...\n", cfg.Port)
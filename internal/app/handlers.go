@@ -2,11 +2,18 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/VladimirVereshchagin/scheduler/internal/auth"
 	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
 )
 
 const defaultLimit = 50 // Default limit value
@@ -23,6 +30,84 @@ func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
 	}
 }
 
+// writeJSON sends a response in JSON format
+func writeJSON(w http.ResponseWriter, response any) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(response); err != nil {
+		log.Println("Error encoding JSON:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error encoding JSON")
+	}
+}
+
+// errorStatus chooses the response status for a service error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrProjectNotFound),
+		errors.Is(err, repository.ErrChecklistItemNotFound), errors.Is(err, repository.ErrAttachmentNotFound),
+		errors.Is(err, repository.ErrFieldNotFound), errors.Is(err, repository.ErrNoteNotFound),
+		errors.Is(err, repository.ErrTemplateNotFound), errors.Is(err, services.ErrBackupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrProjectExists), errors.Is(err, services.ErrProjectNotEmpty),
+		errors.Is(err, repository.ErrDependencyCycle), errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, repository.ErrFieldExists), errors.Is(err, repository.ErrTemplateExists):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// etag formats a task version as an entity tag
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersion extracts the expected task version from the If-Match header.
+// The second value reports whether the header was sent; "*" means any version.
+func ifMatchVersion(r *http.Request) (int64, bool, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	if header == "*" {
+		return 0, true, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, true, errors.New("invalid If-Match header")
+	}
+	return version, true, nil
+}
+
+// writeVersionConflict reports a failed conditional write together with the current task state.
+// Requests that sent If-Match get 412 Precondition Failed, others get 409 Conflict.
+func (a *App) writeVersionConflict(w http.ResponseWriter, id string, conditional bool) {
+	task, err := a.TaskService.GetTaskByID(id)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "Task not found")
+		return
+	}
+
+	statusCode := http.StatusConflict
+	if conditional {
+		statusCode = http.StatusPreconditionFailed
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("ETag", etag(task.Version))
+	w.WriteHeader(statusCode)
+	response := map[string]any{
+		"error": repository.ErrVersionConflict.Error(),
+		"task":  task,
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(response); err != nil {
+		log.Println("Error encoding JSON:", err)
+	}
+}
+
 // handleTask routes requests to the corresponding handlers depending on the method
 func (a *App) handleTask(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -58,7 +143,7 @@ func (a *App) addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := a.TaskService.CreateTask(&task)
+	id, err := a.TaskService.CreateTask(r.Context(), &task)
 	if err != nil {
 		log.Println("Error creating task:", err)
 		writeJSONError(w, http.StatusBadRequest, err.Error())
@@ -93,6 +178,7 @@ func (a *App) getTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etag(task.Version))
 	encoder := json.NewEncoder(w)
 	encoder.SetEscapeHTML(false)
 	if err := encoder.Encode(task); err != nil {
@@ -120,12 +206,25 @@ func (a *App) editTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.TaskService.UpdateTask(&task); err != nil {
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	task.Version = version
+
+	if err := a.TaskService.UpdateTask(r.Context(), &task); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			a.writeVersionConflict(w, task.ID, conditional)
+			return
+		}
 		log.Println("Error updating task:", err)
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag(task.Version))
+
 	response := map[string]string{
 		"message": "Task updated successfully",
 	}
@@ -147,7 +246,17 @@ func (a *App) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.TaskService.DeleteTask(id); err != nil {
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+
+	if err := a.TaskService.DeleteTask(r.Context(), id, version); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			a.writeVersionConflict(w, id, conditional)
+			return
+		}
 		log.Println("Error deleting task:", err)
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
@@ -168,10 +277,18 @@ func (a *App) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 func (a *App) handleTasks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	search := r.URL.Query().Get("search")
-	limit := defaultLimit
+	filter, err := taskFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	tasks, err := a.TaskService.ListTasks(search, limit)
+	tasks, err := a.TaskService.ListTasks(filter)
+	if errors.Is(err, services.ErrInvalidTag) || errors.Is(err, services.ErrInvalidStatus) ||
+		errors.Is(err, services.ErrInvalidField) || errors.Is(err, repository.ErrInvalidSort) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		log.Println("Error getting task list:", err)
 		writeJSONError(w, http.StatusInternalServerError, "Error getting task list")
@@ -194,6 +311,104 @@ func (a *App) handleTasks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// taskFilter reads the task list filters from the query string.
+// "project" limits the list to a project ("none" for tasks outside any project).
+// Tags are given as repeated or comma-separated "tag" parameters, combined with "tag_mode=or" (default) or "tag_mode=and".
+// "status" lists the statuses to show, comma-separated or "all"; open tasks are shown by default.
+// "sort" selects the order (date, priority, title or created) and "order" its direction (asc or desc).
+func taskFilter(r *http.Request) (repository.TaskFilter, error) {
+	query := r.URL.Query()
+	filter := repository.TaskFilter{
+		Search:    query.Get("search"),
+		ProjectID: query.Get("project"),
+		Sort:      query.Get("sort"),
+		Limit:     defaultLimit,
+	}
+
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	switch query.Get("tag_mode") {
+	case "", "or":
+	case "and":
+		filter.AllTags = true
+	default:
+		return filter, errors.New("invalid tag_mode, expected 'and' or 'or'")
+	}
+
+	switch status := query.Get("status"); status {
+	case "":
+	case "all":
+		filter.Statuses = []string{models.StatusTodo, models.StatusInProgress, models.StatusDone, models.StatusCancelled}
+	default:
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				filter.Statuses = append(filter.Statuses, s)
+			}
+		}
+	}
+
+	// Custom field filters are given as "field.<name>=<value>"
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, repository.SortFieldPrefix)
+		if !ok || name == "" || values[0] == "" {
+			continue
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[string]string)
+		}
+		filter.Fields[name] = values[0]
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, errors.New("invalid order, expected 'asc' or 'desc'")
+	}
+
+	return filter, nil
+}
+
+// readDoneOptions reads the optional completion details from the request body.
+// The completion time may be backdated and is given as "20060102" or in RFC 3339 format.
+func readDoneOptions(r *http.Request) (services.DoneOptions, error) {
+	var opts services.DoneOptions
+	if r.Body == nil {
+		return opts, nil
+	}
+	defer r.Body.Close()
+
+	var body struct {
+		CompletedAt string `json:"completed_at"`
+		Note        string `json:"note"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return opts, nil
+		}
+		return opts, errors.New("invalid completion details")
+	}
+
+	opts.Note = body.Note
+	if body.CompletedAt != "" {
+		completedAt, err := parseCompletionTime(body.CompletedAt)
+		if err != nil {
+			return opts, err
+		}
+		opts.CompletedAt = completedAt
+	}
+	return opts, nil
+}
+
 // handleDoneTask handles marking a task as done
 func (a *App) handleDoneTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -209,15 +424,38 @@ func (a *App) handleDoneTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.TaskService.MarkTaskDone(id); err != nil {
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+
+	opts, err := readDoneOptions(r)
+	if err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.Version = version
+
+	unblocked, err := a.TaskService.MarkTaskDone(r.Context(), id, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			a.writeVersionConflict(w, id, conditional)
+			return
+		}
 		log.Println("Error marking task as done:", err)
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	response := map[string]string{
+	response := map[string]any{
 		"message": "Task marked as done",
 	}
+	// Tasks waiting for this one are listed only when completing it unblocked some
+	if len(unblocked) > 0 {
+		response["unblocked"] = unblocked
+	}
 	encoder := json.NewEncoder(w)
 	encoder.SetEscapeHTML(false)
 	if err := encoder.Encode(response); err != nil {
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// multipartOverhead - room for the multipart headers and boundaries around an uploaded file
+const multipartOverhead = 64 << 10
+
+// handleAttachments routes attachment requests to the corresponding handlers depending on the method
+func (a *App) handleAttachments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listAttachmentsHandler(w, r)
+	case http.MethodPost:
+		a.uploadAttachmentHandler(w, r)
+	case http.MethodDelete:
+		a.deleteAttachmentHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listAttachmentsHandler handles getting the attachments of a task
+func (a *App) listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	attachments, err := a.AttachmentService.List(r.URL.Query().Get("id"))
+	if err != nil {
+		log.Println("Error getting attachments:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	if attachments == nil {
+		attachments = []*models.Attachment{}
+	}
+	writeJSON(w, map[string]any{"attachments": attachments})
+}
+
+// uploadAttachmentHandler handles attaching a file sent as the "file" field of a multipart form
+func (a *App) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	r.Body = http.MaxBytesReader(w, r.Body, a.AttachmentService.Limits().MaxSize+multipartOverhead)
+	defer r.Body.Close()
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Expected a multipart form")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeJSONError(w, http.StatusBadRequest, "The 'file' field is required")
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error())
+			return
+		}
+		if err != nil {
+			log.Println("Error reading multipart form:", err)
+			writeJSONError(w, http.StatusBadRequest, "Error reading multipart form")
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		attachment, err := a.AttachmentService.Upload(r.URL.Query().Get("id"), part.FileName(), part)
+		if err != nil {
+			log.Println("Error uploading attachment:", err)
+			writeJSONError(w, attachmentErrorStatus(err), err.Error())
+			return
+		}
+
+		writeJSON(w, attachment)
+		return
+	}
+}
+
+// deleteAttachmentHandler handles deleting an attachment
+func (a *App) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := a.AttachmentService.Delete(r.URL.Query().Get("attachment")); err != nil {
+		log.Println("Error deleting attachment:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Attachment deleted successfully"})
+}
+
+// handleDownloadAttachment handles downloading the contents of an attachment
+func (a *App) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	attachment, content, err := a.AttachmentService.Open(r.URL.Query().Get("attachment"))
+	if err != nil {
+		log.Println("Error opening attachment:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		log.Println("Error sending attachment:", err)
+	}
+}
+
+// attachmentErrorStatus chooses the response status for an upload error
+func attachmentErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrAttachmentTooLarge), errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrAttachmentType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return errorStatus(err)
+	}
+}
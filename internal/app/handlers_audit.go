@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// auditQuery reads the audit trail filters from the query string
+func auditQuery(r *http.Request) (services.AuditQuery, error) {
+	values := r.URL.Query()
+	query := services.AuditQuery{
+		TaskID: values.Get("task_id"),
+		Action: values.Get("action"),
+		Actor:  values.Get("actor"),
+		From:   values.Get("from"),
+		To:     values.Get("to"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = n
+	}
+	return query, nil
+}
+
+// handleAuditLog handles querying the audit trail
+func (a *App) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query, err := auditQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultLimit
+	}
+
+	entries := []*models.AuditEntry{}
+	err = a.AuditService.EachEntry(query, func(entry *models.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		log.Println("Error reading the audit trail:", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{"entries": entries})
+}
+
+// handleAuditExport handles exporting the audit trail as newline-delimited JSON
+func (a *App) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query, err := auditQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Headers are sent with the first entry, so that invalid filters can still be reported as JSON errors
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	err = a.AuditService.EachEntry(query, func(entry *models.AuditEntry) error {
+		start()
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		log.Println("Error exporting the audit trail:", err)
+		if !started {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	start()
+}
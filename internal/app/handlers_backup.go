@@ -0,0 +1,90 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// startedWriter records whether anything has been written to the response
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// Write marks the response as started
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// handleBackup handles downloading a backup of the database.
+// Without "name" a new backup is made and streamed; with it the stored backup of that name is downloaded.
+func (a *App) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if name := r.URL.Query().Get("name"); name != "" {
+		file, info, err := a.BackupService.Open(name)
+		if err != nil {
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+info.Name+`"`)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		if _, err := io.Copy(w, file); err != nil {
+			log.Println("Error sending a backup:", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+a.BackupService.FileName()+`"`)
+	response := &startedWriter{ResponseWriter: w}
+	if err := a.BackupService.Write(response); err != nil {
+		log.Println("Error backing up the database:", err)
+		// Once the backup is being sent the error can only be logged
+		if !response.started {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// handleBackups handles listing the stored backups and storing a new one
+func (a *App) handleBackups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	switch r.Method {
+	case http.MethodGet:
+		backups, err := a.BackupService.List()
+		if err != nil {
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]any{"backups": backups})
+	case http.MethodPost:
+		info, err := a.BackupService.Backup()
+		if err != nil {
+			log.Println("Error backing up the database:", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, services.ErrBackupsDisabled) {
+				status = http.StatusBadRequest
+			}
+			writeJSONError(w, status, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, info)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
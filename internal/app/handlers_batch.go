@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// Batch execution modes
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+// batchRequest - body of a batch request
+type batchRequest struct {
+	Mode       string `json:"mode"` // atomic (default) or best_effort
+	Operations []struct {
+		Op          string       `json:"op"`
+		ID          string       `json:"id"`
+		Version     int64        `json:"version"`
+		Task        *models.Task `json:"task"`
+		CompletedAt string       `json:"completed_at"`
+		Note        string       `json:"note"`
+	} `json:"operations"`
+}
+
+// handleBatch handles running several task operations in one transaction
+func (a *App) handleBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req batchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	atomic := true
+	switch req.Mode {
+	case "", batchModeAtomic:
+	case batchModeBestEffort:
+		atomic = false
+	default:
+		writeJSONError(w, http.StatusBadRequest, "Unknown batch mode")
+		return
+	}
+
+	ops := make([]services.BatchOperation, 0, len(req.Operations))
+	for _, item := range req.Operations {
+		op := services.BatchOperation{
+			Op:      item.Op,
+			ID:      item.ID,
+			Version: item.Version,
+			Task:    item.Task,
+			Done:    services.DoneOptions{Note: item.Note},
+		}
+		if item.CompletedAt != "" {
+			completedAt, err := parseCompletionTime(item.CompletedAt)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			op.Done.CompletedAt = completedAt
+		}
+		ops = append(ops, op)
+	}
+
+	results, err := a.BatchService.Execute(r.Context(), ops, atomic)
+	if errors.Is(err, services.ErrBatchAborted) {
+		w.WriteHeader(http.StatusConflict)
+		writeJSON(w, map[string]any{
+			"error":     err.Error(),
+			"committed": false,
+			"results":   results,
+		})
+		return
+	}
+	if err != nil {
+		log.Println("Error running batch:", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"committed": true,
+		"results":   results,
+	})
+}
+
+// parseCompletionTime parses a completion time given as "20060102" or in RFC 3339 format
+func parseCompletionTime(value string) (time.Time, error) {
+	completedAt, err := time.Parse("20060102", value)
+	if err != nil {
+		completedAt, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return time.Time{}, errors.New("invalid completion time")
+	}
+	return completedAt, nil
+}
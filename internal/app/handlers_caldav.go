@@ -0,0 +1,529 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// XML namespaces of WebDAV, CalDAV and the calendar server extensions
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// CalDAV paths: the principal, its calendar home and the task collection inside it
+const (
+	davRoot       = "/dav/"
+	davHome       = "/dav/calendars/"
+	davCollection = "/dav/calendars/tasks/"
+)
+
+// maxDAVBodySize is the maximum size of a CalDAV request body
+const maxDAVBodySize = 1 << 20
+
+// davAllow lists the methods supported by the CalDAV endpoints
+const davAllow = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+
+// davPrefixes - prefixes of the namespaces declared in responses
+var davPrefixes = map[string]string{nsDAV: "D", nsCalDAV: "C", nsCS: "CS"}
+
+// davEscaper - replacements escaping text in XML content and attribute values
+var davEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\r", "&#13;")
+
+// calendarDataProp - the calendar-data property, returned only when requested
+var calendarDataProp = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+
+// davRequest - body of a PROPFIND or REPORT request
+type davRequest struct {
+	XMLName  xml.Name
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *struct {
+		Names []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"DAV: prop"`
+	Hrefs  []string `xml:"DAV: href"`
+	Filter *struct {
+		Filters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// davCompFilter - component filter of a calendar-query report
+type davCompFilter struct {
+	Name    string          `xml:"name,attr"`
+	Filters []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// davProps - property values of a resource as XML fragments, keyed by property name
+type davProps map[xml.Name]string
+
+// davResponse - a single response of a multi-status body
+type davResponse struct {
+	href    string
+	found   davProps
+	missing []xml.Name
+	status  int // Status of a response without properties, e.g. a missing resource in a multiget report
+}
+
+// handleDAVWellKnown handles CalDAV service discovery by redirecting to the principal
+func (a *App) handleDAVWellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, davRoot, http.StatusMovedPermanently)
+}
+
+// handleDAV handles the CalDAV server exposing tasks as a calendar collection of VTODO resources
+func (a *App) handleDAV(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	path := r.URL.Path
+	if !strings.HasSuffix(path, "/") && !strings.HasSuffix(path, ".ics") {
+		path += "/"
+	}
+
+	name, isObject := strings.CutPrefix(path, davCollection)
+	name, isObject = strings.CutSuffix(name, ".ics")
+	isObject = isObject && name != "" && !strings.Contains(name, "/")
+
+	switch {
+	case isObject:
+		a.handleDAVObject(w, r, name)
+	case path == davRoot || path == davHome || path == davCollection:
+		a.handleDAVCollection(w, r, path)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleDAVCollection handles requests to the principal, the calendar home and the task collection
+func (a *App) handleDAVCollection(w http.ResponseWriter, r *http.Request, path string) {
+	switch r.Method {
+	case http.MethodOptions:
+		writeDAVOptions(w)
+	case "PROPFIND":
+		req, ok := readDAVRequest(w, r)
+		if !ok {
+			return
+		}
+		depth := r.Header.Get("Depth")
+
+		var responses []davResponse
+		switch path {
+		case davRoot:
+			responses = append(responses, davPropResponse(davRoot, principalProps(), req))
+			if depth != "0" {
+				responses = append(responses, davPropResponse(davHome, homeProps(), req))
+			}
+		case davHome:
+			responses = append(responses, davPropResponse(davHome, homeProps(), req))
+			if depth != "0" {
+				resources, err := a.CalDAVService.List()
+				if err != nil {
+					writeDAVError(w, err)
+					return
+				}
+				responses = append(responses, davPropResponse(davCollection, a.collectionProps(resources), req))
+			}
+		case davCollection:
+			resources, err := a.CalDAVService.List()
+			if err != nil {
+				writeDAVError(w, err)
+				return
+			}
+			responses = append(responses, davPropResponse(davCollection, a.collectionProps(resources), req))
+			if depth != "0" {
+				for _, resource := range resources {
+					responses = append(responses, a.objectResponse(resource, req))
+				}
+			}
+		}
+		writeMultiStatus(w, responses)
+	case "REPORT":
+		if path != davCollection {
+			http.Error(w, "Reports are supported on the task collection only", http.StatusForbidden)
+			return
+		}
+		a.handleDAVReport(w, r)
+	default:
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDAVReport handles the calendar-query and calendar-multiget reports of the task collection
+func (a *App) handleDAVReport(w http.ResponseWriter, r *http.Request) {
+	req, ok := readDAVRequest(w, r)
+	if !ok {
+		return
+	}
+	if req == nil {
+		http.Error(w, "Report body is required", http.StatusBadRequest)
+		return
+	}
+
+	var responses []davResponse
+	switch req.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		// Only the component type is filtered on; clients narrow other filters down themselves
+		if req.Filter != nil && !matchesTodo(req.Filter.Filters) {
+			break
+		}
+		resources, err := a.CalDAVService.List()
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		for _, resource := range resources {
+			responses = append(responses, a.objectResponse(resource, req))
+		}
+
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			href = strings.TrimSpace(href)
+			resource, err := a.CalDAVService.Get(davObjectName(href))
+			if err != nil {
+				status := davStatus(err)
+				if status != http.StatusNotFound {
+					writeDAVError(w, err)
+					return
+				}
+				responses = append(responses, davResponse{href: href, status: status})
+				continue
+			}
+			responses = append(responses, a.objectResponse(resource, req))
+		}
+
+	default:
+		http.Error(w, "Unsupported report", http.StatusForbidden)
+		return
+	}
+	writeMultiStatus(w, responses)
+}
+
+// handleDAVObject handles requests to a single task resource
+func (a *App) handleDAVObject(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodOptions:
+		writeDAVOptions(w)
+
+	case http.MethodGet, http.MethodHead:
+		resource, err := a.CalDAVService.Get(name)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := a.CalDAVService.Render(&buf, resource); err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", resource.ETag())
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Println("Error writing calendar object:", err)
+		}
+
+	case http.MethodPut:
+		// No ETag is returned, since the stored object differs from the one sent and clients have to fetch it again
+		body := http.MaxBytesReader(w, r.Body, maxDAVBodySize)
+		_, created, err := a.CalDAVService.Put(r.Context(), name, body, davPreconditions(r))
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	case http.MethodDelete:
+		if err := a.CalDAVService.Delete(r.Context(), name, davPreconditions(r)); err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case "PROPFIND":
+		req, ok := readDAVRequest(w, r)
+		if !ok {
+			return
+		}
+		resource, err := a.CalDAVService.Get(name)
+		if err != nil {
+			writeDAVError(w, err)
+			return
+		}
+		writeMultiStatus(w, []davResponse{a.objectResponse(resource, req)})
+
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// principalProps - properties of the principal
+func principalProps() davProps {
+	return davProps{
+		{Space: nsDAV, Local: "resourcetype"}:           "<D:collection/><D:principal/>",
+		{Space: nsDAV, Local: "displayname"}:            "Scheduler",
+		{Space: nsDAV, Local: "current-user-principal"}: "<D:href>" + davRoot + "</D:href>",
+		{Space: nsDAV, Local: "principal-URL"}:          "<D:href>" + davRoot + "</D:href>",
+		{Space: nsCalDAV, Local: "calendar-home-set"}:   "<D:href>" + davHome + "</D:href>",
+	}
+}
+
+// homeProps - properties of the calendar home
+func homeProps() davProps {
+	return davProps{
+		{Space: nsDAV, Local: "resourcetype"}:           "<D:collection/>",
+		{Space: nsDAV, Local: "displayname"}:            "Calendars",
+		{Space: nsDAV, Local: "current-user-principal"}: "<D:href>" + davRoot + "</D:href>",
+	}
+}
+
+// collectionProps - properties of the task collection
+func (a *App) collectionProps(resources []*services.DAVResource) davProps {
+	return davProps{
+		{Space: nsDAV, Local: "resourcetype"}:           "<D:collection/><C:calendar/>",
+		{Space: nsDAV, Local: "displayname"}:            "Tasks",
+		{Space: nsDAV, Local: "current-user-principal"}: "<D:href>" + davRoot + "</D:href>",
+		{Space: nsDAV, Local: "owner"}:                  "<D:href>" + davRoot + "</D:href>",
+		{Space: nsDAV, Local: "supported-report-set"}: "<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>" +
+			"<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>",
+		{Space: nsDAV, Local: "current-user-privilege-set"}: "<D:privilege><D:read/></D:privilege><D:privilege><D:write/></D:privilege>" +
+			"<D:privilege><D:write-content/></D:privilege><D:privilege><D:bind/></D:privilege><D:privilege><D:unbind/></D:privilege>",
+		{Space: nsCalDAV, Local: "supported-calendar-component-set"}: `<C:comp name="VTODO"/>`,
+		{Space: nsCS, Local: "getctag"}:                              davText(a.CalDAVService.CTag(resources)),
+	}
+}
+
+// objectResponse renders the requested properties of a task resource
+func (a *App) objectResponse(resource *services.DAVResource, req *davRequest) davResponse {
+	var buf bytes.Buffer
+	if err := a.CalDAVService.Render(&buf, resource); err != nil {
+		log.Println("Error rendering calendar object:", err)
+		return davResponse{href: davObjectHref(resource.Name), status: http.StatusInternalServerError}
+	}
+
+	props := davProps{
+		{Space: nsDAV, Local: "resourcetype"}:     "",
+		{Space: nsDAV, Local: "getetag"}:          davText(resource.ETag()),
+		{Space: nsDAV, Local: "getcontenttype"}:   "text/calendar; charset=utf-8; component=VTODO",
+		{Space: nsDAV, Local: "getcontentlength"}: fmt.Sprint(buf.Len()),
+	}
+	response := davPropResponse(davObjectHref(resource.Name), props, req)
+
+	// The content is not part of allprop and is only sent when asked for
+	if req != nil && req.Prop != nil {
+		for i, name := range response.missing {
+			if name == calendarDataProp {
+				response.found[name] = davText(buf.String())
+				response.missing = append(response.missing[:i], response.missing[i+1:]...)
+				break
+			}
+		}
+	}
+	return response
+}
+
+// davPropResponse selects the requested properties; without a request body all of them are returned
+func davPropResponse(href string, props davProps, req *davRequest) davResponse {
+	response := davResponse{href: href, found: davProps{}}
+	switch {
+	case req == nil || req.AllProp != nil || (req.Prop == nil && req.PropName == nil):
+		response.found = props
+	case req.PropName != nil:
+		for name := range props {
+			response.found[name] = ""
+		}
+	default:
+		for _, p := range req.Prop.Names {
+			if value, ok := props[p.XMLName]; ok {
+				response.found[p.XMLName] = value
+			} else {
+				response.missing = append(response.missing, p.XMLName)
+			}
+		}
+	}
+	return response
+}
+
+// matchesTodo reports whether a calendar-query filter can match VTODO components
+func matchesTodo(filters []davCompFilter) bool {
+	for _, calendar := range filters {
+		if calendar.Name != "VCALENDAR" {
+			continue
+		}
+		if len(calendar.Filters) == 0 {
+			return true
+		}
+		for _, component := range calendar.Filters {
+			if component.Name == "VTODO" {
+				return true
+			}
+		}
+	}
+	return len(filters) == 0
+}
+
+// readDAVRequest reads the XML body of a PROPFIND or REPORT request; an empty body gives a nil request
+func readDAVRequest(w http.ResponseWriter, r *http.Request) (*davRequest, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDAVBodySize))
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return nil, false
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, true
+	}
+
+	var req davRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// davPreconditions reads the conditional headers of a write
+func davPreconditions(r *http.Request) services.DAVPreconditions {
+	return services.DAVPreconditions{
+		IfMatch:     strings.TrimPrefix(strings.TrimSpace(r.Header.Get("If-Match")), "W/"),
+		IfNoneMatch: strings.TrimSpace(r.Header.Get("If-None-Match")) == "*",
+	}
+}
+
+// davObjectHref returns the path of a task resource
+func davObjectHref(name string) string {
+	return davCollection + url.PathEscape(name) + ".ics"
+}
+
+// davObjectName extracts the resource name from a path or URL of a task resource
+func davObjectName(href string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	name, ok := strings.CutPrefix(href, davCollection)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(name, ".ics")
+}
+
+// davStatus maps an error to the HTTP status of a CalDAV response
+func davStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, services.ErrInvalidTodo):
+		return http.StatusForbidden
+	}
+	return errorStatus(err)
+}
+
+// writeDAVError writes an error response; objects that cannot be stored are reported with the CalDAV precondition
+func writeDAVError(w http.ResponseWriter, err error) {
+	status := davStatus(err)
+	if status == http.StatusBadRequest {
+		log.Println("CalDAV request failed:", err)
+	}
+	if !errors.Is(err, services.ErrInvalidTodo) {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+		`<D:error xmlns:D="DAV:" xmlns:C="%s"><C:valid-calendar-object-resource/><D:responsedescription>%s</D:responsedescription></D:error>`,
+		nsCalDAV, davText(err.Error()))
+}
+
+// writeDAVOptions advertises the supported methods and CalDAV compliance
+func writeDAVOptions(w http.ResponseWriter) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", davAllow)
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeMultiStatus writes a 207 Multi-Status response
+func writeMultiStatus(w http.ResponseWriter, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<D:multistatus xmlns:D="DAV:" xmlns:C="` + nsCalDAV + `" xmlns:CS="` + nsCS + `">`)
+	for _, response := range responses {
+		b.WriteString("<D:response><D:href>" + davText(response.href) + "</D:href>")
+		if response.status != 0 {
+			b.WriteString("<D:status>" + davStatusLine(response.status) + "</D:status>")
+		}
+		if len(response.found) > 0 {
+			names := make([]xml.Name, 0, len(response.found))
+			for name := range response.found {
+				names = append(names, name)
+			}
+			// Sorted for stable output
+			sort.Slice(names, func(i, j int) bool {
+				return names[i].Space+names[i].Local < names[j].Space+names[j].Local
+			})
+
+			b.WriteString("<D:propstat><D:prop>")
+			for _, name := range names {
+				b.WriteString(davElement(name, response.found[name]))
+			}
+			b.WriteString("</D:prop><D:status>" + davStatusLine(http.StatusOK) + "</D:status></D:propstat>")
+		}
+		if len(response.missing) > 0 {
+			b.WriteString("<D:propstat><D:prop>")
+			for _, name := range response.missing {
+				b.WriteString(davElement(name, ""))
+			}
+			b.WriteString("</D:prop><D:status>" + davStatusLine(http.StatusNotFound) + "</D:status></D:propstat>")
+		}
+		b.WriteString("</D:response>")
+	}
+	b.WriteString("</D:multistatus>\n")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		log.Println("Error writing multi-status response:", err)
+	}
+}
+
+// davElement renders a property element with the given XML content
+func davElement(name xml.Name, content string) string {
+	tag, declaration := name.Local, ""
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "X:" + name.Local
+		declaration = ` xmlns:X="` + davText(name.Space) + `"`
+	}
+
+	if content == "" {
+		return "<" + tag + declaration + "/>"
+	}
+	return "<" + tag + declaration + ">" + content + "</" + tag + ">"
+}
+
+// davText escapes text for use in XML; carriage returns are kept, since parsers would turn CRLF into LF
+func davText(s string) string {
+	return davEscaper.Replace(s)
+}
+
+// davStatusLine formats a status for a multi-status response
+func davStatusLine(status int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", status, http.StatusText(status))
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/auth"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// handleCalendarFeed handles the iCalendar feed of tasks.
+// "type=todo" renders tasks as VTODO instead of all-day VEVENT components; the task filters of /api/tasks apply.
+func (a *App) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter, err := taskFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The feed is rendered in full first, so that errors can still be reported with a proper status
+	var buf bytes.Buffer
+	err = a.CalendarService.WriteFeed(&buf, filter, r.URL.Query().Get("type"))
+	if errors.Is(err, services.ErrInvalidCalendarKind) || errors.Is(err, services.ErrInvalidTag) ||
+		errors.Is(err, services.ErrInvalidStatus) || errors.Is(err, repository.ErrInvalidSort) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Println("Error rendering calendar feed:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error rendering calendar feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="scheduler.ics"`)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Println("Error writing calendar feed:", err)
+	}
+}
+
+// handleCalendarToken handles issuing a long-lived token for subscribing to the calendar feed
+func (a *App) handleCalendarToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	feedURL := "/api/calendar.ics"
+	pass := a.Config.Password
+	if pass == "" {
+		// Without a password the feed is open
+		writeJSON(w, map[string]string{"url": feedURL})
+		return
+	}
+
+	token, err := auth.GenerateFeedToken(pass, a.Config.FeedTokenTTL)
+	if err != nil {
+		log.Println("Error generating feed token:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error generating token")
+		return
+	}
+
+	writeJSON(w, map[string]string{"token": token, "url": feedURL + "?token=" + url.QueryEscape(token)})
+}
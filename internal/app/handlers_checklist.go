@@ -0,0 +1,135 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleChecklist routes checklist requests to the corresponding handlers depending on the method
+func (a *App) handleChecklist(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.getChecklistHandler(w, r)
+	case http.MethodPost:
+		a.addChecklistItemHandler(w, r)
+	case http.MethodDelete:
+		a.deleteChecklistItemHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getChecklistHandler handles getting the checklist of a task
+func (a *App) getChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	items, err := a.ChecklistService.ListItems(r.URL.Query().Get("id"))
+	if err != nil {
+		log.Println("Error getting checklist:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeChecklist(w, items)
+}
+
+// addChecklistItemHandler handles appending an item to the checklist of a task
+func (a *App) addChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	item, err := a.ChecklistService.AddItem(r.URL.Query().Get("id"), body.Text)
+	if err != nil {
+		log.Println("Error adding checklist item:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, item)
+}
+
+// deleteChecklistItemHandler handles deleting a checklist item
+func (a *App) deleteChecklistItemHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := a.ChecklistService.DeleteItem(r.URL.Query().Get("item")); err != nil {
+		log.Println("Error deleting checklist item:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Checklist item deleted successfully"})
+}
+
+// handleToggleChecklistItem handles checking or unchecking a checklist item
+func (a *App) handleToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	item, err := a.ChecklistService.ToggleItem(r.URL.Query().Get("item"))
+	if err != nil {
+		log.Println("Error toggling checklist item:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, item)
+}
+
+// handleReorderChecklist handles changing the order of a task's checklist.
+// The body lists the IDs of all items in the new order: {"items": ["3", "1", "2"]}.
+func (a *App) handleReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	defer r.Body.Close()
+
+	var body struct {
+		Items []string `json:"items"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	items, err := a.ChecklistService.ReorderItems(r.URL.Query().Get("id"), body.Items)
+	if err != nil {
+		log.Println("Error reordering checklist:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeChecklist(w, items)
+}
+
+// writeChecklist writes checklist items as a JSON object
+func writeChecklist(w http.ResponseWriter, items []*models.ChecklistItem) {
+	if items == nil {
+		items = []*models.ChecklistItem{}
+	}
+	writeJSON(w, map[string]any{"items": items})
+}
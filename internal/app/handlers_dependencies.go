@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleDependency handles linking (POST) and unlinking (DELETE) tasks.
+// "id" is the task that waits and "depends_on" the task that has to be done first.
+func (a *App) handleDependency(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	query := r.URL.Query()
+	id, dependsOn := query.Get("id"), query.Get("depends_on")
+
+	switch r.Method {
+	case http.MethodPost:
+		if err := a.DependencyService.LinkTasks(id, dependsOn); err != nil {
+			log.Println("Error linking tasks:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"message": "Dependency added successfully"})
+	case http.MethodDelete:
+		if err := a.DependencyService.UnlinkTasks(id, dependsOn); err != nil {
+			log.Println("Error unlinking tasks:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"message": "Dependency removed successfully"})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleTaskGraph handles exporting the dependency graph as JSON (default) or in the Graphviz DOT format ("format=dot")
+func (a *App) handleTaskGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		writeJSONError(w, http.StatusBadRequest, "invalid format, expected 'json' or 'dot'")
+		return
+	}
+
+	graph, err := a.DependencyService.Graph()
+	if err != nil {
+		log.Println("Error building the task graph:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error building the task graph")
+		return
+	}
+
+	if format == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=UTF-8")
+		if err := writeDOT(w, graph); err != nil {
+			log.Println("Error writing the task graph:", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	writeJSON(w, graph)
+}
+
+// writeDOT writes the dependency graph in the Graphviz DOT format.
+// Edges point from the task that has to be done first to the task waiting for it; blocked tasks are dashed.
+func writeDOT(w http.ResponseWriter, graph *models.TaskGraph) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph tasks {")
+	for _, task := range graph.Nodes {
+		style := ""
+		if task.Blocked {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(out, "  %s [label=%s%s];\n", dotID(task.ID), dotID(task.Title+"\n"+task.Date), style)
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(out, "  %s -> %s;\n", dotID(edge.DependsOnID), dotID(edge.TaskID))
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// dotID quotes a string as a DOT identifier
+func dotID(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
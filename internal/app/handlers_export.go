@@ -0,0 +1,52 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// exportContentTypes - content types of the export formats
+var exportContentTypes = map[string]string{
+	services.FormatJSON:    "application/json; charset=UTF-8",
+	services.FormatNDJSON:  "application/x-ndjson; charset=UTF-8",
+	services.FormatCSV:     "text/csv; charset=UTF-8",
+	services.FormatTodoTxt: "text/plain; charset=UTF-8",
+}
+
+// exportFileNames - names of the downloaded files by format
+var exportFileNames = map[string]string{
+	services.FormatJSON:    "tasks.json",
+	services.FormatNDJSON:  "tasks.ndjson",
+	services.FormatCSV:     "tasks.csv",
+	services.FormatTodoTxt: "todo.txt",
+}
+
+// handleExport handles downloading all tasks with their related data.
+// "format" is json (the default), ndjson, csv or todotxt; the tasks are streamed as they are read.
+func (a *App) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.FormatJSON
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		writeJSONError(w, http.StatusBadRequest, services.ErrInvalidFormat.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFileNames[format]+`"`)
+	if err := a.ExportService.Export(w, format); err != nil {
+		// The response has already started, so the error can only be logged
+		log.Println("Error exporting tasks:", err)
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleField routes custom field requests to the corresponding handlers depending on the method
+func (a *App) handleField(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		a.addFieldHandler(w, r)
+	case http.MethodGet:
+		a.getFieldHandler(w, r)
+	case http.MethodPut:
+		a.editFieldHandler(w, r)
+	case http.MethodDelete:
+		a.deleteFieldHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// readField decodes a custom field from the request body
+func readField(w http.ResponseWriter, r *http.Request) (*models.CustomField, bool) {
+	defer r.Body.Close()
+
+	var field models.CustomField
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&field); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return nil, false
+	}
+	return &field, true
+}
+
+// addFieldHandler handles adding a new custom field
+func (a *App) addFieldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	field, ok := readField(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := a.FieldService.CreateField(field)
+	if err != nil {
+		log.Println("Error creating custom field:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{"id": id})
+}
+
+// getFieldHandler handles getting a custom field by ID
+func (a *App) getFieldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Custom field ID is required")
+		return
+	}
+
+	field, err := a.FieldService.GetField(id)
+	if err != nil {
+		log.Println("Custom field not found:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, field)
+}
+
+// editFieldHandler handles renaming a custom field or changing its options
+func (a *App) editFieldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	field, ok := readField(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.FieldService.UpdateField(field); err != nil {
+		log.Println("Error updating custom field:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Custom field updated successfully"})
+}
+
+// deleteFieldHandler handles deleting a custom field together with its values
+func (a *App) deleteFieldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Custom field ID is required")
+		return
+	}
+
+	if err := a.FieldService.DeleteField(id); err != nil {
+		log.Println("Error deleting custom field:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Custom field deleted successfully"})
+}
+
+// handleFields handles getting the list of custom fields
+func (a *App) handleFields(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	fields, err := a.FieldService.ListFields()
+	if err != nil {
+		log.Println("Error getting custom field list:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error getting custom field list")
+		return
+	}
+
+	if fields == nil {
+		fields = []*models.CustomField{}
+	}
+
+	writeJSON(w, map[string]any{"fields": fields})
+}
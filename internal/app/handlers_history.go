@@ -0,0 +1,61 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleTaskHistory handles getting the completion history of a task
+func (a *App) handleTaskHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Task ID is required")
+		return
+	}
+
+	completions, err := a.TaskService.TaskHistory(id, defaultLimit)
+	if err != nil {
+		log.Println("Error getting task history:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error getting task history")
+		return
+	}
+
+	if completions == nil {
+		completions = []*models.Completion{}
+	}
+
+	writeJSON(w, map[string]any{"completions": completions})
+}
+
+// handleCompletionLog handles getting the completions of all tasks within a range of dates
+func (a *App) handleCompletionLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	completions, err := a.TaskService.CompletionLog(query.Get("from"), query.Get("to"), defaultLimit)
+	if err != nil {
+		log.Println("Error getting completion log:", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if completions == nil {
+		completions = []*models.Completion{}
+	}
+
+	writeJSON(w, map[string]any{"completions": completions})
+}
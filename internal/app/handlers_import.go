@@ -0,0 +1,92 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// maxImportSize is the maximum size of an imported file
+const maxImportSize = 10 << 20
+
+// handleImportICal handles importing tasks from an iCalendar file sent as the request body.
+// "dry_run=true" only reports what would be imported, "project" adds the tasks to a project.
+func (a *App) handleImportICal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	options := services.ImportOptions{
+		DryRun:    query.Get("dry_run") == "true",
+		ProjectID: query.Get("project"),
+	}
+
+	report, err := a.ImportService.ImportICal(r.Context(), http.MaxBytesReader(w, r.Body, maxImportSize), options)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	case errors.Is(err, services.ErrInvalidCalendar), errors.Is(err, repository.ErrProjectNotFound):
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	case err != nil:
+		log.Println("Error importing calendar:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error importing calendar")
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// handleImport handles importing tasks in one of the export formats, sent as the request body.
+// "format" is json, ndjson, csv or todotxt, or todoist, todoist-csv or trello for exports of those applications.
+// "dry_run=true" only reports what would be imported and "project" adds the tasks to a project.
+// CSV columns are matched to task attributes by name; "column.<attribute>=<header>" maps a column with a different name.
+func (a *App) handleImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	options := services.ImportOptions{
+		DryRun:    query.Get("dry_run") == "true",
+		ProjectID: query.Get("project"),
+		Columns:   make(map[string]string),
+	}
+	for key, values := range query {
+		if attribute, ok := strings.CutPrefix(key, "column."); ok && len(values) > 0 {
+			options.Columns[attribute] = values[0]
+		}
+	}
+
+	report, err := a.ImportService.ImportTasks(r.Context(), http.MaxBytesReader(w, r.Body, maxImportSize), query.Get("format"), options)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	case errors.Is(err, services.ErrInvalidFormat), errors.Is(err, services.ErrInvalidImport), errors.Is(err, repository.ErrProjectNotFound):
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	case err != nil:
+		log.Println("Error importing tasks:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error importing tasks")
+		return
+	}
+
+	writeJSON(w, report)
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleNotes routes note requests to the corresponding handlers depending on the method
+func (a *App) handleNotes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.getNotesHandler(w, r)
+	case http.MethodPost:
+		a.addNoteHandler(w, r)
+	case http.MethodPut:
+		a.editNoteHandler(w, r)
+	case http.MethodDelete:
+		a.deleteNoteHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// readNoteText decodes the text of a note from the request body
+func readNoteText(w http.ResponseWriter, r *http.Request) (string, bool) {
+	defer r.Body.Close()
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return "", false
+	}
+	return body.Text, true
+}
+
+// getNotesHandler handles getting the notes of a task
+func (a *App) getNotesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	notes, err := a.NoteService.ListNotes(r.URL.Query().Get("id"))
+	if err != nil {
+		log.Println("Error getting notes:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	if notes == nil {
+		notes = []*models.Note{}
+	}
+
+	writeJSON(w, map[string]any{"notes": notes})
+}
+
+// addNoteHandler handles adding a note to a task
+func (a *App) addNoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	text, ok := readNoteText(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := a.NoteService.AddNote(r.URL.Query().Get("id"), text)
+	if err != nil {
+		log.Println("Error adding note:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, note)
+}
+
+// editNoteHandler handles editing the text of a note
+func (a *App) editNoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	text, ok := readNoteText(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := a.NoteService.EditNote(r.URL.Query().Get("note"), text)
+	if err != nil {
+		log.Println("Error editing note:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, note)
+}
+
+// deleteNoteHandler handles deleting a note
+func (a *App) deleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := a.NoteService.DeleteNote(r.URL.Query().Get("note")); err != nil {
+		log.Println("Error deleting note:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Note deleted successfully"})
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// handleProject routes project requests to the corresponding handlers depending on the method
+func (a *App) handleProject(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		a.addProjectHandler(w, r)
+	case http.MethodGet:
+		a.getProjectHandler(w, r)
+	case http.MethodPut:
+		a.editProjectHandler(w, r)
+	case http.MethodDelete:
+		a.deleteProjectHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// readProject decodes a project from the request body
+func readProject(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	defer r.Body.Close()
+
+	var project models.Project
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&project); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return nil, false
+	}
+	return &project, true
+}
+
+// addProjectHandler handles adding a new project
+func (a *App) addProjectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	project, ok := readProject(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := a.ProjectService.CreateProject(project)
+	if err != nil {
+		log.Println("Error creating project:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{"id": id})
+}
+
+// getProjectHandler handles getting a project by ID
+func (a *App) getProjectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Project ID is required")
+		return
+	}
+
+	project, err := a.ProjectService.GetProject(id)
+	if err != nil {
+		log.Println("Project not found:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, project)
+}
+
+// editProjectHandler handles editing a project
+func (a *App) editProjectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	project, ok := readProject(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.ProjectService.UpdateProject(project); err != nil {
+		log.Println("Error updating project:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Project updated successfully"})
+}
+
+// deleteProjectHandler handles deleting a project.
+// "tasks=archive" archives the project instead, "tasks=move&to=<id>" moves its tasks to another project first.
+// Without either a project any task refers to, including done and deleted ones, is not deleted.
+func (a *App) deleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	query := r.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Project ID is required")
+		return
+	}
+
+	tasks := query.Get("tasks")
+	if err := a.ProjectService.DeleteProject(id, tasks, query.Get("to")); err != nil {
+		log.Println("Error deleting project:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	message := "Project deleted successfully"
+	if tasks == services.ProjectTasksArchive {
+		message = "Project archived successfully"
+	}
+	writeJSON(w, map[string]string{"message": message})
+}
+
+// handleProjects handles getting the list of projects; "archived=true" includes archived projects
+func (a *App) handleProjects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	projects, err := a.ProjectService.ListProjects(r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		log.Println("Error getting project list:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error getting project list")
+		return
+	}
+
+	if projects == nil {
+		projects = []*models.Project{}
+	}
+
+	writeJSON(w, map[string]any{"projects": projects})
+}
+
+// handleRestoreProject handles unarchiving a project
+func (a *App) handleRestoreProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Project ID is required")
+		return
+	}
+
+	if err := a.ProjectService.RestoreProject(id); err != nil {
+		log.Println("Error restoring project:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Project restored"})
+}
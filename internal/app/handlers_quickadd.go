@@ -0,0 +1,49 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// handleQuickAdd handles creating a task from a single line of text.
+// The response contains the parsed structure; "preview=true" only parses the line without creating the task.
+func (a *App) handleQuickAdd(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		parsed, err := a.QuickAddService.Parse(body.Text)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, map[string]any{"task": parsed})
+		return
+	}
+
+	id, parsed, err := a.QuickAddService.AddTask(r.Context(), body.Text)
+	if err != nil {
+		log.Println("Error creating task:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{"id": id, "task": parsed})
+}
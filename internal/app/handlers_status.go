@@ -0,0 +1,71 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// handleTaskStatus handles moving a task to another status: {"status": "in_progress"}.
+// Moving a task to done completes it the same way as /api/task/done.
+func (a *App) handleTaskStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Task ID is required")
+		return
+	}
+
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+
+	defer r.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	var unblocked []*models.Task
+	if body.Status == models.StatusDone {
+		unblocked, err = a.TaskService.MarkTaskDone(r.Context(), id, services.DoneOptions{Version: version})
+	} else {
+		err = a.TaskService.SetStatus(r.Context(), id, body.Status, version)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			a.writeVersionConflict(w, id, conditional)
+			return
+		}
+		log.Println("Error changing task status:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	response := map[string]any{
+		"message": "Task status changed successfully",
+	}
+	if len(unblocked) > 0 {
+		response["unblocked"] = unblocked
+	}
+	writeJSON(w, response)
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleTags handles getting the list of tags with their usage counts
+func (a *App) handleTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tags, err := a.TaskService.ListTags()
+	if err != nil {
+		log.Println("Error getting tags:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error getting tags")
+		return
+	}
+
+	if tags == nil {
+		tags = []*models.TagCount{}
+	}
+
+	writeJSON(w, map[string]any{"tags": tags})
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/services"
+)
+
+// handleTemplates handles listing templates and adding a new one
+func (a *App) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := a.TemplateService.ListTemplates()
+		if err != nil {
+			log.Println("Error getting template list:", err)
+			writeJSONError(w, http.StatusInternalServerError, "Error getting template list")
+			return
+		}
+		if templates == nil {
+			templates = []*models.Template{}
+		}
+		writeJSON(w, map[string]any{"templates": templates})
+	case http.MethodPost:
+		template, ok := readTemplate(w, r)
+		if !ok {
+			return
+		}
+		id, err := a.TemplateService.CreateTemplate(template)
+		if err != nil {
+			log.Println("Error creating template:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]any{"id": id})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleTemplate handles getting, replacing and deleting the template given in the path
+func (a *App) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		template, err := a.TemplateService.GetTemplate(id)
+		if err != nil {
+			log.Println("Template not found:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, template)
+	case http.MethodPut:
+		template, ok := readTemplate(w, r)
+		if !ok {
+			return
+		}
+		template.ID = id
+		if err := a.TemplateService.UpdateTemplate(template); err != nil {
+			log.Println("Error updating template:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"message": "Template updated successfully"})
+	case http.MethodDelete:
+		if err := a.TemplateService.DeleteTemplate(id); err != nil {
+			log.Println("Error deleting template:", err)
+			writeJSONError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, map[string]string{"message": "Template deleted successfully"})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleInstantiateTemplate handles creating a task from a template.
+// The optional body gives the anchor date ("20060102") and the placeholder values.
+func (a *App) handleInstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var body struct {
+		Anchor string            `json:"anchor"`
+		Values map[string]string `json:"values"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return
+	}
+
+	id, err := a.TemplateService.Instantiate(r.Context(), r.PathValue("id"), services.InstantiateOptions{
+		Anchor: body.Anchor,
+		Values: body.Values,
+	})
+	if err != nil {
+		log.Println("Error instantiating template:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]any{"id": id})
+}
+
+// readTemplate decodes a template from the request body
+func readTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	defer r.Body.Close()
+
+	var template models.Template
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&template); err != nil {
+		log.Println("Error reading JSON:", err)
+		writeJSONError(w, http.StatusBadRequest, "Error reading JSON")
+		return nil, false
+	}
+	return &template, true
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// handleTrash routes trash requests depending on the method
+func (a *App) handleTrash(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listTrashHandler(w, r)
+	case http.MethodDelete:
+		a.purgeTaskHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listTrashHandler handles getting the list of deleted tasks
+func (a *App) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tasks, err := a.TaskService.ListTrash(defaultLimit)
+	if err != nil {
+		log.Println("Error getting the trash:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error getting the trash")
+		return
+	}
+
+	if tasks == nil {
+		tasks = []*models.Task{}
+	}
+
+	writeJSON(w, map[string]any{"tasks": tasks})
+}
+
+// purgeTaskHandler handles permanently deleting a task from the trash
+func (a *App) purgeTaskHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Task ID is required")
+		return
+	}
+
+	if err := a.TaskService.PurgeTask(r.Context(), id); err != nil {
+		log.Println("Error purging task:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Task deleted permanently"})
+}
+
+// handleRestoreTask handles moving a task from the trash back to the active list
+func (a *App) handleRestoreTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Task ID is required")
+		return
+	}
+
+	if err := a.TaskService.RestoreTask(r.Context(), id); err != nil {
+		log.Println("Error restoring task:", err)
+		writeJSONError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]string{"message": "Task restored"})
+}
+
+// handleEmptyTrash handles permanently deleting all tasks in the trash
+func (a *App) handleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	purged, err := a.TaskService.PurgeTrash(0)
+	if err != nil {
+		log.Println("Error emptying the trash:", err)
+		writeJSONError(w, http.StatusInternalServerError, "Error emptying the trash")
+		return
+	}
+
+	writeJSON(w, map[string]any{"purged": purged})
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"net"
 	"net/http"
 
 	"github.com/VladimirVereshchagin/scheduler/internal/auth"
@@ -10,10 +12,12 @@ import (
 // Auth - authentication check
 func Auth(next http.HandlerFunc, cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		actor := auth.Actor{Name: auth.Anonymous, IP: clientIP(r)}
+
 		pass := cfg.Password
 		if pass == "" {
 			// Password not set, skipping
-			next(w, r)
+			next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
 			return
 		}
 
@@ -26,14 +30,91 @@ func Auth(next http.HandlerFunc, cfg *config.Config) http.HandlerFunc {
 		}
 
 		// Validate token
-		_, err = auth.ParseToken(cookie.Value, pass)
+		token, err := auth.ParseToken(cookie.Value, pass)
 		if err != nil {
 			// Token is invalid
 			http.Error(w, "Authentication required", http.StatusUnauthorized)
 			return
 		}
 
+		// Remember who performs the request
+		if subject, err := token.Claims.GetSubject(); err == nil && subject != "" {
+			actor.Name = subject
+		}
+
 		// Token is valid, proceed
-		next(w, r)
+		next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+	}
+}
+
+// FeedAuth - authentication check for the calendar feed, which takes a feed token from the "token" query parameter,
+// since calendar applications cannot sign in
+func FeedAuth(next http.HandlerFunc, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := auth.Actor{Name: auth.Anonymous, IP: clientIP(r)}
+
+		pass := cfg.Password
+		if pass == "" {
+			// Password not set, skipping
+			next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+			return
+		}
+
+		token, err := auth.ParseFeedToken(r.URL.Query().Get("token"), pass)
+		if err != nil {
+			http.Error(w, "Feed token required", http.StatusUnauthorized)
+			return
+		}
+
+		if subject, err := token.Claims.GetSubject(); err == nil && subject != "" {
+			actor.Name = subject
+		}
+		next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+	}
+}
+
+// DAVAuth - authentication check for CalDAV clients, which send the password with HTTP basic authentication.
+// The user name is recorded as the actor; a token cookie from the web interface is accepted as well.
+func DAVAuth(next http.HandlerFunc, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := auth.Actor{Name: auth.Anonymous, IP: clientIP(r)}
+
+		pass := cfg.Password
+		if pass == "" {
+			// Password not set, skipping
+			next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+			return
+		}
+
+		if user, password, ok := r.BasicAuth(); ok && subtle.ConstantTimeCompare([]byte(password), []byte(pass)) == 1 {
+			actor.Name = "user"
+			if user != "" {
+				actor.Name = user
+			}
+			next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+			return
+		}
+
+		if cookie, err := r.Cookie("token"); err == nil {
+			if token, err := auth.ParseToken(cookie.Value, pass); err == nil {
+				if subject, err := token.Claims.GetSubject(); err == nil && subject != "" {
+					actor.Name = subject
+				}
+				next(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="Scheduler", charset="UTF-8"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	}
+}
+
+// clientIP - extracts the client IP address from the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
@@ -8,19 +8,69 @@ import (
 	"github.com/VladimirVereshchagin/scheduler/internal/services"
 )
 
+// Services groups the business logic used by the application
+type Services struct {
+	Tasks        services.TaskService       // Service for task operations
+	Audit        services.AuditService      // Service for reading the audit trail
+	Batch        services.BatchService      // Service for transactional bulk operations
+	Projects     services.ProjectService    // Service for project operations
+	Checklists   services.ChecklistService  // Service for the checklists of tasks
+	Dependencies services.DependencyService // Service for dependencies between tasks
+	Attachments  services.AttachmentService // Service for files attached to tasks
+	Fields       services.FieldService      // Service for custom field definitions
+	Notes        services.NoteService       // Service for the notes of tasks
+	Templates    services.TemplateService   // Service for task templates
+	QuickAdd     services.QuickAddService   // Service for one-line task entry
+	Calendar     services.CalendarService   // Service for the iCalendar feed
+	Import       services.ImportService     // Service for importing tasks from other applications
+	Export       services.ExportService     // Service for exporting all tasks
+	CalDAV       services.CalDAVService     // Service for the CalDAV task collection
+	Backup       services.BackupService     // Service for online database backups
+}
+
 // App represents the application structure with its configuration and dependencies
 type App struct {
-	Router      *http.ServeMux       // Router for handling HTTP requests
-	TaskService services.TaskService // Service for task operations
-	Config      *config.Config       // Application configuration
+	Router            *http.ServeMux             // Router for handling HTTP requests
+	TaskService       services.TaskService       // Service for task operations
+	AuditService      services.AuditService      // Service for reading the audit trail
+	BatchService      services.BatchService      // Service for transactional bulk operations
+	ProjectService    services.ProjectService    // Service for project operations
+	ChecklistService  services.ChecklistService  // Service for the checklists of tasks
+	DependencyService services.DependencyService // Service for dependencies between tasks
+	AttachmentService services.AttachmentService // Service for files attached to tasks
+	FieldService      services.FieldService      // Service for custom field definitions
+	NoteService       services.NoteService       // Service for the notes of tasks
+	TemplateService   services.TemplateService   // Service for task templates
+	QuickAddService   services.QuickAddService   // Service for one-line task entry
+	CalendarService   services.CalendarService   // Service for the iCalendar feed
+	ImportService     services.ImportService     // Service for importing tasks from other applications
+	ExportService     services.ExportService     // Service for exporting all tasks
+	CalDAVService     services.CalDAVService     // Service for the CalDAV task collection
+	BackupService     services.BackupService     // Service for online database backups
+	Config            *config.Config             // Application configuration
 }
 
 // NewApp creates a new application and registers the routes
-func NewApp(taskService services.TaskService, cfg *config.Config) *App {
+func NewApp(svc Services, cfg *config.Config) *App {
 	app := &App{
-		Router:      http.NewServeMux(), // Initialize router
-		TaskService: taskService,        // Initialize task service
-		Config:      cfg,                // Load configuration
+		Router:            http.NewServeMux(), // Initialize router
+		TaskService:       svc.Tasks,          // Initialize task service
+		AuditService:      svc.Audit,          // Initialize audit service
+		BatchService:      svc.Batch,          // Initialize batch service
+		ProjectService:    svc.Projects,       // Initialize project service
+		ChecklistService:  svc.Checklists,     // Initialize checklist service
+		DependencyService: svc.Dependencies,   // Initialize dependency service
+		AttachmentService: svc.Attachments,    // Initialize attachment service
+		FieldService:      svc.Fields,         // Initialize custom field service
+		NoteService:       svc.Notes,          // Initialize note service
+		TemplateService:   svc.Templates,      // Initialize template service
+		QuickAddService:   svc.QuickAdd,       // Initialize quick-add service
+		CalendarService:   svc.Calendar,       // Initialize calendar feed service
+		ImportService:     svc.Import,         // Initialize import service
+		ExportService:     svc.Export,         // Initialize export service
+		CalDAVService:     svc.CalDAV,         // Initialize CalDAV service
+		BackupService:     svc.Backup,         // Initialize backup service
+		Config:            cfg,                // Load configuration
 	}
 	app.registerRoutes() // Register routes
 	return app
@@ -33,9 +83,73 @@ func (a *App) registerRoutes() {
 	a.Router.Handle("/", http.FileServer(http.Dir(webDir)))
 
 	// API routes
-	a.Router.HandleFunc("/api/nextdate", a.handleNextDate)                             // Calculate the next task date
-	a.Router.HandleFunc("/api/task", middleware.Auth(a.handleTask, a.Config))          // Handle task operations (CRUD)
-	a.Router.HandleFunc("/api/tasks", middleware.Auth(a.handleTasks, a.Config))        // Get list of tasks
-	a.Router.HandleFunc("/api/task/done", middleware.Auth(a.handleDoneTask, a.Config)) // Mark task as done
-	a.Router.HandleFunc("/api/signin", a.handleSignIn)                                 // User authentication
+	a.Router.HandleFunc("/api/nextdate", a.handleNextDate)                                 // Calculate the next task date
+	a.Router.HandleFunc("/api/task", middleware.Auth(a.handleTask, a.Config))              // Handle task operations (CRUD)
+	a.Router.HandleFunc("/api/tasks", middleware.Auth(a.handleTasks, a.Config))            // Get list of tasks
+	a.Router.HandleFunc("/api/task/done", middleware.Auth(a.handleDoneTask, a.Config))     // Mark task as done
+	a.Router.HandleFunc("/api/task/status", middleware.Auth(a.handleTaskStatus, a.Config)) // Change task status
+	a.Router.HandleFunc("/api/task/quick", middleware.Auth(a.handleQuickAdd, a.Config))    // Create a task from a single line
+	a.Router.HandleFunc("/api/tasks/batch", middleware.Auth(a.handleBatch, a.Config))      // Run several task operations at once
+	a.Router.HandleFunc("/api/tags", middleware.Auth(a.handleTags, a.Config))              // List tags with usage counts
+	a.Router.HandleFunc("/api/signin", a.handleSignIn)                                     // User authentication
+
+	// Project routes
+	a.Router.HandleFunc("/api/project", middleware.Auth(a.handleProject, a.Config))                // Handle project operations (CRUD)
+	a.Router.HandleFunc("/api/projects", middleware.Auth(a.handleProjects, a.Config))              // Get list of projects
+	a.Router.HandleFunc("/api/project/restore", middleware.Auth(a.handleRestoreProject, a.Config)) // Unarchive a project
+
+	// Checklist routes
+	a.Router.HandleFunc("/api/task/checklist", middleware.Auth(a.handleChecklist, a.Config))                  // Get the checklist of a task, add or delete items
+	a.Router.HandleFunc("/api/task/checklist/toggle", middleware.Auth(a.handleToggleChecklistItem, a.Config)) // Check or uncheck an item
+	a.Router.HandleFunc("/api/task/checklist/reorder", middleware.Auth(a.handleReorderChecklist, a.Config))   // Change the order of the items
+
+	// Note routes
+	a.Router.HandleFunc("/api/task/notes", middleware.Auth(a.handleNotes, a.Config)) // List, add, edit or delete the notes of a task
+
+	// Dependency routes
+	a.Router.HandleFunc("/api/task/dependency", middleware.Auth(a.handleDependency, a.Config)) // Link or unlink tasks
+	a.Router.HandleFunc("/api/tasks/graph", middleware.Auth(a.handleTaskGraph, a.Config))      // Export the dependency graph
+
+	// Attachment routes
+	a.Router.HandleFunc("/api/task/attachments", middleware.Auth(a.handleAttachments, a.Config))                 // List, upload or delete attachments
+	a.Router.HandleFunc("/api/task/attachments/download", middleware.Auth(a.handleDownloadAttachment, a.Config)) // Download an attachment
+
+	// Custom field routes
+	a.Router.HandleFunc("/api/field", middleware.Auth(a.handleField, a.Config))   // Handle custom field definitions (CRUD)
+	a.Router.HandleFunc("/api/fields", middleware.Auth(a.handleFields, a.Config)) // Get list of custom fields
+
+	// Template routes
+	a.Router.HandleFunc("/api/templates", middleware.Auth(a.handleTemplates, a.Config))                            // List or add templates
+	a.Router.HandleFunc("/api/templates/{id}", middleware.Auth(a.handleTemplate, a.Config))                        // Get, replace or delete a template
+	a.Router.HandleFunc("/api/templates/{id}/instantiate", middleware.Auth(a.handleInstantiateTemplate, a.Config)) // Create a task from a template
+
+	// Calendar routes
+	a.Router.HandleFunc("/api/calendar.ics", middleware.FeedAuth(a.handleCalendarFeed, a.Config)) // iCalendar feed for calendar subscriptions
+	a.Router.HandleFunc("/api/calendar/token", middleware.Auth(a.handleCalendarToken, a.Config))  // Issue a feed token
+
+	// CalDAV routes
+	a.Router.HandleFunc("/.well-known/caldav", a.handleDAVWellKnown)        // CalDAV service discovery
+	a.Router.HandleFunc("/dav/", middleware.DAVAuth(a.handleDAV, a.Config)) // CalDAV collection of tasks for native clients
+
+	// Import and export routes
+	a.Router.HandleFunc("/api/import/ical", middleware.Auth(a.handleImportICal, a.Config)) // Import tasks from an iCalendar file
+	a.Router.HandleFunc("/api/import", middleware.Auth(a.handleImport, a.Config))          // Import tasks exported from another instance
+	a.Router.HandleFunc("/api/export", middleware.Auth(a.handleExport, a.Config))          // Export all tasks as JSON, NDJSON, CSV or todo.txt
+
+	// Completion history routes
+	a.Router.HandleFunc("/api/task/history", middleware.Auth(a.handleTaskHistory, a.Config)) // Completion history of a task
+	a.Router.HandleFunc("/api/history", middleware.Auth(a.handleCompletionLog, a.Config))    // Completions of all tasks
+
+	// Audit trail routes
+	a.Router.HandleFunc("/api/admin/audit", middleware.Auth(a.handleAuditLog, a.Config))           // Query the audit trail
+	a.Router.HandleFunc("/api/admin/audit/export", middleware.Auth(a.handleAuditExport, a.Config)) // Export the audit trail as NDJSON
+
+	// Backup routes
+	a.Router.HandleFunc("/api/admin/backup", middleware.Auth(a.handleBackup, a.Config))   // Download a new or a stored backup
+	a.Router.HandleFunc("/api/admin/backups", middleware.Auth(a.handleBackups, a.Config)) // List or store backups
+
+	// Trash routes
+	a.Router.HandleFunc("/api/trash", middleware.Auth(a.handleTrash, a.Config))               // List or purge deleted tasks
+	a.Router.HandleFunc("/api/trash/restore", middleware.Auth(a.handleRestoreTask, a.Config)) // Restore a deleted task
+	a.Router.HandleFunc("/api/trash/empty", middleware.Auth(a.handleEmptyTrash, a.Config))    // Purge all deleted tasks
 }
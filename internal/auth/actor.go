@@ -0,0 +1,28 @@
+package auth
+
+import "context"
+
+// Anonymous is the actor name used when authentication is disabled
+const Anonymous = "anonymous"
+
+// Actor identifies who performs a request
+type Actor struct {
+	Name string // Subject of the JWT token
+	IP   string // Client IP address
+}
+
+// actorKey is the context key for the request actor
+type actorKey struct{}
+
+// WithActor returns a copy of the context carrying the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored in the context, or an anonymous actor
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Name: Anonymous}
+}
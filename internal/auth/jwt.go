@@ -51,6 +51,55 @@ func ParseToken(tokenString, password string) (*jwt.Token, error) {
 		return nil, fmt.Errorf("invalid token hash")
 	}
 
+	// Feed tokens only grant access to the calendar feed
+	if _, scoped := claims["scope"]; scoped {
+		return nil, fmt.Errorf("token is limited to %v", claims["scope"])
+	}
+
+	return token, nil
+}
+
+// FeedScope is the scope of tokens that only grant access to the calendar feed
+const FeedScope = "calendar"
+
+// GenerateFeedToken generates a long-lived JWT token for subscribing to the calendar feed.
+// A zero ttl creates a token that does not expire; changing the password revokes all feed tokens.
+func GenerateFeedToken(password string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":   "feed",
+		"scope": FeedScope,
+		"hash":  GeneratePasswordHash(password),
+	}
+	if ttl > 0 {
+		claims["exp"] = time.Now().Add(ttl).Unix()
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(password))
+}
+
+// ParseFeedToken parses a calendar feed token and checks its validity
+func ParseFeedToken(tokenString, password string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(password), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if claims["hash"] != GeneratePasswordHash(password) {
+		return nil, fmt.Errorf("invalid token hash")
+	}
+	if claims["scope"] != FeedScope {
+		return nil, fmt.Errorf("not a feed token")
+	}
+
 	return token, nil
 }
 
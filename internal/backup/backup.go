@@ -0,0 +1,281 @@
+// Package backup reads and writes database backup files.
+// A backup is an SQLite database file, optionally compressed with gzip and then encrypted with AES-256-GCM.
+// Readers recognize the layers by their headers, so the file name does not matter when restoring.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrKeyRequired is returned when reading an encrypted backup without a key
+	ErrKeyRequired = errors.New("the backup is encrypted, a key is required")
+	// ErrDecrypt is returned when a backup cannot be decrypted, because the key is wrong or the file was changed
+	ErrDecrypt = errors.New("cannot decrypt the backup: wrong key or damaged file")
+	// ErrUnknownFormat is returned for files that are not backups
+	ErrUnknownFormat = errors.New("not a database backup")
+)
+
+// File headers
+var (
+	sqliteMagic    = []byte("SQLite format 3\x00")
+	gzipMagic      = []byte{0x1f, 0x8b}
+	encryptedMagic = []byte("SCHDBAK\x01") // Followed by the salt and the nonce prefix
+)
+
+const (
+	saltSize       = 16
+	noncePrefix    = 7        // Random part of the nonces; the rest is the chunk counter and the last chunk flag
+	chunkSize      = 64 << 10 // Plaintext bytes per encrypted chunk
+	keyIterations  = 600000   // PBKDF2-HMAC-SHA256 iterations deriving the key from the passphrase
+	keySize        = 32
+	maxChunkNumber = 1<<32 - 1
+)
+
+// Options controls how backups are written.
+type Options struct {
+	Compress bool   // Compress the backup with gzip
+	Key      string // Passphrase to encrypt the backup with; empty leaves it unencrypted
+}
+
+// Ext returns the file extension of backups written with the options, such as ".db.gz.enc"
+func (o Options) Ext() string {
+	ext := ".db"
+	if o.Compress {
+		ext += ".gz"
+	}
+	if o.Key != "" {
+		ext += ".enc"
+	}
+	return ext
+}
+
+// NewWriter returns a writer that compresses and encrypts a database file into w as the options say.
+// The backup is complete only after Close.
+func NewWriter(w io.Writer, options Options) (io.WriteCloser, error) {
+	var layers []io.WriteCloser
+	if options.Key != "" {
+		encrypted, err := newEncryptingWriter(w, options.Key)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, encrypted)
+		w = encrypted
+	}
+	if options.Compress {
+		compressed := gzip.NewWriter(w)
+		layers = append(layers, compressed)
+		w = compressed
+	}
+	return &layeredWriter{Writer: w, layers: layers}, nil
+}
+
+// layeredWriter closes the layers of a backup from the innermost one out
+type layeredWriter struct {
+	io.Writer
+	layers []io.WriteCloser
+}
+
+// Close flushes every layer
+func (w *layeredWriter) Close() error {
+	for i := len(w.layers) - 1; i >= 0; i-- {
+		if err := w.layers[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewReader returns a reader of the database file in a backup, decrypting and decompressing it as needed.
+// Damaged or tampered encrypted backups fail with ErrDecrypt while being read.
+func NewReader(r io.Reader, key string) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.Peek(len(sqliteMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, encryptedMagic):
+		if key == "" {
+			return nil, ErrKeyRequired
+		}
+		decrypted, err := newDecryptingReader(reader, key)
+		if err != nil {
+			return nil, err
+		}
+		return NewReader(decrypted, "")
+	case bytes.HasPrefix(header, gzipMagic):
+		decompressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+		}
+		return decompressed, nil
+	case bytes.Equal(header, sqliteMagic):
+		return reader, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// encryptingWriter encrypts data in chunks, so that backups of any size are streamed.
+// The nonce of every chunk holds its number and whether it is the last one, which makes reordered,
+// dropped or truncated chunks fail to decrypt.
+type encryptingWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	chunk  []byte
+	number uint32
+}
+
+// newEncryptingWriter writes the header of an encrypted backup
+func newEncryptingWriter(w io.Writer, passphrase string) (*encryptingWriter, error) {
+	salt := make([]byte, saltSize)
+	prefix := make([]byte, noncePrefix)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, encryptedMagic...), salt...), prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{w: w, aead: aead, prefix: prefix, chunk: make([]byte, 0, chunkSize)}, nil
+}
+
+// Write encrypts full chunks; a chunk is held back until it is known not to be the last one
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(e.chunk) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.chunk[len(e.chunk):chunkSize], p)
+		e.chunk = e.chunk[:len(e.chunk)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close encrypts the last chunk
+func (e *encryptingWriter) Close() error {
+	return e.seal(true)
+}
+
+// seal encrypts and writes the buffered chunk
+func (e *encryptingWriter) seal(last bool) error {
+	if e.number == maxChunkNumber {
+		return errors.New("backup is too large to encrypt")
+	}
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.number, last), e.chunk, nil)
+	e.number++
+	e.chunk = e.chunk[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptingReader reverses encryptingWriter
+type decryptingReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	number uint32
+	plain  []byte
+	done   bool
+}
+
+// newDecryptingReader reads the header of an encrypted backup
+func newDecryptingReader(r *bufio.Reader, passphrase string) (*decryptingReader, error) {
+	header := make([]byte, len(encryptedMagic)+saltSize+noncePrefix)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrDecrypt
+	}
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{r: r, aead: aead, prefix: header[len(encryptedMagic)+saltSize:]}, nil
+}
+
+// Read decrypts the next chunk when the previous one has been read
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		sealed := make([]byte, chunkSize+d.aead.Overhead())
+		n, err := io.ReadFull(d.r, sealed)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, ErrDecrypt
+		}
+		_, peekErr := d.r.Peek(1)
+		d.done = errors.Is(peekErr, io.EOF)
+		if d.plain, err = d.aead.Open(sealed[:0], chunkNonce(d.prefix, d.number, d.done), sealed[:n], nil); err != nil {
+			return 0, ErrDecrypt
+		}
+		d.number++
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// chunkNonce builds the nonce of a chunk from the random prefix, the chunk number and the last chunk flag
+func chunkNonce(prefix []byte, number uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefix:], number)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// newAEAD derives the key from the passphrase and salt and creates the cipher
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2([]byte(passphrase), salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a key with PBKDF2-HMAC-SHA256 (RFC 8018); a single block is enough for an AES-256 key
+func pbkdf2(password, salt []byte) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	key := append([]byte{}, u...)
+	for i := 1; i < keyIterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key[:keySize]
+}
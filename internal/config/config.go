@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,8 +16,48 @@ type Config struct {
 	Port     string // Port for server startup
 	DBFile   string // Database file
 	Password string // Password for authentication
+
+	DBBusyTimeout time.Duration // How long database connections wait for locks held by other processes
+	DBMaxReaders  int           // Maximum number of database connections reading at the same time
+
+	TrashRetention time.Duration // How long deleted tasks are kept in the trash (0 disables purging)
+	DeleteOnDone   bool          // Whether completed one-off tasks are moved to the trash instead of being kept as done
+	FeedTokenTTL   time.Duration // Lifetime of calendar feed tokens (0 means they do not expire)
+
+	AttachmentStorage string   // Where attachment contents are stored: AttachmentStorageFS or AttachmentStorageSQLite
+	AttachmentDir     string   // Directory for attachments stored on the filesystem
+	AttachmentMaxSize int64    // Maximum size of an attachment in bytes
+	AttachmentTypes   []string // MIME types allowed for attachments
+
+	TodoTxtFile         string        // todo.txt file synced with the tasks (empty disables the sync)
+	TodoTxtSyncInterval time.Duration // How often the todo.txt file is synced
+
+	VaultDir          string        // Directory of Markdown files synced with the tasks (empty disables the sync)
+	VaultInbox        string        // File of the directory new tasks are added to
+	VaultSyncInterval time.Duration // How often the Markdown files are synced
+
+	BackupDir      string        // Directory backups are stored in (empty disables stored and scheduled backups)
+	BackupInterval time.Duration // How often backups are made on schedule (0 disables scheduled backups)
+	BackupKeep     int           // Number of stored backups kept (0 keeps all of them)
+	BackupCompress bool          // Whether backups are compressed with gzip
+	BackupKey      string        // Passphrase backups are encrypted with (empty leaves them unencrypted)
 }
 
+// What happens to one-off tasks when they are marked as done
+const (
+	DoneModeKeep   = "keep"   // Keep the task with the done status
+	DoneModeDelete = "delete" // Move the task to the trash, as earlier versions did
+)
+
+// Attachment storage backends
+const (
+	AttachmentStorageFS     = "fs"     // Files in Config.AttachmentDir
+	AttachmentStorageSQLite = "sqlite" // Blobs in the database
+)
+
+// defaultAttachmentTypes - MIME types allowed for attachments unless configured otherwise
+const defaultAttachmentTypes = "image/png,image/jpeg,image/gif,image/webp,application/pdf"
+
 // LoadConfig loads configuration from .env file or system variables
 func LoadConfig() *Config {
 	// Load environment variables from .env file
@@ -41,10 +83,68 @@ func LoadConfig() *Config {
 		log.Fatalf("Error creating directory for database: %v", err)
 	}
 
+	maxReaders := getEnvInt("TODO_DB_MAX_READERS", 4)
+	if maxReaders == 0 {
+		log.Fatalf("Invalid number of database readers: %d", maxReaders)
+	}
+
+	retentionDays := getEnvInt("TODO_TRASH_RETENTION_DAYS", 30)
+
+	doneMode := getEnv("TODO_DONE_MODE", DoneModeKeep)
+	if doneMode != DoneModeKeep && doneMode != DoneModeDelete {
+		log.Fatalf("Invalid done mode: %s", doneMode)
+	}
+
+	attachmentStorage := getEnv("TODO_ATTACHMENT_STORAGE", AttachmentStorageFS)
+	if attachmentStorage != AttachmentStorageFS && attachmentStorage != AttachmentStorageSQLite {
+		log.Fatalf("Invalid attachment storage: %s", attachmentStorage)
+	}
+
+	var attachmentTypes []string
+	for _, t := range strings.Split(getEnv("TODO_ATTACHMENT_TYPES", defaultAttachmentTypes), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			attachmentTypes = append(attachmentTypes, strings.ToLower(t))
+		}
+	}
+
+	todoTxtInterval := getEnvInt("TODO_TODOTXT_SYNC_SECONDS", 30)
+	if todoTxtInterval == 0 {
+		log.Fatalf("Invalid todo.txt sync interval: %d", todoTxtInterval)
+	}
+	vaultInterval := getEnvInt("TODO_VAULT_SYNC_SECONDS", 30)
+	if vaultInterval == 0 {
+		log.Fatalf("Invalid Markdown sync interval: %d", vaultInterval)
+	}
+
 	return &Config{
 		Port:     port,
 		DBFile:   dbFile,
 		Password: password,
+
+		DBBusyTimeout: time.Duration(getEnvInt("TODO_DB_BUSY_TIMEOUT_MS", 5000)) * time.Millisecond,
+		DBMaxReaders:  maxReaders,
+
+		TrashRetention: time.Duration(retentionDays) * 24 * time.Hour,
+		DeleteOnDone:   doneMode == DoneModeDelete,
+		FeedTokenTTL:   time.Duration(getEnvInt("TODO_FEED_TOKEN_TTL_DAYS", 365)) * 24 * time.Hour,
+
+		AttachmentStorage: attachmentStorage,
+		AttachmentDir:     getEnv("TODO_ATTACHMENT_DIR", filepath.Join(filepath.Dir(dbFile), "attachments")),
+		AttachmentMaxSize: int64(getEnvInt("TODO_ATTACHMENT_MAX_SIZE_MB", 10)) << 20,
+		AttachmentTypes:   attachmentTypes,
+
+		TodoTxtFile:         os.Getenv("TODO_TODOTXT_FILE"),
+		TodoTxtSyncInterval: time.Duration(todoTxtInterval) * time.Second,
+
+		VaultDir:          os.Getenv("TODO_VAULT_DIR"),
+		VaultInbox:        getEnv("TODO_VAULT_INBOX", "Scheduler.md"),
+		VaultSyncInterval: time.Duration(vaultInterval) * time.Second,
+
+		BackupDir:      os.Getenv("TODO_BACKUP_DIR"),
+		BackupInterval: time.Duration(getEnvInt("TODO_BACKUP_INTERVAL_HOURS", 24)) * time.Hour,
+		BackupKeep:     getEnvInt("TODO_BACKUP_KEEP", 7),
+		BackupCompress: getEnv("TODO_BACKUP_COMPRESS", "false") == "true",
+		BackupKey:      os.Getenv("TODO_BACKUP_KEY"),
 	}
 }
 
@@ -57,6 +157,19 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvInt gets a non-negative integer environment variable or returns the default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid value for %s: %s", key, value)
+	}
+	return n
+}
+
 // isValidPort checks if the port is within the valid range
 func isValidPort(port string) bool {
 	p, err := strconv.Atoi(port)
@@ -0,0 +1,283 @@
+// Package ical reads and writes iCalendar (RFC 5545) objects.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the maximum length of a content line in octets, excluding the line break
+const maxLineLength = 75
+
+// Property is a content line of an iCalendar object, such as SUMMARY or DTSTART
+type Property struct {
+	Name   string            // Property name in upper case
+	Params map[string]string // Property parameters, such as VALUE=DATE
+	Value  string            // Raw value; text values are escaped with EscapeText
+}
+
+// Component is a block of an iCalendar object, such as VCALENDAR, VTODO or VEVENT
+type Component struct {
+	Name       string       // Component name in upper case
+	Properties []Property   // Properties in order
+	Components []*Component // Nested components
+}
+
+// NewComponent creates an empty component
+func NewComponent(name string) *Component {
+	return &Component{Name: name}
+}
+
+// Add appends a property; params are given as name and value pairs
+func (c *Component) Add(name, value string, params ...string) {
+	p := Property{Name: name, Value: value}
+	if len(params) > 0 {
+		p.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			p.Params[params[i]] = params[i+1]
+		}
+	}
+	c.Properties = append(c.Properties, p)
+}
+
+// AddText appends a property with a text value, escaping it
+func (c *Component) AddText(name, text string) {
+	c.Add(name, EscapeText(text))
+}
+
+// Encode writes a component with its nested components, using CRLF line breaks and folding long lines
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	encode(bw, c)
+	return bw.Flush()
+}
+
+// encode writes a component to a buffered writer, which keeps the first error
+func encode(w *bufio.Writer, c *Component) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, p := range c.Properties {
+		writeLine(w, p.line())
+	}
+	for _, child := range c.Components {
+		encode(w, child)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+// line formats a property as an unfolded content line
+func (p Property) line() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := p.Params[name]
+		if strings.ContainsAny(value, ";:,") {
+			value = `"` + value + `"`
+		}
+		b.WriteString(";" + name + "=" + value)
+	}
+
+	b.WriteString(":" + p.Value)
+	return b.String()
+}
+
+// writeLine writes a content line, folding it into lines of at most 75 octets without splitting characters
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = maxLineLength - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// EscapeText escapes a text value: backslashes, semicolons, commas and line breaks
+func EscapeText(text string) string {
+	return textEscaper.Replace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// textEscaper - replacements for EscapeText
+var textEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\n", `\n`)
+
+// Get returns the first property with the given name, or nil if there is none
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Value returns the raw value of the first property with the given name, or an empty string
+func (c *Component) Value(name string) string {
+	if p := c.Get(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// Text returns the unescaped text value of the first property with the given name
+func (c *Component) Text(name string) string {
+	return UnescapeText(c.Value(name))
+}
+
+// Decode reads the top-level components of an iCalendar stream, usually a single VCALENDAR
+func Decode(r io.Reader) ([]*Component, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var (
+		roots []*Component
+		stack []*Component
+		line  string
+		n     int
+	)
+
+	// handle processes an unfolded content line
+	handle := func(line string) error {
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		switch p.Name {
+		case "BEGIN":
+			stack = append(stack, NewComponent(strings.ToUpper(p.Value)))
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return fmt.Errorf("line %d: unexpected END:%s", n, p.Value)
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				roots = append(roots, c)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+		default:
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: property %s outside of a component", n, p.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Properties = append(c.Properties, p)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		// Lines starting with a space or a tab continue the previous line
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			line += text[1:]
+			continue
+		}
+		if err := handle(line); err != nil {
+			return nil, err
+		}
+		line = text
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := handle(line); err != nil {
+		return nil, err
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("component %s is not closed", stack[len(stack)-1].Name)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("no iCalendar components found")
+	}
+	return roots, nil
+}
+
+// parseLine splits a content line into the property name, parameters and value
+func parseLine(line string) (Property, error) {
+	var (
+		p        Property
+		quoted   bool
+		start    int
+		paramKey string
+	)
+
+	// flush records the name or parameter ending at i
+	flush := func(i int) {
+		part := line[start:i]
+		if p.Name == "" {
+			p.Name = strings.ToUpper(part)
+			return
+		}
+		if paramKey == "" {
+			return
+		}
+		if p.Params == nil {
+			p.Params = make(map[string]string)
+		}
+		p.Params[paramKey] = strings.Trim(part, `"`)
+		paramKey = ""
+	}
+
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '=' && p.Name != "" && paramKey == "":
+			paramKey = strings.ToUpper(line[start:i])
+			start = i + 1
+		case c == ';':
+			flush(i)
+			start = i + 1
+		case c == ':':
+			flush(i)
+			p.Value = line[i+1:]
+			if p.Name == "" {
+				return p, errors.New("missing property name")
+			}
+			return p, nil
+		}
+	}
+	return p, fmt.Errorf("missing value in %q", line)
+}
+
+// UnescapeText reverses EscapeText
+func UnescapeText(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
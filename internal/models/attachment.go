@@ -0,0 +1,11 @@
+package models
+
+// Attachment represents a file attached to a task
+type Attachment struct {
+	ID          string `json:"id" db:"id"`                     // Unique identifier for the attachment
+	TaskID      string `json:"task_id" db:"task_id"`           // Task the file is attached to
+	Name        string `json:"name" db:"name"`                 // Original file name
+	ContentType string `json:"content_type" db:"content_type"` // MIME type detected from the contents
+	Size        int64  `json:"size" db:"size"`                 // Size in bytes
+	CreatedAt   string `json:"created_at" db:"created_at"`     // Upload time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "encoding/json"
+
+// AuditEntry represents a single change made to a task
+type AuditEntry struct {
+	ID     string          `json:"id"`               // Unique identifier for the entry
+	Time   string          `json:"time"`             // Time of the change
+	Action string          `json:"action"`           // Operation: create, update, delete, done, restore or purge
+	TaskID string          `json:"task_id"`          // Changed task
+	Actor  string          `json:"actor"`            // Who made the change
+	IP     string          `json:"ip"`               // Client IP address
+	Before json.RawMessage `json:"before,omitempty"` // Task state before the change
+	After  json.RawMessage `json:"after,omitempty"`  // Task state after the change
+}
@@ -0,0 +1,10 @@
+package models
+
+// ChecklistItem represents a step of a task
+type ChecklistItem struct {
+	ID       string `json:"id" db:"id"`             // Unique identifier for the item
+	TaskID   string `json:"task_id" db:"task_id"`   // Task the item belongs to
+	Position int    `json:"position" db:"position"` // Position of the item in the checklist, starting at 0
+	Text     string `json:"text" db:"text"`         // Item text
+	Done     bool   `json:"done" db:"done"`         // Whether the item has been checked
+}
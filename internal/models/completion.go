@@ -0,0 +1,11 @@
+package models
+
+// Completion represents a record of a task being completed
+type Completion struct {
+	ID            string `json:"id"`                                 // Unique identifier for the record
+	TaskID        string `json:"task_id" db:"task_id"`               // Completed task
+	Title         string `json:"title" db:"title"`                   // Task title at the time of completion
+	ScheduledDate string `json:"scheduled_date" db:"scheduled_date"` // Date the task was scheduled for
+	CompletedAt   string `json:"completed_at" db:"completed_at"`     // Time the task was actually completed
+	Note          string `json:"note" db:"note"`                     // Optional note about the completion
+}
@@ -0,0 +1,13 @@
+package models
+
+// Dependency represents a task that cannot start until another task is done
+type Dependency struct {
+	TaskID      string `json:"task_id" db:"task_id"`             // Blocked task
+	DependsOnID string `json:"depends_on_id" db:"depends_on_id"` // Task that has to be done first
+}
+
+// TaskGraph represents active tasks linked by dependencies
+type TaskGraph struct {
+	Nodes []*Task       `json:"nodes"` // Tasks taking part in dependencies
+	Edges []*Dependency `json:"edges"` // Dependencies between the tasks
+}
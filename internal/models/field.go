@@ -0,0 +1,17 @@
+package models
+
+// Custom field types
+const (
+	FieldText   = "text"   // Free text
+	FieldNumber = "number" // Decimal number
+	FieldDate   = "date"   // Date in the format "20060102"
+	FieldEnum   = "enum"   // One of the options of the field
+)
+
+// CustomField represents the definition of a custom task field
+type CustomField struct {
+	ID      string   `json:"id" db:"id"`               // Unique identifier for the field
+	Name    string   `json:"name" db:"name"`           // Field name used as the key of task values
+	Type    string   `json:"type" db:"type"`           // Value type: text, number, date or enum
+	Options []string `json:"options,omitempty" db:"-"` // Allowed values of an enum field
+}
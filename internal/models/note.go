@@ -0,0 +1,10 @@
+package models
+
+// Note represents a timestamped note left on a task
+type Note struct {
+	ID        string `json:"id" db:"id"`                           // Unique identifier for the note
+	TaskID    string `json:"task_id" db:"task_id"`                 // Task the note belongs to
+	Text      string `json:"text" db:"text"`                       // Note text
+	CreatedAt string `json:"created_at" db:"created_at"`           // Time the note was added
+	UpdatedAt string `json:"updated_at,omitempty" db:"updated_at"` // Time the note was last edited, empty if never
+}
@@ -0,0 +1,57 @@
+package models
+
+import "fmt"
+
+// Priority represents the importance of a task; higher values are more important
+type Priority int
+
+// Task priorities
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+// priorityNames - names of the priorities used in the API
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	if p < PriorityNone || int(p) >= len(priorityNames) {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority converts a priority name into a Priority; an empty name means no priority
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNone, nil
+	}
+	for i, n := range priorityNames {
+		if n == name {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q", name)
+}
+
+// MarshalText encodes the priority as its name
+func (p Priority) MarshalText() ([]byte, error) {
+	if p < PriorityNone || int(p) >= len(priorityNames) {
+		return nil, fmt.Errorf("invalid priority %d", int(p))
+	}
+	return []byte(priorityNames[p]), nil
+}
+
+// UnmarshalText decodes the priority from its name
+func (p *Priority) UnmarshalText(text []byte) error {
+	priority, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = priority
+	return nil
+}
@@ -0,0 +1,11 @@
+package models
+
+// Project represents a named list of tasks
+type Project struct {
+	ID            string `json:"id"`                                     // Unique identifier for the project
+	Name          string `json:"name" db:"name"`                         // Project name
+	Color         string `json:"color" db:"color"`                       // Color in the format "#rrggbb"
+	DefaultRepeat string `json:"default_repeat" db:"default_repeat"`     // Repetition rule for new tasks without one
+	ArchivedAt    string `json:"archived_at,omitempty" db:"archived_at"` // Time the project was archived
+	TaskCount     int    `json:"task_count" db:"task_count"`             // Number of active tasks in the project
+}
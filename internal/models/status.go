@@ -0,0 +1,39 @@
+package models
+
+// Task statuses
+const (
+	StatusTodo       = "todo"        // Not started yet
+	StatusInProgress = "in_progress" // Being worked on
+	StatusDone       = "done"        // Completed
+	StatusCancelled  = "cancelled"   // Will not be done
+)
+
+// statusTransitions - statuses a task can move to from each status.
+// Closed tasks can only be reopened.
+var statusTransitions = map[string][]string{
+	StatusTodo:       {StatusInProgress, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusDone, StatusCancelled},
+	StatusDone:       {StatusTodo},
+	StatusCancelled:  {StatusTodo},
+}
+
+// IsValidStatus checks whether a status is known
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CanTransition checks whether a task can move from one status to another
+func CanTransition(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosedStatus checks whether a status means the task needs no more work
+func IsClosedStatus(status string) bool {
+	return status == StatusDone || status == StatusCancelled
+}
@@ -0,0 +1,7 @@
+package models
+
+// TagCount represents a tag together with the number of tasks using it
+type TagCount struct {
+	Name  string `json:"name" db:"name"`   // Tag name
+	Count int    `json:"count" db:"count"` // Number of active tasks with the tag
+}
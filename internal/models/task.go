@@ -2,9 +2,19 @@ package models
 
 // Task represents a task in the scheduler
 type Task struct {
-	ID      string `json:"id"`                   // Unique identifier for the task
-	Date    string `json:"date" db:"date"`       // Task date
-	Title   string `json:"title" db:"title"`     // Task title
-	Comment string `json:"comment" db:"comment"` // Additional comment for the task
-	Repeat  string `json:"repeat" db:"repeat"`   // Task repetition rule
+	ID         string            `json:"id"`                                     // Unique identifier for the task
+	Date       string            `json:"date" db:"date"`                         // Task date
+	Title      string            `json:"title" db:"title"`                       // Task title
+	Comment    string            `json:"comment" db:"comment"`                   // Additional comment for the task
+	Repeat     string            `json:"repeat" db:"repeat"`                     // Task repetition rule
+	Priority   *Priority         `json:"priority,omitempty" db:"priority"`       // Task priority; nil leaves it unchanged on update
+	Status     string            `json:"status,omitempty" db:"status"`           // Task status; changed only through status transitions
+	CreatedAt  string            `json:"created_at,omitempty" db:"created_at"`   // Time the task was created
+	ProjectID  *string           `json:"project_id,omitempty" db:"project_id"`   // Project of the task; nil leaves it unchanged on update
+	Version    int64             `json:"-" db:"version"`                         // Revision number, exposed as the ETag header
+	DeletedAt  string            `json:"deleted_at,omitempty" db:"deleted_at"`   // Time the task was moved to the trash
+	Tags       []string          `json:"tags,omitempty" db:"-"`                  // Task tags; nil leaves the stored tags unchanged on update
+	Fields     map[string]string `json:"fields,omitempty" db:"-"`                // Custom field values by field name; nil leaves them unchanged on update
+	Blocked    bool              `json:"blocked,omitempty" db:"blocked"`         // Whether the task waits for tasks it depends on
+	NotesCount int               `json:"notes_count,omitempty" db:"notes_count"` // Number of notes on the task
 }
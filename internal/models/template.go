@@ -0,0 +1,16 @@
+package models
+
+// Template represents a saved blueprint for creating structured tasks.
+// Text fields may contain placeholders such as {{date}} or {{sprint}}.
+type Template struct {
+	ID         string    `json:"id" db:"id"`                           // Unique identifier for the template
+	Name       string    `json:"name" db:"name"`                       // Template name
+	Title      string    `json:"title" db:"title"`                     // Title of the created tasks
+	Comment    string    `json:"comment" db:"comment"`                 // Comment of the created tasks
+	Repeat     string    `json:"repeat" db:"repeat"`                   // Repetition rule of the created tasks
+	Priority   *Priority `json:"priority,omitempty" db:"priority"`     // Priority of the created tasks
+	ProjectID  *string   `json:"project_id,omitempty" db:"project_id"` // Project of the created tasks
+	DateOffset int       `json:"date_offset" db:"date_offset"`         // Task date in days after the anchor date
+	Tags       []string  `json:"tags,omitempty" db:"-"`                // Tags of the created tasks
+	Checklist  []string  `json:"checklist,omitempty" db:"-"`           // Checklist items of the created tasks
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// ErrAttachmentNotFound - the attachment does not exist
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// attachmentColumns - columns selected when reading attachments
+const attachmentColumns = `id, task_id, name, content_type, size, created_at`
+
+// AttachmentRepository - interface for attachment metadata
+type AttachmentRepository interface {
+	Create(attachment *models.Attachment) (string, error)
+	GetByID(id string) (*models.Attachment, error)
+	List(taskID string) ([]*models.Attachment, error)
+	Delete(id string) error
+	ListOrphaned() ([]*models.Attachment, error)
+}
+
+// attachmentRepository - implementation of the AttachmentRepository interface
+type attachmentRepository struct {
+	db dbtx
+}
+
+// NewAttachmentRepository - creates a new attachment repository
+func NewAttachmentRepository(db *DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+// Create - adds the metadata of a new attachment
+func (r *attachmentRepository) Create(attachment *models.Attachment) (string, error) {
+	attachment.CreatedAt = timestamp(time.Now())
+	query := `
+        INSERT INTO attachments (task_id, name, content_type, size, created_at)
+        VALUES (:task_id, :name, :content_type, :size, :created_at)
+    `
+	res, err := r.db.NamedExec(query, attachment)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	attachment.ID = fmt.Sprintf("%d", id)
+	return attachment.ID, nil
+}
+
+// GetByID - retrieves the metadata of an attachment by its ID
+func (r *attachmentRepository) GetByID(id string) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.Get(&attachment, `SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// List - retrieves the attachments of a task in upload order
+func (r *attachmentRepository) List(taskID string) ([]*models.Attachment, error) {
+	var attachments []*models.Attachment
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE task_id = ? ORDER BY id ASC`
+	if err := r.db.Select(&attachments, query, taskID); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Delete - removes the metadata of an attachment
+func (r *attachmentRepository) Delete(id string) error {
+	err := expectOneRow(r.db.Exec(`DELETE FROM attachments WHERE id = ?`, id))
+	if errors.Is(err, ErrNotFound) {
+		return ErrAttachmentNotFound
+	}
+	return err
+}
+
+// ListOrphaned - retrieves attachments of tasks that have been purged
+func (r *attachmentRepository) ListOrphaned() ([]*models.Attachment, error) {
+	var attachments []*models.Attachment
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE task_id NOT IN (SELECT id FROM scheduler)`
+	if err := r.db.Select(&attachments, query); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// AttachmentStore - storage for the contents of attachments
+type AttachmentStore interface {
+	Save(id string, data []byte) error
+	Open(id string) (io.ReadCloser, error)
+	Delete(id string) error
+}
+
+// fileStore - keeps attachments as files named after their IDs
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore - creates an attachment store in a directory, creating the directory if needed
+func NewFileStore(dir string) (AttachmentStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+// Save - writes the contents of an attachment
+func (s *fileStore) Save(id string, data []byte) error {
+	// The file is renamed into place only when it has been written completely
+	tmp, err := os.CreateTemp(s.dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// Open - opens the contents of an attachment for reading
+func (s *fileStore) Open(id string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrAttachmentNotFound
+	}
+	return file, err
+}
+
+// Delete - removes the contents of an attachment; missing files are ignored
+func (s *fileStore) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path - file holding the contents of an attachment
+func (s *fileStore) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// blobStore - keeps attachments as blobs in the database
+type blobStore struct {
+	db dbtx
+}
+
+// NewBlobStore - creates an attachment store in the database
+func NewBlobStore(db *DB) AttachmentStore {
+	return &blobStore{db: db}
+}
+
+// Save - writes the contents of an attachment
+func (s *blobStore) Save(id string, data []byte) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO attachment_blobs (attachment_id, data) VALUES (?, ?)`, id, data)
+	return err
+}
+
+// Open - opens the contents of an attachment for reading
+func (s *blobStore) Open(id string) (io.ReadCloser, error) {
+	var data []byte
+	err := s.db.Get(&data, `SELECT data FROM attachment_blobs WHERE attachment_id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete - removes the contents of an attachment
+func (s *blobStore) Delete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM attachment_blobs WHERE attachment_id = ?`, id)
+	return err
+}
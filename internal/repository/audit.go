@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// AuditFilter - conditions for selecting audit entries
+type AuditFilter struct {
+	TaskID string // Only changes of this task
+	Action string // Only this operation
+	Actor  string // Only changes made by this actor
+	From   string // Changes made at or after this time (RFC 3339)
+	To     string // Changes made before this time (RFC 3339)
+	Limit  int    // Maximum number of entries, 0 means no limit
+}
+
+// AuditRepository - interface for the audit trail
+type AuditRepository interface {
+	Create(entry *models.AuditEntry) error
+	Each(filter AuditFilter, fn func(entry *models.AuditEntry) error) error
+}
+
+// auditRepository - implementation of the AuditRepository interface
+type auditRepository struct {
+	db dbtx
+}
+
+// auditRow - audit entry as stored in the database
+type auditRow struct {
+	ID     string `db:"id"`
+	Time   string `db:"time"`
+	Action string `db:"action"`
+	TaskID string `db:"task_id"`
+	Actor  string `db:"actor"`
+	IP     string `db:"ip"`
+	Before string `db:"before"`
+	After  string `db:"after"`
+}
+
+// NewAuditRepository - creates a new audit trail repository
+func NewAuditRepository(db *DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create - appends an entry to the audit trail
+func (r *auditRepository) Create(entry *models.AuditEntry) error {
+	row := auditRow{
+		Time:   entry.Time,
+		Action: entry.Action,
+		TaskID: entry.TaskID,
+		Actor:  entry.Actor,
+		IP:     entry.IP,
+		Before: string(entry.Before),
+		After:  string(entry.After),
+	}
+	query := `
+        INSERT INTO audit_log (time, action, task_id, actor, ip, before, after)
+        VALUES (:time, :action, :task_id, :actor, :ip, :before, :after)
+    `
+	res, err := r.db.NamedExec(query, row)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = fmt.Sprintf("%d", id)
+	return nil
+}
+
+// Each - calls fn for every entry matching the filter, oldest first.
+// Entries are streamed from the database, so large exports do not have to fit in memory.
+func (r *auditRepository) Each(filter AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	limit := filter.Limit
+	if limit == 0 {
+		limit = -1 // No limit in SQLite
+	}
+
+	query := `
+        SELECT id, time, action, task_id, actor, ip, before, after
+        FROM audit_log
+        WHERE (:task_id = '' OR task_id = :task_id)
+          AND (:action = '' OR action = :action)
+          AND (:actor = '' OR actor = :actor)
+          AND (:from = '' OR time >= :from)
+          AND (:to = '' OR time < :to)
+        ORDER BY id ASC
+        LIMIT :limit
+    `
+	params := map[string]interface{}{
+		"task_id": filter.TaskID,
+		"action":  filter.Action,
+		"actor":   filter.Actor,
+		"from":    filter.From,
+		"to":      filter.To,
+		"limit":   limit,
+	}
+
+	rows, err := r.db.NamedQuery(query, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row auditRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		entry := &models.AuditEntry{
+			ID:     row.ID,
+			Time:   row.Time,
+			Action: row.Action,
+			TaskID: row.TaskID,
+			Actor:  row.Actor,
+			IP:     row.IP,
+		}
+		if row.Before != "" {
+			entry.Before = []byte(row.Before)
+		}
+		if row.After != "" {
+			entry.After = []byte(row.After)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvalidBackup - the file is not a valid copy of the task database
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// BackupRepository - interface for copying the database while it is in use
+type BackupRepository interface {
+	VacuumInto(path string) error
+}
+
+// backupRepository - implementation of the BackupRepository interface
+type backupRepository struct {
+	db *DB
+}
+
+// NewBackupRepository - creates a new backup repository
+func NewBackupRepository(db *DB) BackupRepository {
+	return &backupRepository{db: db}
+}
+
+// VacuumInto - writes a consistent, compacted copy of the database to a new file.
+// Queries keep being served while the copy is made; changes wait for it on the writer connection.
+func (r *backupRepository) VacuumInto(path string) error {
+	_, err := r.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// VerifyDatabase - checks that the file is an intact task database
+func VerifyDatabase(path string) error {
+	db, err := sqlx.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.Get(&result, "PRAGMA integrity_check"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	var exists int
+	if err := db.Get(&exists, "SELECT count(*) FROM sqlite_master WHERE type='table' AND name='scheduler'"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("%w: no task table", ErrInvalidBackup)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+var (
+	// ErrChecklistItemNotFound - the checklist item does not exist
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	// ErrChecklistOrder - the new order does not list every item of the checklist exactly once
+	ErrChecklistOrder = errors.New("the new order must list every checklist item exactly once")
+)
+
+// checklistColumns - columns selected when reading checklist items
+const checklistColumns = `id, task_id, position, text, done`
+
+// ChecklistRepository - interface for checklist operations
+type ChecklistRepository interface {
+	Create(item *models.ChecklistItem) (string, error)
+	GetByID(id string) (*models.ChecklistItem, error)
+	List(taskID string) ([]*models.ChecklistItem, error)
+	SetDone(id string, done bool) error
+	Delete(id string) error
+	Reorder(taskID string, ids []string) error
+	Reset(taskID string) error
+}
+
+// checklistRepository - implementation of the ChecklistRepository interface
+type checklistRepository struct {
+	db dbtx
+}
+
+// NewChecklistRepository - creates a new checklist repository
+func NewChecklistRepository(db *DB) ChecklistRepository {
+	return &checklistRepository{db: db}
+}
+
+// Create - appends an item to the end of the task's checklist
+func (r *checklistRepository) Create(item *models.ChecklistItem) (string, error) {
+	query := `
+        INSERT INTO checklist_items (task_id, position, text, done)
+        SELECT :task_id, COALESCE(MAX(position) + 1, 0), :text, :done
+        FROM checklist_items WHERE task_id = :task_id
+    `
+	res, err := r.db.NamedExec(query, item)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	item.ID = fmt.Sprintf("%d", id)
+	return item.ID, nil
+}
+
+// GetByID - retrieves a checklist item by its ID
+func (r *checklistRepository) GetByID(id string) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	err := r.db.Get(&item, `SELECT `+checklistColumns+` FROM checklist_items WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChecklistItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// List - retrieves the checklist of a task in order
+func (r *checklistRepository) List(taskID string) ([]*models.ChecklistItem, error) {
+	var items []*models.ChecklistItem
+	query := `SELECT ` + checklistColumns + ` FROM checklist_items WHERE task_id = ? ORDER BY position ASC, id ASC`
+	if err := r.db.Select(&items, query, taskID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SetDone - checks or unchecks a checklist item
+func (r *checklistRepository) SetDone(id string, done bool) error {
+	return expectItem(r.db.Exec(`UPDATE checklist_items SET done = ? WHERE id = ?`, done, id))
+}
+
+// Delete - removes a checklist item
+func (r *checklistRepository) Delete(id string) error {
+	return expectItem(r.db.Exec(`DELETE FROM checklist_items WHERE id = ?`, id))
+}
+
+// Reorder - sets the order of a task's checklist; ids must list every item of the checklist exactly once
+func (r *checklistRepository) Reorder(taskID string, ids []string) error {
+	if len(ids) == 0 {
+		var count int
+		if err := r.db.Get(&count, `SELECT count(*) FROM checklist_items WHERE task_id = ?`, taskID); err != nil {
+			return err
+		}
+		if count != 0 {
+			return ErrChecklistOrder
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool, len(ids))
+	cases := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, 3*len(ids)+3)
+	for position, id := range ids {
+		if seen[id] {
+			return ErrChecklistOrder
+		}
+		seen[id] = true
+		cases = append(cases, "WHEN ? THEN ?")
+		args = append(args, id, position)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args = append(args, taskID, taskID, len(ids), taskID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, len(ids))
+
+	// All positions are updated by a single statement, which checks the items of the checklist when it runs,
+	// so that an item added or deleted meanwhile fails the reorder instead of getting a stale position
+	query := `
+        UPDATE checklist_items SET position = CASE id ` + strings.Join(cases, " ") + ` END
+        WHERE task_id = ?
+          AND (SELECT count(*) FROM checklist_items WHERE task_id = ?) = ?
+          AND (SELECT count(*) FROM checklist_items WHERE task_id = ? AND id IN (` + placeholders + `)) = ?
+    `
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrChecklistOrder
+	}
+	return nil
+}
+
+// Reset - unchecks all items of a task's checklist
+func (r *checklistRepository) Reset(taskID string) error {
+	_, err := r.db.Exec(`UPDATE checklist_items SET done = 0 WHERE task_id = ? AND done != 0`, taskID)
+	return err
+}
+
+// expectItem - converts the result of a statement addressing a single checklist item into an error
+func expectItem(result sql.Result, err error) error {
+	if err := expectOneRow(result, err); errors.Is(err, ErrNotFound) {
+		return ErrChecklistItemNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
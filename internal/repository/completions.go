@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// CompletionFilter - conditions for selecting completion records
+type CompletionFilter struct {
+	TaskID string // Only completions of this task
+	From   string // Completed at or after this time (RFC 3339)
+	To     string // Completed before this time (RFC 3339)
+	Limit  int    // Maximum number of records
+}
+
+// CompletionRepository - interface for task completion history
+type CompletionRepository interface {
+	Create(completion *models.Completion) (string, error)
+	List(filter CompletionFilter) ([]*models.Completion, error)
+}
+
+// completionRepository - implementation of the CompletionRepository interface
+type completionRepository struct {
+	db dbtx
+}
+
+// NewCompletionRepository - creates a new completion history repository
+func NewCompletionRepository(db *DB) CompletionRepository {
+	return &completionRepository{db: db}
+}
+
+// Create - records a task completion
+func (r *completionRepository) Create(completion *models.Completion) (string, error) {
+	query := `
+        INSERT INTO completions (task_id, title, scheduled_date, completed_at, note)
+        VALUES (:task_id, :title, :scheduled_date, :completed_at, :note)
+    `
+	res, err := r.db.NamedExec(query, completion)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	completion.ID = fmt.Sprintf("%d", id)
+	return completion.ID, nil
+}
+
+// List - retrieves completion records, most recent first
+func (r *completionRepository) List(filter CompletionFilter) ([]*models.Completion, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+
+	query := `
+        SELECT id, task_id, title, scheduled_date, completed_at, note
+        FROM completions
+        WHERE (:task_id = '' OR task_id = :task_id)
+          AND (:from = '' OR completed_at >= :from)
+          AND (:to = '' OR completed_at < :to)
+        ORDER BY completed_at DESC, id DESC
+        LIMIT :limit
+    `
+	params := map[string]interface{}{
+		"task_id": filter.TaskID,
+		"from":    filter.From,
+		"to":      filter.To,
+		"limit":   filter.Limit,
+	}
+
+	rows, err := r.db.NamedQuery(query, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var completions []*models.Completion
+	for rows.Next() {
+		var completion models.Completion
+		if err := rows.StructScan(&completion); err != nil {
+			return nil, err
+		}
+		completions = append(completions, &completion)
+	}
+	return completions, rows.Err()
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DBOptions - connection settings of the database
+type DBOptions struct {
+	BusyTimeout time.Duration // How long a connection waits for a lock held by another process, such as a command
+	MaxReaders  int           // Maximum number of connections reading at the same time
+}
+
+// DB - the task database with separate connection pools for writing and reading.
+// SQLite allows one writer at a time, so changes go through a pool with a single connection, where they wait
+// for each other instead of failing with "database is locked". In WAL mode queries run alongside the writer,
+// so they are served by a pool of read-only connections.
+type DB struct {
+	*sqlx.DB          // Writer pool, also used for transactions
+	reader   *sqlx.DB // Pool of read-only connections
+}
+
+// openDB - opens the writer and reader pools of the database file
+func openDB(dbPath string, options DBOptions) (*DB, error) {
+	busyTimeout := fmt.Sprintf("busy_timeout(%d)", options.BusyTimeout.Milliseconds())
+
+	// The writer switches the file to WAL mode before any reader opens it. Transactions take the write lock
+	// when they begin, so that another process writing meanwhile makes them wait rather than fail midway.
+	writer, err := sqlx.Open("sqlite", dsn(dbPath, url.Values{
+		"_pragma": {busyTimeout, "journal_mode(WAL)", "synchronous(NORMAL)", "foreign_keys(1)"},
+		"_txlock": {"immediate"},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	if err := writer.Ping(); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	reader, err := sqlx.Open("sqlite", dsn(dbPath, url.Values{
+		"_pragma": {busyTimeout, "query_only(1)"},
+	}))
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	reader.SetMaxOpenConns(options.MaxReaders)
+	reader.SetMaxIdleConns(options.MaxReaders)
+	if err := reader.Ping(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+
+	return &DB{DB: writer, reader: reader}, nil
+}
+
+// dsn - builds the data source name of the database file with connection parameters
+func dsn(dbPath string, params url.Values) string {
+	return dbPath + "?" + params.Encode()
+}
+
+// Get - runs a query returning a single row on a reader connection
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.reader.Get(dest, query, args...)
+}
+
+// Select - runs a query returning rows on a reader connection
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.reader.Select(dest, query, args...)
+}
+
+// Queryx - runs a query on a reader connection; the connection is held until the rows are closed
+func (db *DB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return db.reader.Queryx(query, args...)
+}
+
+// NamedQuery - runs a query with named parameters on a reader connection
+func (db *DB) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
+	return db.reader.NamedQuery(query, arg)
+}
+
+// QueryRowx - runs a statement returning a single row on the writer connection,
+// for changes that report values with RETURNING
+func (db *DB) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return db.DB.QueryRowx(query, args...)
+}
+
+// Close - closes both connection pools
+func (db *DB) Close() error {
+	return errors.Join(db.reader.Close(), db.DB.Close())
+}
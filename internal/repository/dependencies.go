@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// ErrDependencyCycle - the dependency would make a task depend on itself
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+// blockedColumn - whether a task has prerequisites that are still pending.
+// A prerequisite stops blocking once it is done: one-off tasks are closed or leave the active list,
+// repeating tasks record a completion after the dependency was added.
+const blockedColumn = `EXISTS (
+        SELECT 1
+        FROM task_dependencies d
+        JOIN scheduler p ON p.id = d.depends_on_id
+        WHERE d.task_id = scheduler.id
+          AND p.deleted_at IS NULL
+          AND p.status NOT IN ('done', 'cancelled')
+          AND NOT EXISTS (SELECT 1 FROM completions c WHERE c.task_id = d.depends_on_id AND c.id > d.since)
+    ) AS blocked`
+
+// DependencyRepository - interface for dependencies between tasks
+type DependencyRepository interface {
+	Link(taskID, dependsOnID string) error
+	Unlink(taskID, dependsOnID string) error
+	Dependents(taskID string) ([]string, error)
+	ListActive() ([]*models.Dependency, error)
+	List() ([]*models.Dependency, error)
+}
+
+// dependencyRepository - implementation of the DependencyRepository interface
+type dependencyRepository struct {
+	db dbtx
+}
+
+// NewDependencyRepository - creates a new dependency repository
+func NewDependencyRepository(db *DB) DependencyRepository {
+	return &dependencyRepository{db: db}
+}
+
+// Link - makes a task depend on another one; linking tasks that are already linked does nothing.
+// The cycle check and the insert are a single statement, so concurrent links cannot create a cycle.
+func (r *dependencyRepository) Link(taskID, dependsOnID string) error {
+	if taskID == dependsOnID {
+		return ErrDependencyCycle
+	}
+
+	query := `
+        WITH RECURSIVE prerequisites(id) AS (
+            SELECT depends_on_id FROM task_dependencies WHERE task_id = ?
+            UNION
+            SELECT d.depends_on_id FROM task_dependencies d JOIN prerequisites p ON d.task_id = p.id
+        )
+        INSERT OR IGNORE INTO task_dependencies (task_id, depends_on_id, since)
+        SELECT ?, ?, (SELECT COALESCE(MAX(id), 0) FROM completions)
+        WHERE ? NOT IN (SELECT id FROM prerequisites)
+    `
+	result, err := r.db.Exec(query, dependsOnID, taskID, dependsOnID, taskID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected > 0 {
+		return err
+	}
+
+	// Nothing was inserted: either the link already exists or it would close a cycle
+	var exists int
+	err = r.db.Get(&exists, `SELECT count(*) FROM task_dependencies WHERE task_id = ? AND depends_on_id = ?`, taskID, dependsOnID)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrDependencyCycle
+	}
+	return nil
+}
+
+// Unlink - removes a dependency between two tasks
+func (r *dependencyRepository) Unlink(taskID, dependsOnID string) error {
+	return expectOneRow(r.db.Exec(`DELETE FROM task_dependencies WHERE task_id = ? AND depends_on_id = ?`, taskID, dependsOnID))
+}
+
+// Dependents - retrieves the IDs of open tasks depending on a task
+func (r *dependencyRepository) Dependents(taskID string) ([]string, error) {
+	var ids []string
+	query := `
+        SELECT d.task_id
+        FROM task_dependencies d
+        JOIN scheduler s ON s.id = d.task_id AND s.deleted_at IS NULL AND s.status NOT IN ('done', 'cancelled')
+        WHERE d.depends_on_id = ?
+        ORDER BY d.task_id
+    `
+	if err := r.db.Select(&ids, query, taskID); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListActive - retrieves dependencies between tasks that are both open
+func (r *dependencyRepository) ListActive() ([]*models.Dependency, error) {
+	var dependencies []*models.Dependency
+	query := `
+        SELECT d.task_id, d.depends_on_id
+        FROM task_dependencies d
+        JOIN scheduler s ON s.id = d.task_id AND s.deleted_at IS NULL AND s.status NOT IN ('done', 'cancelled')
+        JOIN scheduler p ON p.id = d.depends_on_id AND p.deleted_at IS NULL AND p.status NOT IN ('done', 'cancelled')
+        ORDER BY d.depends_on_id, d.task_id
+    `
+	if err := r.db.Select(&dependencies, query); err != nil {
+		return nil, err
+	}
+	return dependencies, nil
+}
+
+// List - retrieves all dependencies between tasks outside the trash
+func (r *dependencyRepository) List() ([]*models.Dependency, error) {
+	var dependencies []*models.Dependency
+	query := `
+        SELECT d.task_id, d.depends_on_id
+        FROM task_dependencies d
+        JOIN scheduler s ON s.id = d.task_id AND s.deleted_at IS NULL
+        JOIN scheduler p ON p.id = d.depends_on_id AND p.deleted_at IS NULL
+        ORDER BY d.task_id, d.depends_on_id
+    `
+	if err := r.db.Select(&dependencies, query); err != nil {
+		return nil, err
+	}
+	return dependencies, nil
+}
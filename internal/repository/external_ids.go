@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ExternalIDRepository - interface for links between imported tasks and their IDs in the source they came from
+type ExternalIDRepository interface {
+	TaskID(source, externalID string) (string, error)
+	ExternalID(source, taskID string) (string, error)
+	ExternalIDs(source string) (map[string]string, error)
+	Link(source, externalID, taskID string) error
+}
+
+// externalIDRepository - implementation of the ExternalIDRepository interface
+type externalIDRepository struct {
+	db dbtx
+}
+
+// NewExternalIDRepository - creates a new external ID repository
+func NewExternalIDRepository(db *DB) ExternalIDRepository {
+	return &externalIDRepository{db: db}
+}
+
+// TaskID - returns the ID of the task imported under the external ID, or an empty string if there is none
+func (r *externalIDRepository) TaskID(source, externalID string) (string, error) {
+	var taskID string
+	err := r.db.Get(&taskID, `
+        SELECT e.task_id FROM external_ids e
+        JOIN scheduler s ON s.id = e.task_id
+        WHERE e.source = ? AND e.external_id = ?`, source, externalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return taskID, err
+}
+
+// ExternalID - returns the external ID the task was imported under, or an empty string if there is none
+func (r *externalIDRepository) ExternalID(source, taskID string) (string, error) {
+	var externalID string
+	err := r.db.Get(&externalID, `SELECT external_id FROM external_ids WHERE source = ? AND task_id = ?`, source, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return externalID, err
+}
+
+// ExternalIDs - returns the external IDs of all tasks imported from the source, keyed by task ID
+func (r *externalIDRepository) ExternalIDs(source string) (map[string]string, error) {
+	var links []struct {
+		TaskID     string `db:"task_id"`
+		ExternalID string `db:"external_id"`
+	}
+	if err := r.db.Select(&links, `SELECT task_id, external_id FROM external_ids WHERE source = ?`, source); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(links))
+	for _, link := range links {
+		ids[link.TaskID] = link.ExternalID
+	}
+	return ids, nil
+}
+
+// Link - records that the task was imported under the external ID, replacing an earlier link
+func (r *externalIDRepository) Link(source, externalID, taskID string) error {
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO external_ids (source, external_id, task_id) VALUES (?, ?, ?)`, source, externalID, taskID)
+	return err
+}
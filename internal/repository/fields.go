@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrFieldNotFound - the custom field does not exist
+	ErrFieldNotFound = errors.New("custom field not found")
+	// ErrFieldExists - another custom field has the same name
+	ErrFieldExists = errors.New("custom field with this name already exists")
+)
+
+// fieldRow - custom field as stored in the database, with the enum options encoded as JSON
+type fieldRow struct {
+	ID      string `db:"id"`
+	Name    string `db:"name"`
+	Type    string `db:"type"`
+	Options string `db:"options"`
+}
+
+// FieldRepository - interface for custom field definitions
+type FieldRepository interface {
+	Create(field *models.CustomField) (string, error)
+	GetByID(id string) (*models.CustomField, error)
+	GetByName(name string) (*models.CustomField, error)
+	Update(field *models.CustomField) error
+	Delete(id string) error
+	List() ([]*models.CustomField, error)
+}
+
+// fieldRepository - implementation of the FieldRepository interface
+type fieldRepository struct {
+	db dbtx
+}
+
+// NewFieldRepository - creates a new custom field repository
+func NewFieldRepository(db *DB) FieldRepository {
+	return &fieldRepository{db: db}
+}
+
+// Create - adds a new custom field definition
+func (r *fieldRepository) Create(field *models.CustomField) (string, error) {
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := r.db.Exec(`INSERT INTO custom_fields (name, type, options) VALUES (?, ?, ?)`, field.Name, field.Type, string(options))
+	if isUniqueViolation(err) {
+		return "", ErrFieldExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	field.ID = fmt.Sprintf("%d", id)
+	return field.ID, nil
+}
+
+// GetByID - retrieves a custom field by its ID
+func (r *fieldRepository) GetByID(id string) (*models.CustomField, error) {
+	return r.get(`SELECT id, name, type, options FROM custom_fields WHERE id = ?`, id)
+}
+
+// GetByName - retrieves a custom field by its name
+func (r *fieldRepository) GetByName(name string) (*models.CustomField, error) {
+	return r.get(`SELECT id, name, type, options FROM custom_fields WHERE name = ?`, name)
+}
+
+// Update - changes the name and options of a custom field; the type cannot be changed
+func (r *fieldRepository) Update(field *models.CustomField) error {
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`UPDATE custom_fields SET name = ?, options = ? WHERE id = ?`, field.Name, string(options), field.ID)
+	if isUniqueViolation(err) {
+		return ErrFieldExists
+	}
+	return expectField(result, err)
+}
+
+// Delete - removes a custom field together with its values
+func (r *fieldRepository) Delete(id string) error {
+	if err := expectField(r.db.Exec(`DELETE FROM custom_fields WHERE id = ?`, id)); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`DELETE FROM task_field_values WHERE field_id = ?`, id)
+	return err
+}
+
+// List - retrieves all custom fields ordered by name
+func (r *fieldRepository) List() ([]*models.CustomField, error) {
+	var rows []fieldRow
+	if err := r.db.Select(&rows, `SELECT id, name, type, options FROM custom_fields ORDER BY name ASC`); err != nil {
+		return nil, err
+	}
+
+	fields := make([]*models.CustomField, 0, len(rows))
+	for _, row := range rows {
+		field, err := row.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// get - retrieves a single custom field
+func (r *fieldRepository) get(query string, arg interface{}) (*models.CustomField, error) {
+	var row fieldRow
+	err := r.db.Get(&row, query, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFieldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.field()
+}
+
+// field - decodes a stored custom field
+func (row fieldRow) field() (*models.CustomField, error) {
+	field := &models.CustomField{ID: row.ID, Name: row.Name, Type: row.Type}
+	if err := json.Unmarshal([]byte(row.Options), &field.Options); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// expectField - converts the result of a statement addressing a single custom field into an error
+func expectField(result sql.Result, err error) error {
+	if err := expectOneRow(result, err); errors.Is(err, ErrNotFound) {
+		return ErrFieldNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetFields - replaces the custom field values of a task; values are keyed by field name
+func (r *taskRepository) SetFields(taskID string, values map[string]string) error {
+	if _, err := r.db.Exec(`DELETE FROM task_field_values WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO task_field_values (task_id, field_id, value)
+        SELECT ?, id, ? FROM custom_fields WHERE name = ?
+    `
+	for name, value := range values {
+		if _, err := r.db.Exec(query, taskID, value, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFields - fills in the custom field values of the given tasks
+func (r *taskRepository) loadFields(tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(tasks))
+	byID := make(map[string]*models.Task, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+		byID[task.ID] = task
+	}
+
+	query, args, err := sqlx.In(`
+        SELECT v.task_id, f.name, v.value
+        FROM task_field_values v
+        JOIN custom_fields f ON f.id = v.field_id
+        WHERE v.task_id IN (?)`, ids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, name, value string
+		if err := rows.Scan(&taskID, &name, &value); err != nil {
+			return err
+		}
+		if task, ok := byID[taskID]; ok {
+			if task.Fields == nil {
+				task.Fields = make(map[string]string)
+			}
+			task.Fields[name] = value
+		}
+	}
+	return rows.Err()
+}
+
+// fieldOrder - ORDER BY expression sorting tasks by a custom field, with tasks lacking a value last.
+// The expression takes the field name twice as arguments.
+func (r *taskRepository) fieldOrder(name string) (string, error) {
+	var fieldType string
+	err := r.db.Get(&fieldType, `SELECT type FROM custom_fields WHERE name = ?`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidSort
+	}
+	if err != nil {
+		return "", err
+	}
+
+	value := `(
+        SELECT v.value
+        FROM task_field_values v
+        JOIN custom_fields f ON f.id = v.field_id
+        WHERE v.task_id = scheduler.id AND f.name = ?)`
+	sortValue := value
+	switch fieldType {
+	case models.FieldNumber:
+		sortValue = `CAST(` + value + ` AS REAL)`
+	case models.FieldEnum:
+		// Enum values sort in the order of the field options
+		sortValue = `(
+        SELECT o.key
+        FROM task_field_values v
+        JOIN custom_fields f ON f.id = v.field_id, json_each(f.options) o
+        WHERE v.task_id = scheduler.id AND f.name = ? AND o.value = v.value)`
+	}
+	return value + ` IS NULL, ` + sortValue, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// ErrNoteNotFound - the note does not exist
+var ErrNoteNotFound = errors.New("note not found")
+
+// noteColumns - columns selected when reading notes
+const noteColumns = `id, task_id, text, created_at, COALESCE(updated_at, '') AS updated_at`
+
+// notesCountColumn - number of notes on a task, selected together with the task
+const notesCountColumn = `(SELECT count(*) FROM task_notes n WHERE n.task_id = scheduler.id) AS notes_count`
+
+// noteTextColumn - texts of all notes on a task, selected for text search
+const noteTextColumn = `COALESCE((SELECT group_concat(n.text, char(10)) FROM task_notes n WHERE n.task_id = scheduler.id), '') AS note_text`
+
+// NoteRepository - interface for task notes
+type NoteRepository interface {
+	Create(note *models.Note) (string, error)
+	GetByID(id string) (*models.Note, error)
+	List(taskID string) ([]*models.Note, error)
+	Update(id, text string) error
+	Delete(id string) error
+}
+
+// noteRepository - implementation of the NoteRepository interface
+type noteRepository struct {
+	db dbtx
+}
+
+// NewNoteRepository - creates a new note repository
+func NewNoteRepository(db *DB) NoteRepository {
+	return &noteRepository{db: db}
+}
+
+// Create - adds a note to a task
+func (r *noteRepository) Create(note *models.Note) (string, error) {
+	note.CreatedAt = timestamp(time.Now())
+	res, err := r.db.NamedExec(`INSERT INTO task_notes (task_id, text, created_at) VALUES (:task_id, :text, :created_at)`, note)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	note.ID = fmt.Sprintf("%d", id)
+	return note.ID, nil
+}
+
+// GetByID - retrieves a note by its ID
+func (r *noteRepository) GetByID(id string) (*models.Note, error) {
+	var note models.Note
+	err := r.db.Get(&note, `SELECT `+noteColumns+` FROM task_notes WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// List - retrieves the notes of a task, oldest first
+func (r *noteRepository) List(taskID string) ([]*models.Note, error) {
+	var notes []*models.Note
+	query := `SELECT ` + noteColumns + ` FROM task_notes WHERE task_id = ? ORDER BY created_at ASC, id ASC`
+	if err := r.db.Select(&notes, query, taskID); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// Update - replaces the text of a note and records the time of the edit
+func (r *noteRepository) Update(id, text string) error {
+	return expectNote(r.db.Exec(`UPDATE task_notes SET text = ?, updated_at = ? WHERE id = ?`, text, timestamp(time.Now()), id))
+}
+
+// Delete - removes a note
+func (r *noteRepository) Delete(id string) error {
+	return expectNote(r.db.Exec(`DELETE FROM task_notes WHERE id = ?`, id))
+}
+
+// expectNote - converts the result of a statement addressing a single note into an error
+func expectNote(result sql.Result, err error) error {
+	if err := expectOneRow(result, err); errors.Is(err, ErrNotFound) {
+		return ErrNoteNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
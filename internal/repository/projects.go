@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+var (
+	// ErrProjectNotFound - the project does not exist
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectExists - another project has the same name
+	ErrProjectExists = errors.New("project with this name already exists")
+)
+
+// projectColumns - columns selected when reading projects
+const projectColumns = `
+    p.id, p.name, p.color, p.default_repeat, COALESCE(p.archived_at, '') AS archived_at,
+    (SELECT count(*) FROM scheduler s
+     WHERE s.project_id = p.id AND s.deleted_at IS NULL AND s.status NOT IN ('done', 'cancelled')) AS task_count`
+
+// ProjectRepository - interface for project operations
+type ProjectRepository interface {
+	Create(project *models.Project) (string, error)
+	GetByID(id string) (*models.Project, error)
+	Update(project *models.Project) error
+	Delete(id string) error
+	List(includeArchived bool) ([]*models.Project, error)
+	SetArchived(id string, archived bool) error
+	MoveTasks(fromID, toID string) (int64, error)
+	CountTasks(id string) (int, error)
+}
+
+// projectRepository - implementation of the ProjectRepository interface
+type projectRepository struct {
+	db dbtx
+}
+
+// NewProjectRepository - creates a new project repository
+func NewProjectRepository(db *DB) ProjectRepository {
+	return &projectRepository{db: db}
+}
+
+// Create - adds a new project to the database
+func (r *projectRepository) Create(project *models.Project) (string, error) {
+	query := `
+        INSERT INTO projects (name, color, default_repeat)
+        VALUES (:name, :color, :default_repeat)
+    `
+	res, err := r.db.NamedExec(query, project)
+	if isUniqueViolation(err) {
+		return "", ErrProjectExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	project.ID = fmt.Sprintf("%d", id)
+	return project.ID, nil
+}
+
+// GetByID - retrieves a project by its ID, including archived projects
+func (r *projectRepository) GetByID(id string) (*models.Project, error) {
+	var project models.Project
+	err := r.db.Get(&project, `SELECT `+projectColumns+` FROM projects p WHERE p.id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Update - updates the name, color and default repetition rule of a project
+func (r *projectRepository) Update(project *models.Project) error {
+	query := `
+        UPDATE projects
+        SET name = :name, color = :color, default_repeat = :default_repeat
+        WHERE id = :id
+    `
+	result, err := r.db.NamedExec(query, project)
+	if isUniqueViolation(err) {
+		return ErrProjectExists
+	}
+	return expectProject(result, err)
+}
+
+// Delete - deletes a project; its tasks must have been moved beforehand
+func (r *projectRepository) Delete(id string) error {
+	return expectProject(r.db.Exec(`DELETE FROM projects WHERE id = ?`, id))
+}
+
+// List - retrieves projects ordered by name
+func (r *projectRepository) List(includeArchived bool) ([]*models.Project, error) {
+	var projects []*models.Project
+	query := `
+        SELECT ` + projectColumns + `
+        FROM projects p
+        WHERE ? OR p.archived_at IS NULL
+        ORDER BY p.name ASC
+    `
+	if err := r.db.Select(&projects, query, includeArchived); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// SetArchived - archives or unarchives a project
+func (r *projectRepository) SetArchived(id string, archived bool) error {
+	var archivedAt interface{}
+	if archived {
+		archivedAt = timestamp(time.Now())
+	}
+	return expectProject(r.db.Exec(`UPDATE projects SET archived_at = ? WHERE id = ?`, archivedAt, id))
+}
+
+// MoveTasks - moves all tasks, including deleted ones, to another project or out of any project if toID is empty
+func (r *projectRepository) MoveTasks(fromID, toID string) (int64, error) {
+	query := `
+        UPDATE scheduler
+        SET project_id = NULLIF(?, ''), version = version + 1
+        WHERE project_id = ?
+    `
+	result, err := r.db.Exec(query, toID, fromID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountTasks - counts all tasks referring to a project, including done, cancelled and deleted ones
+func (r *projectRepository) CountTasks(id string) (int, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT count(*) FROM scheduler WHERE project_id = ?`, id)
+	return count, err
+}
+
+// expectProject - converts the result of a statement addressing a single project into an error
+func expectProject(result sql.Result, err error) error {
+	if err := expectOneRow(result, err); errors.Is(err, ErrNotFound) {
+		return ErrProjectNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// isUniqueViolation - checks whether an error is caused by a UNIQUE constraint
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
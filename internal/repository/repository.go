@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,27 +18,57 @@ import (
 
 const defaultLimit = 50 // Default limit value
 
+// eachBatchSize - number of tasks read at once by Each
+const eachBatchSize = 500
+
+// taskColumns - columns selected when reading tasks
+const taskColumns = `id, date, title, comment, repeat, priority, status, COALESCE(created_at, '') AS created_at,
+    project_id, version, COALESCE(deleted_at, '') AS deleted_at, ` + blockedColumn + `, ` + notesCountColumn
+
+var (
+	// ErrNotFound - the task does not exist
+	ErrNotFound = errors.New("task not found")
+	// ErrVersionConflict - the stored task version differs from the expected one
+	ErrVersionConflict = errors.New("task has been modified by another request")
+	// ErrInvalidSort - the requested sort key is not supported
+	ErrInvalidSort = errors.New("invalid sort key, expected date, priority, title, created or field.<name>")
+)
+
 // TaskRepository - interface for task operations
 type TaskRepository interface {
 	Create(task *models.Task) (string, error)
 	GetByID(id string) (*models.Task, error)
 	Update(task *models.Task) error
-	Delete(id string) error
-	List(search string, limit int) ([]*models.Task, error)
+	SetStatus(id, status string, version int64) error
+	Delete(id string, version int64) error
+	List(filter TaskFilter) ([]*models.Task, error)
+	Each(fn func(task *models.Task) error) error
+	ListDeleted(limit int) ([]*models.Task, error)
+	Restore(id string) error
+	Purge(id string) error
+	PurgeDeletedBefore(before time.Time) (int64, error)
+	SetTags(taskID string, tags []string) error
+	SetFields(taskID string, values map[string]string) error
+	ListTags() ([]*models.TagCount, error)
 }
 
 // taskRepository - implementation of the TaskRepository interface
 type taskRepository struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 // NewTaskRepository - creates a new task repository
-func NewTaskRepository(db *sqlx.DB) TaskRepository {
+func NewTaskRepository(db *DB) TaskRepository {
 	return &taskRepository{db: db}
 }
 
+// NewTaskRepositoryTx - creates a task repository bound to a transaction
+func NewTaskRepositoryTx(tx *sqlx.Tx) TaskRepository {
+	return &taskRepository{db: tx}
+}
+
 // NewDB - opens or creates a new database
-func NewDB(dbPath string) (*sqlx.DB, error) {
+func NewDB(dbPath string, options DBOptions) (*DB, error) {
 	// If the database path is not provided, use the default path
 	if dbPath == "" {
 		dbPath = filepath.Join("data", "scheduler.db")
@@ -56,28 +89,28 @@ func NewDB(dbPath string) (*sqlx.DB, error) {
 	}
 
 	// Open the database
-	db, err := sqlx.Open("sqlite", dbPath)
+	db, err := openDB(dbPath, options)
 	if err != nil {
 		log.Printf("Error opening database: %v", err)
 		return nil, err
 	}
 
-	// Check the connection
-	if err := db.Ping(); err != nil {
-		log.Printf("Error connecting to the database: %v", err)
-		return nil, err
-	}
-
 	// Check if the table exists
 	var exists int
 	err = db.Get(&exists, "SELECT count(*) FROM sqlite_master WHERE type='table' AND name='scheduler'")
 	if err != nil || exists == 0 {
 		log.Println("Table 'scheduler' not found. Creating a new table.")
-		createTable(db)
+		createTable(db.DB)
 	} else {
 		log.Println("Database and 'scheduler' table already exist.")
 	}
 
+	// Bring older databases up to the current schema
+	if err := migrate(db.DB); err != nil {
+		log.Printf("Error migrating the database: %v", err)
+		return nil, err
+	}
+
 	// Log the database file path after creating the table
 	log.Printf("Using database file: %s", dbPath)
 
@@ -95,7 +128,13 @@ func createTable(db *sqlx.DB) {
             date TEXT NOT NULL,
             title TEXT NOT NULL,
             comment TEXT,
-            repeat TEXT DEFAULT '' NOT NULL
+            repeat TEXT DEFAULT '' NOT NULL,
+            version INTEGER NOT NULL DEFAULT 1,
+            deleted_at TEXT,
+            project_id INTEGER REFERENCES projects(id),
+            priority INTEGER NOT NULL DEFAULT 0,
+            created_at TEXT,
+            status TEXT NOT NULL DEFAULT 'todo'
         );
         CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
     `
@@ -109,9 +148,11 @@ func createTable(db *sqlx.DB) {
 // Create - adds a new task to the database
 func (r *taskRepository) Create(task *models.Task) (string, error) {
 	query := `
-        INSERT INTO scheduler (date, title, comment, repeat)
-        VALUES (:date, :title, :comment, :repeat)
+        INSERT INTO scheduler (date, title, comment, repeat, priority, status, created_at, project_id)
+        VALUES (:date, :title, :comment, :repeat, COALESCE(:priority, 0), COALESCE(NULLIF(:status, ''), 'todo'), :created_at,
+                NULLIF(:project_id, ''))
     `
+	task.CreatedAt = timestamp(time.Now())
 	res, err := r.db.NamedExec(query, task)
 	if err != nil {
 		return "", err
@@ -129,42 +170,86 @@ func (r *taskRepository) Create(task *models.Task) (string, error) {
 // GetByID - retrieves a task by its ID
 func (r *taskRepository) GetByID(id string) (*models.Task, error) {
 	var task models.Task
-	err := r.db.Get(&task, `SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?`, id)
+	err := r.db.Get(&task, `SELECT `+taskColumns+` FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	task.ID = id
+
+	if err := r.loadDetails([]*models.Task{&task}); err != nil {
+		return nil, err
+	}
 	return &task, nil
 }
 
-// Update - updates a task in the database
+// Update - updates a task in the database.
+// If task.Version is set, the update is applied only when the stored version matches it.
+// A nil task.Priority keeps the current priority.
+// A nil task.ProjectID keeps the current project, an empty one removes the task from its project.
 func (r *taskRepository) Update(task *models.Task) error {
 	query := `
         UPDATE scheduler
-        SET date = :date, title = :title, comment = :comment, repeat = :repeat
-        WHERE id = :id
+        SET date = :date, title = :title, comment = :comment, repeat = :repeat,
+            priority = COALESCE(:priority, priority),
+            project_id = CASE WHEN :project_id IS NULL THEN project_id ELSE NULLIF(:project_id, '') END,
+            version = version + 1
+        WHERE id = :id AND deleted_at IS NULL AND (:version = 0 OR version = :version)
+        RETURNING version
     `
-	result, err := r.db.NamedExec(query, task)
+	// The new version is returned by the same statement, so that it cannot be the version of a later change
+	query, args, err := sqlx.Named(query, task)
+	if err != nil {
+		return err
+	}
+	var version int64
+	err = r.db.QueryRowx(query, args...).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.missOrConflict(task.ID)
+	}
 	if err != nil {
 		return err
 	}
 
-	// Check if any rows were updated
-	rowsAffected, err := result.RowsAffected()
+	// Report the new version back to the caller
+	task.Version = version
+	return nil
+}
+
+// SetStatus - changes the status of a task.
+// If version is not zero, the status is changed only when the stored version matches it.
+func (r *taskRepository) SetStatus(id, status string, version int64) error {
+	query := `
+        UPDATE scheduler
+        SET status = ?, version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+    `
+	result, err := r.db.Exec(query, status, id, version, version)
 	if err != nil {
 		return err
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+		return r.missOrConflict(id)
 	}
-
 	return nil
 }
 
-// Delete - deletes a task by its ID
-func (r *taskRepository) Delete(id string) error {
-	result, err := r.db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+// Delete - moves a task to the trash by its ID.
+// If version is not zero, the task is deleted only when the stored version matches it.
+func (r *taskRepository) Delete(id string, version int64) error {
+	query := `
+        UPDATE scheduler
+        SET deleted_at = ?, version = version + 1
+        WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)
+    `
+	result, err := r.db.Exec(query, timestamp(time.Now()), id, version, version)
 	if err != nil {
 		return err
 	}
@@ -176,113 +261,347 @@ func (r *taskRepository) Delete(id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+		return r.missOrConflict(id)
 	}
 
 	return nil
 }
 
-// List - retrieves a list of tasks with filtering and limitation
-func (r *taskRepository) List(search string, limit int) ([]*models.Task, error) {
-	var tasks []*models.Task
-	var err error
-	var query string
-	var rows *sqlx.Rows
+// missOrConflict - explains why a conditional write did not affect any rows
+func (r *taskRepository) missOrConflict(id string) error {
+	var exists int
+	if err := r.db.Get(&exists, `SELECT count(*) FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
 
+// TaskFilter - conditions for selecting tasks
+type TaskFilter struct {
+	Search    string            // Date in the format "dd.mm.yyyy" or text to find in the title, comment or notes
+	Tags      []string          // Tags the tasks must have
+	AllTags   bool              // Whether the tasks must have all of the tags rather than any of them
+	ProjectID string            // Only tasks of this project; NoProject selects tasks outside any project
+	Statuses  []string          // Only tasks with these statuses; open tasks (todo and in progress) by default
+	Fields    map[string]string // Only tasks with these custom field values, keyed by field name
+	Sort      string            // Sort key, SortDate by default or SortFieldPrefix followed by a custom field name
+	Desc      bool              // Whether to sort in descending order
+	Limit     int               // Maximum number of tasks
+}
+
+// Sort keys accepted by TaskFilter.Sort
+const (
+	SortDate     = "date"
+	SortPriority = "priority"
+	SortTitle    = "title"
+	SortCreated  = "created"
+
+	// SortFieldPrefix - prefix of sort keys ordering tasks by a custom field, e.g. "field.estimate"
+	SortFieldPrefix = "field."
+)
+
+// sortColumns - ORDER BY expressions for the sort keys
+var sortColumns = map[string]string{
+	SortDate:     "date",
+	SortPriority: "priority",
+	SortTitle:    "title COLLATE NOCASE",
+	SortCreated:  "created_at",
+}
+
+// NoProject - TaskFilter.ProjectID value selecting tasks that do not belong to any project
+const NoProject = "none"
+
+// List - retrieves a list of tasks with filtering and limitation
+func (r *taskRepository) List(filter TaskFilter) ([]*models.Task, error) {
+	limit := filter.Limit
 	if limit == 0 {
 		limit = defaultLimit
 	}
 
-	params := map[string]interface{}{
-		"limit": limit,
+	sort := filter.Sort
+	if sort == "" {
+		sort = SortDate
 	}
-
-	switch {
-	case search == "":
-		// Query without filtering
-		query = `
-            SELECT id, date, title, comment, repeat
-            FROM scheduler
-            ORDER BY date ASC
-            LIMIT :limit
-        `
-		rows, err = r.db.NamedQuery(query, params)
+	orderBy, ok := sortColumns[sort]
+	var orderArgs []interface{}
+	if fieldName, isField := strings.CutPrefix(sort, SortFieldPrefix); isField {
+		var err error
+		orderBy, err = r.fieldOrder(fieldName)
 		if err != nil {
 			return nil, err
 		}
-		defer rows.Close()
+		orderArgs = append(orderArgs, fieldName, fieldName)
+	} else if !ok {
+		return nil, ErrInvalidSort
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
 
-		for rows.Next() {
-			var task models.Task
-			err = rows.StructScan(&task)
-			if err != nil {
-				return nil, err
-			}
-			tasks = append(tasks, &task)
-		}
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
 
+	search := filter.Search
+	textSearch := false
+	switch {
+	case search == "":
+		// No filtering
 	case isValidDate(search):
 		// Filtering by date
 		date, _ := parseDate(search)
-		params["date"] = date.Format("20060102")
-		query = `
-            SELECT id, date, title, comment, repeat
-            FROM scheduler
-            WHERE date = :date
-            ORDER BY date ASC
-            LIMIT :limit
-        `
-		rows, err = r.db.NamedQuery(query, params)
+		conditions = append(conditions, "date = ?")
+		args = append(args, date.Format("20060102"))
+	default:
+		// Filtering by title, comment or notes is done on the application side (case-insensitive Unicode)
+		textSearch = true
+	}
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.StatusTodo, models.StatusInProgress}
+	}
+	statusQuery, statusArgs, err := sqlx.In(`status IN (?)`, statuses)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, statusQuery)
+	args = append(args, statusArgs...)
+
+	switch filter.ProjectID {
+	case "":
+		// Tasks of archived projects are shown only when the project is requested explicitly
+		conditions = append(conditions, "(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived_at IS NOT NULL))")
+	case NoProject:
+		conditions = append(conditions, "project_id IS NULL")
+	default:
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+
+	if len(filter.Tags) > 0 {
+		tagQuery, tagArgs, err := sqlx.In(`
+            SELECT tt.task_id
+            FROM task_tags tt
+            JOIN tags t ON t.id = tt.tag_id
+            WHERE t.name IN (?)
+            GROUP BY tt.task_id
+            HAVING ? = 0 OR count(DISTINCT t.id) = ?`,
+			filter.Tags, filter.AllTags, len(filter.Tags))
 		if err != nil {
 			return nil, err
 		}
-		defer rows.Close()
+		conditions = append(conditions, "id IN ("+tagQuery+")")
+		args = append(args, tagArgs...)
+	}
 
-		for rows.Next() {
-			var task models.Task
-			err = rows.StructScan(&task)
-			if err != nil {
-				return nil, err
-			}
-			tasks = append(tasks, &task)
-		}
+	for name, value := range filter.Fields {
+		conditions = append(conditions, `id IN (
+            SELECT v.task_id
+            FROM task_field_values v
+            JOIN custom_fields f ON f.id = v.field_id
+            WHERE f.name = ? AND v.value = ?)`)
+		args = append(args, name, value)
+	}
 
-	default:
-		// Filtering by title or comment (case-insensitive Unicode)
-		query = `
-            SELECT id, date, title, comment, repeat
-            FROM scheduler
-            ORDER BY date ASC
-            LIMIT :limit
-        `
-		rows, err = r.db.NamedQuery(query, params)
-		if err != nil {
+	columns := taskColumns
+	if textSearch {
+		columns += `, ` + noteTextColumn
+	}
+
+	query := `
+        SELECT ` + columns + `
+        FROM scheduler
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY ` + orderBy + ` ` + direction + `, id ` + direction
+	args = append(args, orderArgs...)
+	if !textSearch {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	searchLower := strings.ToLower(search)
+	for rows.Next() {
+		var row struct {
+			models.Task
+			NoteText string `db:"note_text"`
+		}
+		if err := rows.StructScan(&row); err != nil {
 			return nil, err
 		}
-		defer rows.Close()
-
-		// Application-side filtering
-		searchLower := strings.ToLower(search)
-		for rows.Next() {
-			var task models.Task
-			err = rows.StructScan(&task)
-			if err != nil {
-				return nil, err
+
+		if textSearch {
+			titleLower := strings.ToLower(row.Title)
+			commentLower := strings.ToLower(row.Comment)
+			notesLower := strings.ToLower(row.NoteText)
+			if !strings.Contains(titleLower, searchLower) && !strings.Contains(commentLower, searchLower) &&
+				!strings.Contains(notesLower, searchLower) {
+				continue
 			}
+		}
 
-			titleLower := strings.ToLower(task.Title)
-			commentLower := strings.ToLower(task.Comment)
+		task := row.Task
+		tasks = append(tasks, &task)
+		if len(tasks) >= limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-			if strings.Contains(titleLower, searchLower) || strings.Contains(commentLower, searchLower) {
-				tasks = append(tasks, &task)
-				if len(tasks) >= limit {
-					break
-				}
+	if err := r.loadDetails(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// ListDeleted - retrieves tasks from the trash, most recently deleted first
+func (r *taskRepository) ListDeleted(limit int) ([]*models.Task, error) {
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	var tasks []*models.Task
+	query := `
+        SELECT ` + taskColumns + `
+        FROM scheduler
+        WHERE deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC
+        LIMIT ?
+    `
+	if err := r.db.Select(&tasks, query, limit); err != nil {
+		return nil, err
+	}
+	if err := r.loadDetails(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// loadDetails - fills in the tags and custom field values of the given tasks
+func (r *taskRepository) loadDetails(tasks []*models.Task) error {
+	if err := r.loadTags(tasks); err != nil {
+		return err
+	}
+	return r.loadFields(tasks)
+}
+
+// Each - calls fn for every task outside the trash, in the order of creation.
+// Tasks are read in batches, so large exports neither have to fit in memory nor keep a query open while fn runs.
+func (r *taskRepository) Each(fn func(task *models.Task) error) error {
+	var lastID int64
+	for {
+		var tasks []*models.Task
+		err := r.db.Select(&tasks, `SELECT `+taskColumns+` FROM scheduler WHERE deleted_at IS NULL AND id > ? ORDER BY id LIMIT ?`,
+			lastID, eachBatchSize)
+		if err != nil {
+			return err
+		}
+		if err := r.loadDetails(tasks); err != nil {
+			return err
+		}
+
+		for _, task := range tasks {
+			if err := fn(task); err != nil {
+				return err
 			}
 		}
+		if len(tasks) < eachBatchSize {
+			return nil
+		}
+		if lastID, err = strconv.ParseInt(tasks[len(tasks)-1].ID, 10, 64); err != nil {
+			return err
+		}
 	}
+}
 
-	return tasks, nil
+// Restore - moves a task from the trash back to the active list
+func (r *taskRepository) Restore(id string) error {
+	query := `
+        UPDATE scheduler
+        SET deleted_at = NULL, version = version + 1
+        WHERE id = ? AND deleted_at IS NOT NULL
+    `
+	return expectOneRow(r.db.Exec(query, id))
+}
+
+// Purge - permanently deletes a task from the trash
+func (r *taskRepository) Purge(id string) error {
+	err := expectOneRow(r.db.Exec(`DELETE FROM scheduler WHERE id = ? AND deleted_at IS NOT NULL`, id))
+	if err != nil {
+		return err
+	}
+	if err := r.deletePurgedTaskData(); err != nil {
+		return err
+	}
+	return r.deleteUnusedTags()
+}
+
+// PurgeDeletedBefore - permanently deletes tasks moved to the trash before the given time
+func (r *taskRepository) PurgeDeletedBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM scheduler WHERE deleted_at IS NOT NULL AND deleted_at < ?`, timestamp(before))
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := r.deletePurgedTaskData(); err != nil {
+		return 0, err
+	}
+	return purged, r.deleteUnusedTags()
+}
+
+// deletePurgedTaskData - removes checklist items, notes, custom field values, external IDs and dependencies of purged tasks
+func (r *taskRepository) deletePurgedTaskData() error {
+	if _, err := r.db.Exec(`DELETE FROM checklist_items WHERE task_id NOT IN (SELECT id FROM scheduler)`); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM task_notes WHERE task_id NOT IN (SELECT id FROM scheduler)`); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM task_field_values WHERE task_id NOT IN (SELECT id FROM scheduler)`); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM external_ids WHERE task_id NOT IN (SELECT id FROM scheduler)`); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+        DELETE FROM task_dependencies
+        WHERE task_id NOT IN (SELECT id FROM scheduler) OR depends_on_id NOT IN (SELECT id FROM scheduler)`)
+	return err
+}
+
+// expectOneRow - converts the result of a statement addressing a single task into an error
+func expectOneRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// timestamp - formats a moment in time for storage
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
 }
 
 // parseDate - parses a date in the format "dd.mm.yyyy"
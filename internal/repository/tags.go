@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SetTags - replaces the tags of a task, creating new tags as needed
+func (r *taskRepository) SetTags(taskID string, tags []string) error {
+	if _, err := r.db.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := r.db.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return err
+		}
+		query := `
+            INSERT OR IGNORE INTO task_tags (task_id, tag_id)
+            SELECT ?, id FROM tags WHERE name = ?
+        `
+		if _, err := r.db.Exec(query, taskID, tag); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteUnusedTags()
+}
+
+// ListTags - retrieves all tags with the number of active tasks using them
+func (r *taskRepository) ListTags() ([]*models.TagCount, error) {
+	var tags []*models.TagCount
+	query := `
+        SELECT t.name AS name, count(s.id) AS count
+        FROM tags t
+        LEFT JOIN task_tags tt ON tt.tag_id = t.id
+        LEFT JOIN scheduler s ON s.id = tt.task_id AND s.deleted_at IS NULL AND s.status NOT IN ('done', 'cancelled')
+        GROUP BY t.id
+        ORDER BY count DESC, t.name ASC
+    `
+	if err := r.db.Select(&tags, query); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// loadTags - fills in the tags of the given tasks
+func (r *taskRepository) loadTags(tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(tasks))
+	byID := make(map[string]*models.Task, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+		byID[task.ID] = task
+	}
+
+	query, args, err := sqlx.In(`
+        SELECT tt.task_id, t.name
+        FROM task_tags tt
+        JOIN tags t ON t.id = tt.tag_id
+        WHERE tt.task_id IN (?)
+        ORDER BY t.name ASC`, ids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		if task, ok := byID[taskID]; ok {
+			task.Tags = append(task.Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// deleteUnusedTags - removes links to purged tasks and tags that are no longer used
+func (r *taskRepository) deleteUnusedTags() error {
+	if _, err := r.db.Exec(`DELETE FROM task_tags WHERE task_id NOT IN (SELECT id FROM scheduler)`); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM task_tags)`)
+	return err
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+var (
+	// ErrTemplateNotFound - the template does not exist
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateExists - another template has the same name
+	ErrTemplateExists = errors.New("template with this name already exists")
+)
+
+// templateColumns - columns selected when reading templates
+const templateColumns = `id, name, title, comment, repeat, priority, project_id, date_offset, tags, checklist`
+
+// templateRow - template as stored in the database, with the tags and checklist encoded as JSON
+type templateRow struct {
+	models.Template
+	TagsJSON      string `db:"tags"`
+	ChecklistJSON string `db:"checklist"`
+}
+
+// TemplateRepository - interface for task templates
+type TemplateRepository interface {
+	Create(template *models.Template) (string, error)
+	GetByID(id string) (*models.Template, error)
+	Update(template *models.Template) error
+	Delete(id string) error
+	List() ([]*models.Template, error)
+}
+
+// templateRepository - implementation of the TemplateRepository interface
+type templateRepository struct {
+	db dbtx
+}
+
+// NewTemplateRepository - creates a new template repository
+func NewTemplateRepository(db *DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+// Create - adds a new template
+func (r *templateRepository) Create(template *models.Template) (string, error) {
+	row, err := newTemplateRow(template)
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+        INSERT INTO templates (name, title, comment, repeat, priority, project_id, date_offset, tags, checklist)
+        VALUES (:name, :title, :comment, :repeat, COALESCE(:priority, 0), :project_id, :date_offset, :tags, :checklist)
+    `
+	res, err := r.db.NamedExec(query, row)
+	if isUniqueViolation(err) {
+		return "", ErrTemplateExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	template.ID = fmt.Sprintf("%d", id)
+	return template.ID, nil
+}
+
+// GetByID - retrieves a template by its ID
+func (r *templateRepository) GetByID(id string) (*models.Template, error) {
+	var row templateRow
+	err := r.db.Get(&row, `SELECT `+templateColumns+` FROM templates WHERE id = ?`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.template()
+}
+
+// Update - replaces all fields of a template
+func (r *templateRepository) Update(template *models.Template) error {
+	row, err := newTemplateRow(template)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE templates
+        SET name = :name, title = :title, comment = :comment, repeat = :repeat, priority = COALESCE(:priority, 0),
+            project_id = :project_id, date_offset = :date_offset, tags = :tags, checklist = :checklist
+        WHERE id = :id
+    `
+	result, err := r.db.NamedExec(query, row)
+	if isUniqueViolation(err) {
+		return ErrTemplateExists
+	}
+	return expectTemplate(result, err)
+}
+
+// Delete - removes a template
+func (r *templateRepository) Delete(id string) error {
+	return expectTemplate(r.db.Exec(`DELETE FROM templates WHERE id = ?`, id))
+}
+
+// List - retrieves all templates ordered by name
+func (r *templateRepository) List() ([]*models.Template, error) {
+	var rows []templateRow
+	if err := r.db.Select(&rows, `SELECT `+templateColumns+` FROM templates ORDER BY name ASC`); err != nil {
+		return nil, err
+	}
+
+	templates := make([]*models.Template, 0, len(rows))
+	for _, row := range rows {
+		template, err := row.template()
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// newTemplateRow - encodes a template for storage
+func newTemplateRow(template *models.Template) (*templateRow, error) {
+	tags, err := json.Marshal(template.Tags)
+	if err != nil {
+		return nil, err
+	}
+	checklist, err := json.Marshal(template.Checklist)
+	if err != nil {
+		return nil, err
+	}
+	return &templateRow{Template: *template, TagsJSON: string(tags), ChecklistJSON: string(checklist)}, nil
+}
+
+// template - decodes a stored template
+func (row templateRow) template() (*models.Template, error) {
+	template := row.Template
+	if err := json.Unmarshal([]byte(row.TagsJSON), &template.Tags); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(row.ChecklistJSON), &template.Checklist); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// expectTemplate - converts the result of a statement addressing a single template into an error
+func expectTemplate(result sql.Result, err error) error {
+	if err := expectOneRow(result, err); errors.Is(err, ErrNotFound) {
+		return ErrTemplateNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
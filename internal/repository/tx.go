@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dbtx - database handle shared by *sqlx.DB and *sqlx.Tx, so that repositories can work inside transactions
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+	NamedQuery(query string, arg interface{}) (*sqlx.Rows, error)
+	Queryx(query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowx(query string, args ...interface{}) *sqlx.Row
+}
+
+// Tx - repositories bound to a single database transaction
+type Tx struct {
+	Tasks        TaskRepository
+	Completions  CompletionRepository
+	Audit        AuditRepository
+	Projects     ProjectRepository
+	Checklists   ChecklistRepository
+	Dependencies DependencyRepository
+	Fields       FieldRepository
+	ExternalIDs  ExternalIDRepository
+	Notes        NoteRepository
+
+	tx *sqlx.Tx
+}
+
+// Transactor - runs functions inside database transactions
+type Transactor interface {
+	// Transact commits the transaction if fn succeeds and rolls it back otherwise
+	Transact(fn func(tx *Tx) error) error
+}
+
+// transactor - implementation of the Transactor interface
+type transactor struct {
+	db *DB
+}
+
+// NewTransactor - creates a new transaction runner
+func NewTransactor(db *DB) Transactor {
+	return &transactor{db: db}
+}
+
+// Transact - runs fn with repositories bound to a new transaction
+func (t *transactor) Transact(fn func(tx *Tx) error) error {
+	sqlTx, err := t.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	tx := &Tx{
+		Tasks:        NewTaskRepositoryTx(sqlTx),
+		Completions:  &completionRepository{db: sqlTx},
+		Audit:        &auditRepository{db: sqlTx},
+		Projects:     &projectRepository{db: sqlTx},
+		Checklists:   &checklistRepository{db: sqlTx},
+		Dependencies: &dependencyRepository{db: sqlTx},
+		Fields:       &fieldRepository{db: sqlTx},
+		ExternalIDs:  &externalIDRepository{db: sqlTx},
+		Notes:        &noteRepository{db: sqlTx},
+		tx:           sqlTx,
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// Savepoint - runs fn inside a savepoint, undoing only its changes if it fails
+func (tx *Tx) Savepoint(fn func() error) error {
+	if _, err := tx.tx.Exec(`SAVEPOINT item`); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := tx.tx.Exec(`ROLLBACK TO item`); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		if _, relErr := tx.tx.Exec(`RELEASE item`); relErr != nil {
+			return fmt.Errorf("%w (release failed: %v)", err, relErr)
+		}
+		return err
+	}
+
+	_, err := tx.tx.Exec(`RELEASE item`)
+	return err
+}
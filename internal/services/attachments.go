@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+var (
+	// ErrAttachmentTooLarge is returned when an uploaded file exceeds the size limit
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	// ErrAttachmentType is returned when the type of an uploaded file is not allowed
+	ErrAttachmentType = errors.New("attachment type is not allowed")
+)
+
+// AttachmentLimits restricts the files that can be attached to tasks.
+type AttachmentLimits struct {
+	MaxSize int64    // Maximum size in bytes
+	Types   []string // Allowed MIME types
+}
+
+// AttachmentService provides an interface for files attached to tasks.
+type AttachmentService interface {
+	Upload(taskID, name string, content io.Reader) (*models.Attachment, error)
+	List(taskID string) ([]*models.Attachment, error)
+	Open(id string) (*models.Attachment, io.ReadCloser, error)
+	Delete(id string) error
+	DeleteOrphaned() (int, error)
+	Limits() AttachmentLimits
+}
+
+// attachmentService implements the AttachmentService interface.
+type attachmentService struct {
+	tasks  repository.TaskRepository       // Repository for the tasks owning the attachments.
+	repo   repository.AttachmentRepository // Repository for attachment metadata.
+	store  repository.AttachmentStore      // Storage for attachment contents.
+	limits AttachmentLimits                // Size and type restrictions.
+}
+
+// NewAttachmentService creates a new attachment service.
+func NewAttachmentService(tasks repository.TaskRepository, repo repository.AttachmentRepository, store repository.AttachmentStore, limits AttachmentLimits) AttachmentService {
+	return &attachmentService{tasks: tasks, repo: repo, store: store, limits: limits}
+}
+
+// Upload attaches a file to an active task.
+// The MIME type is detected from the contents rather than trusted from the client.
+func (s *attachmentService) Upload(taskID, name string, content io.Reader) (*models.Attachment, error) {
+	if taskID == "" {
+		return nil, errors.New("task ID is required")
+	}
+	if _, err := s.tasks.GetByID(taskID); err != nil {
+		return nil, err
+	}
+
+	name = attachmentName(name)
+	if name == "" {
+		return nil, errors.New("file name is required")
+	}
+
+	// One byte over the limit is enough to tell that the file is too large
+	data, err := io.ReadAll(io.LimitReader(content, s.limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.limits.MaxSize {
+		return nil, fmt.Errorf("%w, the limit is %d bytes", ErrAttachmentTooLarge, s.limits.MaxSize)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !s.allowedType(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentType, contentType)
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	id, err := s.repo.Create(attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Save(id, data); err != nil {
+		if delErr := s.repo.Delete(id); delErr != nil {
+			log.Println("Error removing the metadata of an unsaved attachment:", delErr)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// List returns the attachments of an active task.
+func (s *attachmentService) List(taskID string) ([]*models.Attachment, error) {
+	if taskID == "" {
+		return nil, errors.New("task ID is required")
+	}
+	if _, err := s.tasks.GetByID(taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(taskID)
+}
+
+// Open returns an attachment with its contents; the caller must close the contents.
+func (s *attachmentService) Open(id string) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.store.Open(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// Delete removes an attachment of an active task.
+func (s *attachmentService) Delete(id string) error {
+	if _, err := s.attachment(id); err != nil {
+		return err
+	}
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// DeleteOrphaned removes the attachments of purged tasks and returns their number.
+func (s *attachmentService) DeleteOrphaned() (int, error) {
+	orphaned, err := s.repo.ListOrphaned()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, attachment := range orphaned {
+		if err := s.store.Delete(attachment.ID); err != nil {
+			return i, err
+		}
+		if err := s.repo.Delete(attachment.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(orphaned), nil
+}
+
+// Limits returns the size and type restrictions for attachments.
+func (s *attachmentService) Limits() AttachmentLimits {
+	return s.limits
+}
+
+// attachment returns an attachment of an active task.
+// Attachments of tasks in the trash are kept so that restoring a task restores them too.
+func (s *attachmentService) attachment(id string) (*models.Attachment, error) {
+	if id == "" {
+		return nil, errors.New("attachment ID is required")
+	}
+
+	attachment, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.tasks.GetByID(attachment.TaskID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, repository.ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// allowedType checks whether files of a MIME type can be attached.
+func (s *attachmentService) allowedType(contentType string) bool {
+	for _, allowed := range s.limits.Types {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentName strips the directories and control characters from an uploaded file name.
+func attachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// attachmentCleaningTaskService removes the attachments of tasks when they are purged from the trash.
+type attachmentCleaningTaskService struct {
+	TaskService
+	attachments AttachmentService
+}
+
+// NewAttachmentCleaningTaskService wraps a task service so that purging tasks also deletes their attachments.
+func NewAttachmentCleaningTaskService(next TaskService, attachments AttachmentService) TaskService {
+	return &attachmentCleaningTaskService{TaskService: next, attachments: attachments}
+}
+
+// PurgeTask permanently deletes a task together with its attachments.
+func (s *attachmentCleaningTaskService) PurgeTask(ctx context.Context, id string) error {
+	if err := s.TaskService.PurgeTask(ctx, id); err != nil {
+		return err
+	}
+	s.deleteOrphaned()
+	return nil
+}
+
+// PurgeTrash permanently deletes expired tasks together with their attachments.
+func (s *attachmentCleaningTaskService) PurgeTrash(retention time.Duration) (int64, error) {
+	purged, err := s.TaskService.PurgeTrash(retention)
+	if err != nil {
+		return purged, err
+	}
+	s.deleteOrphaned()
+	return purged, nil
+}
+
+// deleteOrphaned removes attachments left behind by purged tasks.
+// Failures are only logged: the purge itself has succeeded and the next purge retries the cleanup.
+func (s *attachmentCleaningTaskService) deleteOrphaned() {
+	if _, err := s.attachments.DeleteOrphaned(); err != nil {
+		log.Println("Error deleting attachments of purged tasks:", err)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/auth"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// Audited operations
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionDone    = "done"
+	ActionStatus  = "status"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// AuditQuery holds the conditions for reading the audit trail.
+// From and To are in the format "20060102" (inclusive) or RFC 3339.
+type AuditQuery struct {
+	TaskID string
+	Action string
+	Actor  string
+	From   string
+	To     string
+	Limit  int
+}
+
+// AuditService provides read access to the audit trail.
+type AuditService interface {
+	EachEntry(query AuditQuery, fn func(entry *models.AuditEntry) error) error
+}
+
+// auditService implements the AuditService interface.
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditService creates a new audit trail service.
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+// EachEntry calls fn for every audit entry matching the query, oldest first.
+func (s *auditService) EachEntry(query AuditQuery, fn func(entry *models.AuditEntry) error) error {
+	filter := repository.AuditFilter{
+		TaskID: query.TaskID,
+		Action: query.Action,
+		Actor:  query.Actor,
+		Limit:  query.Limit,
+	}
+
+	if query.From != "" {
+		from, err := parseBound(query.From, false)
+		if err != nil {
+			return errors.New("invalid 'from' parameter")
+		}
+		filter.From = from
+	}
+
+	if query.To != "" {
+		to, err := parseBound(query.To, true)
+		if err != nil {
+			return errors.New("invalid 'to' parameter")
+		}
+		filter.To = to
+	}
+
+	return s.repo.Each(filter, fn)
+}
+
+// parseBound converts a date or a timestamp into an RFC 3339 bound of a time range.
+// An upper bound given as a date includes the whole day.
+func parseBound(value string, upper bool) (string, error) {
+	if date, err := time.Parse(dateFormat, value); err == nil {
+		if upper {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date.Format(time.RFC3339), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// snapshot returns the current state of a task, or nil if it is not available
+func (s *taskService) snapshot(id string) *models.Task {
+	task, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil
+	}
+	return task
+}
+
+// record writes an audit entry for an operation, in the transaction that made the change.
+// The state after the change is read back, so it reflects what was actually stored.
+// A failure to write the entry fails the operation.
+func (s *taskService) record(ctx context.Context, action, id string, before *models.Task) error {
+	actor := auth.ActorFromContext(ctx)
+	entry := &models.AuditEntry{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Action: action,
+		TaskID: id,
+		Actor:  actor.Name,
+		IP:     actor.IP,
+		Before: marshalTask(before),
+		After:  marshalTask(s.snapshot(id)),
+	}
+	if err := s.audit.Create(entry); err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
+	return nil
+}
+
+// marshalTask converts a task to JSON, or returns nil for a missing task
+func marshalTask(task *models.Task) json.RawMessage {
+	if task == nil {
+		return nil
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		log.Println("Error encoding task for the audit trail:", err)
+		return nil
+	}
+	return data
+}
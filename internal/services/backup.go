@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/backup"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+var (
+	// ErrBackupsDisabled is returned when backups are to be stored but no backup directory is configured
+	ErrBackupsDisabled = errors.New("no backup directory is configured")
+	// ErrBackupNotFound is returned for backups that are not in the backup directory
+	ErrBackupNotFound = errors.New("backup not found")
+)
+
+const (
+	backupPrefix     = "scheduler-"
+	backupTimeFormat = "20060102-150405.000"
+	// backupCheckInterval - how often scheduled backups check whether the newest backup has become too old
+	backupCheckInterval = time.Hour
+)
+
+// BackupOptions configures where backups are stored and how they are written.
+type BackupOptions struct {
+	Dir  string // Directory backups are stored in; empty allows only streamed backups
+	Keep int    // Number of stored backups kept when a new one is made; 0 keeps all of them
+	backup.Options
+}
+
+// BackupInfo describes a stored backup.
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupService provides an interface for backing up the database while it is in use.
+type BackupService interface {
+	Backup() (*BackupInfo, error)
+	Write(w io.Writer) error
+	FileName() string
+	List() ([]BackupInfo, error)
+	Open(name string) (io.ReadCloser, *BackupInfo, error)
+}
+
+// backupService implements the BackupService interface.
+type backupService struct {
+	repo    repository.BackupRepository
+	options BackupOptions
+	mu      sync.Mutex // Serializes stored backups and their rotation
+}
+
+// NewBackupService creates a backup service writing backups with the given options.
+func NewBackupService(repo repository.BackupRepository, options BackupOptions) BackupService {
+	return &backupService{repo: repo, options: options}
+}
+
+// Backup stores a new backup in the backup directory and removes the oldest ones beyond the number to keep.
+// The backup appears under its final name only once it is complete.
+func (s *backupService) Backup() (*BackupInfo, error) {
+	if s.options.Dir == "" {
+		return nil, ErrBackupsDisabled
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.options.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	name := s.FileName()
+	path := filepath.Join(s.options.Dir, name)
+	temp, err := os.CreateTemp(s.options.Dir, "."+name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := s.Write(temp); err != nil {
+		temp.Close()
+		return nil, err
+	}
+	if err := temp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return nil, err
+	}
+
+	info, err := backupInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rotate(); err != nil {
+		log.Println("Error removing old backups:", err)
+	}
+	return info, nil
+}
+
+// Write writes a new backup to w. The database is copied and verified first, so that a damaged copy
+// is reported before anything is written.
+func (s *backupService) Write(w io.Writer) error {
+	// The copy is made next to the stored backups, where there is room for them
+	if s.options.Dir != "" {
+		if err := os.MkdirAll(s.options.Dir, 0o700); err != nil {
+			return err
+		}
+	}
+	dir, err := os.MkdirTemp(s.options.Dir, ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, "scheduler.db")
+	if err := s.repo.VacuumInto(snapshot); err != nil {
+		return fmt.Errorf("copying the database: %w", err)
+	}
+	if err := repository.VerifyDatabase(snapshot); err != nil {
+		return err
+	}
+
+	file, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder, err := backup.NewWriter(w, s.options.Options)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(encoder, file); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// FileName returns the name of a backup made now, e.g. "scheduler-20240131-020000.000.db.gz"
+func (s *backupService) FileName() string {
+	return backupPrefix + time.Now().UTC().Format(backupTimeFormat) + s.options.Ext()
+}
+
+// List returns the stored backups, newest first.
+func (s *backupService) List() ([]BackupInfo, error) {
+	if s.options.Dir == "" {
+		return nil, ErrBackupsDisabled
+	}
+	entries, err := os.ReadDir(s.options.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+		info, err := backupInfo(filepath.Join(s.options.Dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, *info)
+	}
+	// Names sort by the time the backups were made
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// Open opens a stored backup for downloading.
+func (s *backupService) Open(name string) (io.ReadCloser, *BackupInfo, error) {
+	if s.options.Dir == "" {
+		return nil, nil, ErrBackupsDisabled
+	}
+	if !isBackupName(name) || filepath.Base(name) != name {
+		return nil, nil, ErrBackupNotFound
+	}
+	path := filepath.Join(s.options.Dir, name)
+	info, err := backupInfo(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// rotate removes the oldest stored backups beyond the number to keep
+func (s *backupService) rotate() error {
+	if s.options.Keep == 0 {
+		return nil
+	}
+	backups, err := s.List()
+	if err != nil || len(backups) <= s.options.Keep {
+		return err
+	}
+	for _, old := range backups[s.options.Keep:] {
+		if err := os.Remove(filepath.Join(s.options.Dir, old.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBackupName reports whether a file name is one given to stored backups
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.Contains(name, ".db")
+}
+
+// backupInfo describes the backup file at the given path
+func backupInfo(path string) (*BackupInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupInfo{Name: stat.Name(), Size: stat.Size(), CreatedAt: stat.ModTime().UTC()}, nil
+}
+
+// RestoreBackup replaces the database file with the one in a backup. The backup is decrypted with the key
+// when it is encrypted and verified before anything is replaced; with dryRun it is only verified.
+// The replaced database is kept next to it with the ".before-restore" suffix.
+// The server must not be running while the database is restored.
+func RestoreBackup(dbPath string, r io.Reader, key string, dryRun bool) error {
+	decoded, err := backup.NewReader(r, key)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(dbPath), "."+filepath.Base(dbPath)+".*.restore")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := io.Copy(temp, decoded); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := repository.VerifyDatabase(temp.Name()); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	// The journal files belong to the replaced database and would corrupt the restored one
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(dbPath+suffix, dbPath+".before-restore"+suffix)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(temp.Name(), dbPath)
+}
+
+// StartBackups stores a backup whenever the newest one is older than the interval, starting right away
+// if there is none. The checks run in the background until the stop channel is closed.
+func StartBackups(service BackupService, interval time.Duration, stop <-chan struct{}) {
+	check := func() {
+		backups, err := service.List()
+		if err != nil {
+			log.Println("Error listing backups:", err)
+			return
+		}
+		if len(backups) > 0 && time.Since(backups[0].CreatedAt) < interval {
+			return
+		}
+		info, err := service.Backup()
+		if err != nil {
+			log.Println("Error backing up the database:", err)
+			return
+		}
+		log.Printf("Backed up the database to %s", info.Name)
+	}
+
+	runPeriodically(min(interval, backupCheckInterval), stop, check)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// MaxBatchSize is the maximum number of operations in a single batch
+const MaxBatchSize = 1000
+
+// Batch operation types
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+	BatchDone   = "done"
+)
+
+// ErrBatchAborted is returned when an all-or-nothing batch is rolled back
+var ErrBatchAborted = errors.New("batch rolled back")
+
+// BatchOperation describes a single operation of a batch.
+type BatchOperation struct {
+	Op      string       // One of BatchCreate, BatchUpdate, BatchDelete or BatchDone
+	ID      string       // Task ID for delete and done
+	Version int64        // Expected task version, 0 means any
+	Task    *models.Task // Task for create and update
+	Done    DoneOptions  // Completion details for done
+}
+
+// BatchResult describes the outcome of a single operation of a batch.
+type BatchResult struct {
+	Index int    `json:"index"`           // Position of the operation in the batch
+	Op    string `json:"op"`              // Operation type
+	ID    string `json:"id,omitempty"`    // Affected task
+	OK    bool   `json:"ok"`              // Whether the operation was applied
+	Error string `json:"error,omitempty"` // Reason of the failure
+}
+
+// BatchService executes several task operations in a single transaction.
+type BatchService interface {
+	// Execute runs the operations in order. In atomic mode the first failure rolls back the whole batch
+	// and ErrBatchAborted is returned; otherwise failed operations are skipped and the rest are committed.
+	Execute(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+}
+
+// batchService implements the BatchService interface.
+type batchService struct {
+	transactor repository.Transactor
+	options    TaskOptions
+}
+
+// NewBatchService creates a new batch service handling tasks with the given options.
+func NewBatchService(transactor repository.Transactor, options TaskOptions) BatchService {
+	return &batchService{transactor: transactor, options: options}
+}
+
+// Execute runs the operations of a batch inside one transaction.
+func (s *batchService) Execute(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("no operations")
+	}
+	if len(ops) > MaxBatchSize {
+		return nil, fmt.Errorf("too many operations, the limit is %d", MaxBatchSize)
+	}
+
+	var results []BatchResult
+	err := s.transactor.Transact(func(tx *repository.Tx) error {
+		// Task service bound to the transaction, so that the audit trail and history are rolled back with it
+		tasks := newTxTaskService(tx, s.options)
+
+		results = make([]BatchResult, 0, len(ops))
+		failed := false
+		for i, op := range ops {
+			result := BatchResult{Index: i, Op: op.Op, ID: op.ID}
+
+			err := tx.Savepoint(func() error {
+				id, err := executeOperation(ctx, tasks, op)
+				result.ID = id
+				return err
+			})
+			if err != nil {
+				result.Error = err.Error()
+				failed = true
+			} else {
+				result.OK = true
+			}
+			results = append(results, result)
+
+			if failed && atomic {
+				return ErrBatchAborted
+			}
+		}
+		return nil
+	})
+
+	// In atomic mode nothing was applied, so successful results are reported as rolled back
+	if errors.Is(err, ErrBatchAborted) {
+		for i := range results {
+			if results[i].OK {
+				results[i].OK = false
+				results[i].Error = ErrBatchAborted.Error()
+			}
+		}
+	}
+	return results, err
+}
+
+// executeOperation applies a single batch operation and returns the ID of the affected task
+func executeOperation(ctx context.Context, tasks TaskService, op BatchOperation) (string, error) {
+	switch op.Op {
+	case BatchCreate:
+		if op.Task == nil || op.Task.Title == "" {
+			return "", errors.New("task title is required")
+		}
+		return tasks.CreateTask(ctx, op.Task)
+	case BatchUpdate:
+		if op.Task == nil || op.Task.ID == "" || op.Task.Title == "" {
+			return "", errors.New("task ID or title is required")
+		}
+		op.Task.Version = op.Version
+		return op.Task.ID, tasks.UpdateTask(ctx, op.Task)
+	case BatchDelete:
+		return op.ID, tasks.DeleteTask(ctx, op.ID, op.Version)
+	case BatchDone:
+		op.Done.Version = op.Version
+		_, err := tasks.MarkTaskDone(ctx, op.ID, op.Done)
+		return op.ID, err
+	default:
+		return op.ID, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
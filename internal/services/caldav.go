@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/ical"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// Sources under which the resource names and UIDs of tasks created by CalDAV clients are recorded
+const (
+	SourceCalDAVName = "caldav"
+	SourceCalDAVUID  = "caldav-uid"
+)
+
+// repeatProperty keeps repeat rules without an RRULE equivalent, so that they survive a round trip through a client
+const repeatProperty = "X-SCHEDULER-REPEAT"
+
+var (
+	// ErrInvalidTodo is returned when a stored calendar object is not a single VTODO the scheduler can hold
+	ErrInvalidTodo = errors.New("invalid calendar object")
+
+	// davNamePattern matches the resource names accepted from clients
+	davNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@+-]{0,199}$`)
+)
+
+// DAVResource is a task exposed as a calendar object resource.
+type DAVResource struct {
+	Name string       // Resource name without the ".ics" extension
+	UID  string       // iCalendar UID of the task
+	Task *models.Task // The task
+}
+
+// ETag returns the entity tag of the resource, which changes with every modification of the task
+func (r *DAVResource) ETag() string {
+	return fmt.Sprintf(`"%s-%d"`, r.Task.ID, r.Task.Version)
+}
+
+// DAVPreconditions are the conditional request headers of a write.
+type DAVPreconditions struct {
+	IfMatch     string // Entity tag the resource must have, "*" for any existing resource
+	IfNoneMatch bool   // Whether the resource must not exist yet
+}
+
+// CalDAVService provides an interface for exposing tasks as a CalDAV collection of VTODO resources.
+type CalDAVService interface {
+	List() ([]*DAVResource, error)
+	Get(name string) (*DAVResource, error)
+	Put(ctx context.Context, name string, r io.Reader, pre DAVPreconditions) (*DAVResource, bool, error)
+	Delete(ctx context.Context, name string, pre DAVPreconditions) error
+	Render(w io.Writer, resource *DAVResource) error
+	CTag(resources []*DAVResource) string
+}
+
+// calDAVService implements the CalDAVService interface.
+type calDAVService struct {
+	tasks       TaskService                     // Service all task reads and writes go through.
+	externalIDs repository.ExternalIDRepository // Resource names and UIDs chosen by clients.
+}
+
+// NewCalDAVService creates a new CalDAV service.
+func NewCalDAVService(tasks TaskService, externalIDs repository.ExternalIDRepository) CalDAVService {
+	return &calDAVService{tasks: tasks, externalIDs: externalIDs}
+}
+
+// List returns all tasks, including closed ones, as resources
+func (s *calDAVService) List() ([]*DAVResource, error) {
+	tasks, err := s.tasks.ListTasks(repository.TaskFilter{
+		Statuses: []string{models.StatusTodo, models.StatusInProgress, models.StatusDone, models.StatusCancelled},
+		Limit:    feedLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.externalIDs.ExternalIDs(SourceCalDAVName)
+	if err != nil {
+		return nil, err
+	}
+	uids, err := s.externalIDs.ExternalIDs(SourceCalDAVUID)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*DAVResource, 0, len(tasks))
+	for _, task := range tasks {
+		resources = append(resources, newDAVResource(task, names[task.ID], uids[task.ID]))
+	}
+	return resources, nil
+}
+
+// Get returns the resource with the given name
+func (s *calDAVService) Get(name string) (*DAVResource, error) {
+	id, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.resource(id)
+}
+
+// Put stores a VTODO under the given name, creating a task or updating the one already stored there.
+// The second value reports whether a task was created.
+// The status is changed through the regular transitions, so completing a repeating task moves it to its next date.
+func (s *calDAVService) Put(ctx context.Context, name string, r io.Reader, pre DAVPreconditions) (*DAVResource, bool, error) {
+	if !davNamePattern.MatchString(name) {
+		return nil, false, fmt.Errorf("%w: invalid resource name", ErrInvalidTodo)
+	}
+	todo, err := parseTodo(r)
+	if err != nil {
+		return nil, false, err
+	}
+	task, err := todoTask(todo)
+	if err != nil {
+		return nil, false, err
+	}
+
+	id, err := s.resolve(name)
+	if errors.Is(err, repository.ErrNotFound) {
+		if pre.IfMatch != "" {
+			return nil, false, repository.ErrVersionConflict
+		}
+		return s.create(ctx, name, todo.Value("UID"), task)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	current, err := s.resource(id)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkPreconditions(current, pre); err != nil {
+		return nil, false, err
+	}
+
+	status := task.Status
+	task.ID = id
+	task.Status = ""
+	task.Version = current.Task.Version
+	if err := s.tasks.UpdateTask(ctx, task); err != nil {
+		return nil, false, err
+	}
+	if err := s.transition(ctx, id, current.Task.Status, status); err != nil {
+		return nil, false, err
+	}
+
+	updated, err := s.resource(id)
+	return updated, false, err
+}
+
+// create adds a task for a resource stored by a client and remembers the name and UID it was stored under
+func (s *calDAVService) create(ctx context.Context, name, uid string, task *models.Task) (*DAVResource, bool, error) {
+	id, err := s.tasks.CreateTask(ctx, task)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.externalIDs.Link(SourceCalDAVName, name, id); err != nil {
+		return nil, false, err
+	}
+	if uid != "" && uid != taskUID(id) {
+		if err := s.externalIDs.Link(SourceCalDAVUID, uid, id); err != nil {
+			return nil, false, err
+		}
+	}
+
+	created, err := s.resource(id)
+	return created, true, err
+}
+
+// transition moves a task from one status to another, passing through "todo" when there is no direct transition
+func (s *calDAVService) transition(ctx context.Context, id, from, to string) error {
+	if to == "" {
+		to = models.StatusTodo
+	}
+	if from == to {
+		return nil
+	}
+
+	if !models.CanTransition(from, to) {
+		if err := s.tasks.SetStatus(ctx, id, models.StatusTodo, 0); err != nil {
+			return err
+		}
+		if to == models.StatusTodo {
+			return nil
+		}
+	}
+	if to == models.StatusDone {
+		_, err := s.tasks.MarkTaskDone(ctx, id, DoneOptions{})
+		return err
+	}
+	return s.tasks.SetStatus(ctx, id, to, 0)
+}
+
+// Delete moves the task stored under the given name to the trash
+func (s *calDAVService) Delete(ctx context.Context, name string, pre DAVPreconditions) error {
+	id, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	current, err := s.resource(id)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(current, pre); err != nil {
+		return err
+	}
+	return s.tasks.DeleteTask(ctx, id, current.Task.Version)
+}
+
+// Render writes the resource as an iCalendar object with a single VTODO
+func (s *calDAVService) Render(w io.Writer, resource *DAVResource) error {
+	task := resource.Task
+
+	// The timestamp is fixed, so that the content only changes together with the entity tag
+	stamp := time.Unix(0, 0).UTC()
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		stamp = created.UTC()
+	}
+
+	rule := ""
+	if task.Repeat != "" {
+		rule, _ = timeutils.RRule(task.Date, task.Repeat)
+	}
+	todo := taskComponent(task, CalendarTodos, task.Date, resource.UID, rule, stamp)
+	if task.Repeat != "" && rule == "" {
+		todo.Add(repeatProperty, task.Repeat)
+	}
+
+	calendar := ical.NewComponent("VCALENDAR")
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", "-//scheduler//Task Scheduler//EN")
+	calendar.Components = append(calendar.Components, todo)
+	return ical.Encode(w, calendar)
+}
+
+// CTag returns a tag of the whole collection, which changes whenever a resource is added, modified or removed
+func (s *calDAVService) CTag(resources []*DAVResource) string {
+	tags := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		tags = append(tags, resource.Name+resource.ETag())
+	}
+	sort.Strings(tags)
+
+	hash := sha1.New()
+	for _, tag := range tags {
+		io.WriteString(hash, tag+"\n")
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+}
+
+// resolve returns the ID of the task stored under a resource name.
+// Names chosen by clients take precedence; other tasks are named after their ID.
+func (s *calDAVService) resolve(name string) (string, error) {
+	id, err := s.externalIDs.TaskID(SourceCalDAVName, name)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	if _, err := strconv.ParseInt(name, 10, 64); err != nil {
+		return "", repository.ErrNotFound
+	}
+	// A task created by a client is only available under the name the client chose
+	if clientName, err := s.externalIDs.ExternalID(SourceCalDAVName, name); err != nil || clientName != "" {
+		if err == nil {
+			err = repository.ErrNotFound
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+// resource loads a task as a resource
+func (s *calDAVService) resource(id string) (*DAVResource, error) {
+	task, err := s.tasks.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.externalIDs.ExternalID(SourceCalDAVName, id)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := s.externalIDs.ExternalID(SourceCalDAVUID, id)
+	if err != nil {
+		return nil, err
+	}
+	return newDAVResource(task, name, uid), nil
+}
+
+// newDAVResource names a task, falling back to its ID and its feed UID when no client chose them
+func newDAVResource(task *models.Task, name, uid string) *DAVResource {
+	if name == "" {
+		name = task.ID
+	}
+	if uid == "" {
+		uid = taskUID(task.ID)
+	}
+	return &DAVResource{Name: name, UID: uid, Task: task}
+}
+
+// checkPreconditions compares the conditional headers of a write with the current resource
+func checkPreconditions(current *DAVResource, pre DAVPreconditions) error {
+	if pre.IfNoneMatch || (pre.IfMatch != "" && pre.IfMatch != "*" && pre.IfMatch != current.ETag()) {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
+
+// parseTodo reads a calendar object and returns its VTODO.
+// Overrides of single occurrences are ignored, since tasks have no per-occurrence state.
+func parseTodo(r io.Reader) (*ical.Component, error) {
+	roots, err := ical.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTodo, err)
+	}
+	if len(roots) != 1 || roots[0].Name != "VCALENDAR" {
+		return nil, fmt.Errorf("%w: expected a single VCALENDAR", ErrInvalidTodo)
+	}
+
+	var todo *ical.Component
+	for _, c := range roots[0].Components {
+		switch {
+		case c.Name == "VEVENT" || c.Name == "VJOURNAL":
+			return nil, fmt.Errorf("%w: only VTODO components are supported", ErrInvalidTodo)
+		case c.Name != "VTODO" || c.Get("RECURRENCE-ID") != nil:
+			continue
+		case todo != nil:
+			return nil, fmt.Errorf("%w: more than one VTODO", ErrInvalidTodo)
+		}
+		todo = c
+	}
+	if todo == nil {
+		return nil, fmt.Errorf("%w: missing VTODO", ErrInvalidTodo)
+	}
+	return todo, nil
+}
+
+// todoTask converts a VTODO into a task.
+// Unlike an import, every property replaces the stored value, so a missing priority or category clears it.
+func todoTask(todo *ical.Component) (*models.Task, error) {
+	task, reason := icalTask(todo)
+	if reason != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTodo, reason)
+	}
+
+	// The due date is what task applications show and change; DTSTART only anchors the repeat rule
+	if due := todo.Value("DUE"); len(due) >= 8 {
+		if _, err := time.Parse(dateFormat, due[:8]); err == nil {
+			task.Date = due[:8]
+		}
+	}
+
+	if rule := todo.Value("RRULE"); rule != "" {
+		repeat, err := timeutils.FromRRule(task.Date, rule)
+		if err != nil {
+			if repeat = todo.Value(repeatProperty); repeat == "" {
+				return nil, fmt.Errorf("%w: unsupported RRULE: %v", ErrInvalidTodo, err)
+			}
+		}
+		task.Repeat = repeat
+	} else {
+		task.Repeat = todo.Value(repeatProperty)
+	}
+
+	if task.Priority == nil {
+		none := models.PriorityNone
+		task.Priority = &none
+	}
+	if task.Tags == nil {
+		task.Tags = []string{}
+	}
+	return task, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/ical"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// Kinds of calendar components tasks are rendered as
+const (
+	CalendarEvents = "event" // All-day VEVENT components, shown by every calendar application
+	CalendarTodos  = "todo"  // VTODO components, shown by applications with task lists
+)
+
+const (
+	// feedLimit is the maximum number of tasks in the calendar feed
+	feedLimit = 10000
+	// expandHorizon is how far ahead occurrences are listed when a repeat rule has no RRULE equivalent
+	expandHorizon = 365 * 24 * time.Hour
+	// expandLimit is the maximum number of occurrences listed for a task
+	expandLimit = 100
+)
+
+// ErrInvalidCalendarKind is returned for an unknown kind of calendar components
+var ErrInvalidCalendarKind = errors.New("invalid calendar component type, expected event or todo")
+
+// CalendarService provides an interface for the iCalendar feed.
+type CalendarService interface {
+	WriteFeed(w io.Writer, filter repository.TaskFilter, kind string) error
+}
+
+// calendarService implements the CalendarService interface.
+type calendarService struct {
+	repo repository.TaskRepository // Repository the tasks are read from.
+}
+
+// NewCalendarService creates a new calendar feed service.
+func NewCalendarService(repo repository.TaskRepository) CalendarService {
+	return &calendarService{repo: repo}
+}
+
+// WriteFeed writes the tasks matching the filter as an iCalendar object.
+// Repeat rules become RRULE properties; rules without an equivalent are expanded into separate occurrences.
+func (s *calendarService) WriteFeed(w io.Writer, filter repository.TaskFilter, kind string) error {
+	if kind == "" {
+		kind = CalendarEvents
+	}
+	if kind != CalendarEvents && kind != CalendarTodos {
+		return ErrInvalidCalendarKind
+	}
+
+	for _, status := range filter.Statuses {
+		if !models.IsValidStatus(status) {
+			return ErrInvalidStatus
+		}
+	}
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return err
+	}
+	filter.Tags = tags
+
+	filter.Limit = feedLimit
+	tasks, err := s.repo.List(filter)
+	if err != nil {
+		return err
+	}
+
+	calendar := newCalendar()
+	now := time.Now().UTC()
+	for _, task := range tasks {
+		calendar.Components = append(calendar.Components, taskComponents(task, kind, now)...)
+	}
+	return ical.Encode(w, calendar)
+}
+
+// newCalendar creates an empty VCALENDAR object
+func newCalendar() *ical.Component {
+	calendar := ical.NewComponent("VCALENDAR")
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", "-//scheduler//Task Scheduler//EN")
+	calendar.Add("CALSCALE", "GREGORIAN")
+	calendar.Add("METHOD", "PUBLISH")
+	calendar.AddText("X-WR-CALNAME", "Scheduler")
+	return calendar
+}
+
+// taskUID returns the iCalendar UID of a task
+func taskUID(id string) string {
+	return "task-" + id + "@scheduler"
+}
+
+// taskComponents renders a task as a single component or, when its repeat rule cannot be expressed as an RRULE,
+// as one component per upcoming occurrence
+func taskComponents(task *models.Task, kind string, now time.Time) []*ical.Component {
+	if task.Repeat == "" {
+		return []*ical.Component{taskComponent(task, kind, task.Date, taskUID(task.ID), "", now)}
+	}
+	if rule, ok := timeutils.RRule(task.Date, task.Repeat); ok {
+		return []*ical.Component{taskComponent(task, kind, task.Date, taskUID(task.ID), rule, now)}
+	}
+
+	dates, err := timeutils.Occurrences(task.Date, task.Repeat, now.Add(expandHorizon), expandLimit)
+	if err != nil || len(dates) == 0 {
+		dates = []string{task.Date}
+	}
+	components := make([]*ical.Component, 0, len(dates))
+	for _, date := range dates {
+		uid := taskUID(task.ID + "-" + date)
+		components = append(components, taskComponent(task, kind, date, uid, "", now))
+	}
+	return components
+}
+
+// taskComponent renders a single occurrence of a task
+func taskComponent(task *models.Task, kind, date, uid, rule string, now time.Time) *ical.Component {
+	name := "VEVENT"
+	if kind == CalendarTodos {
+		name = "VTODO"
+	}
+
+	c := ical.NewComponent(name)
+	c.Add("UID", uid)
+	c.Add("DTSTAMP", now.Format("20060102T150405Z"))
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		c.Add("CREATED", created.UTC().Format("20060102T150405Z"))
+	}
+	c.AddText("SUMMARY", task.Title)
+	if task.Comment != "" {
+		c.AddText("DESCRIPTION", task.Comment)
+	}
+
+	if kind == CalendarTodos {
+		if rule != "" {
+			c.Add("DTSTART", date, "VALUE", "DATE")
+		}
+		c.Add("DUE", date, "VALUE", "DATE")
+	} else {
+		c.Add("DTSTART", date, "VALUE", "DATE")
+		if start, err := time.Parse(dateFormat, date); err == nil {
+			c.Add("DTEND", start.AddDate(0, 0, 1).Format(dateFormat), "VALUE", "DATE")
+		}
+	}
+	if rule != "" {
+		c.Add("RRULE", rule)
+	}
+
+	if len(task.Tags) > 0 {
+		categories := make([]string, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			categories = append(categories, ical.EscapeText(tag))
+		}
+		c.Add("CATEGORIES", strings.Join(categories, ","))
+	}
+	if priority := icalPriority(task.Priority); priority > 0 {
+		c.Add("PRIORITY", fmt.Sprint(priority))
+	}
+	c.Add("STATUS", icalStatus(task.Status, kind))
+	return c
+}
+
+// icalPriority maps a task priority to the iCalendar scale, where 1 is the highest and 0 means undefined
+func icalPriority(priority *models.Priority) int {
+	if priority == nil {
+		return 0
+	}
+	switch *priority {
+	case models.PriorityUrgent:
+		return 1
+	case models.PriorityHigh:
+		return 3
+	case models.PriorityMedium:
+		return 5
+	case models.PriorityLow:
+		return 7
+	}
+	return 0
+}
+
+// icalStatus maps a task status to the iCalendar status of the component
+func icalStatus(status, kind string) string {
+	if kind == CalendarEvents {
+		if status == models.StatusCancelled {
+			return "CANCELLED"
+		}
+		return "CONFIRMED"
+	}
+
+	switch status {
+	case models.StatusInProgress:
+		return "IN-PROCESS"
+	case models.StatusDone:
+		return "COMPLETED"
+	case models.StatusCancelled:
+		return "CANCELLED"
+	}
+	return "NEEDS-ACTION"
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// maxChecklistItemLength limits the length of a checklist item text
+const maxChecklistItemLength = 500
+
+// ChecklistService provides an interface for the checklists of tasks.
+type ChecklistService interface {
+	ListItems(taskID string) ([]*models.ChecklistItem, error)
+	AddItem(taskID, text string) (*models.ChecklistItem, error)
+	ToggleItem(id string) (*models.ChecklistItem, error)
+	DeleteItem(id string) error
+	ReorderItems(taskID string, ids []string) ([]*models.ChecklistItem, error)
+}
+
+// checklistService implements the ChecklistService interface.
+type checklistService struct {
+	tasks repository.TaskRepository      // Repository for the tasks owning the checklists.
+	items repository.ChecklistRepository // Repository for checklist items.
+}
+
+// NewChecklistService creates a new checklist service.
+func NewChecklistService(tasks repository.TaskRepository, items repository.ChecklistRepository) ChecklistService {
+	return &checklistService{tasks: tasks, items: items}
+}
+
+// ListItems returns the checklist of an active task in order.
+func (s *checklistService) ListItems(taskID string) ([]*models.ChecklistItem, error) {
+	if err := s.activeTask(taskID); err != nil {
+		return nil, err
+	}
+	return s.items.List(taskID)
+}
+
+// AddItem appends an unchecked item to the checklist of an active task.
+func (s *checklistService) AddItem(taskID, text string) (*models.ChecklistItem, error) {
+	if err := s.activeTask(taskID); err != nil {
+		return nil, err
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("checklist item text is required")
+	}
+	if len([]rune(text)) > maxChecklistItemLength {
+		return nil, errors.New("checklist item text is too long")
+	}
+
+	item := &models.ChecklistItem{TaskID: taskID, Text: text}
+	id, err := s.items.Create(item)
+	if err != nil {
+		return nil, err
+	}
+	return s.items.GetByID(id)
+}
+
+// ToggleItem checks an unchecked item or unchecks a checked one and returns its new state.
+func (s *checklistService) ToggleItem(id string) (*models.ChecklistItem, error) {
+	item, err := s.item(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.items.SetDone(id, !item.Done); err != nil {
+		return nil, err
+	}
+	item.Done = !item.Done
+	return item, nil
+}
+
+// DeleteItem removes an item from the checklist of an active task.
+func (s *checklistService) DeleteItem(id string) error {
+	if _, err := s.item(id); err != nil {
+		return err
+	}
+	return s.items.Delete(id)
+}
+
+// ReorderItems sets the order of a task's checklist and returns the reordered checklist.
+// The IDs must list every item of the checklist exactly once.
+func (s *checklistService) ReorderItems(taskID string, ids []string) ([]*models.ChecklistItem, error) {
+	if err := s.activeTask(taskID); err != nil {
+		return nil, err
+	}
+	if err := s.items.Reorder(taskID, ids); err != nil {
+		return nil, err
+	}
+	return s.items.List(taskID)
+}
+
+// item returns a checklist item of an active task.
+func (s *checklistService) item(id string) (*models.ChecklistItem, error) {
+	if id == "" {
+		return nil, errors.New("checklist item ID is required")
+	}
+
+	item, err := s.items.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.activeTask(item.TaskID); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// activeTask checks that a task exists and is not in the trash.
+func (s *checklistService) activeTask(taskID string) error {
+	if taskID == "" {
+		return errors.New("task ID is required")
+	}
+	_, err := s.tasks.GetByID(taskID)
+	return err
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// DependencyService provides an interface for dependencies between tasks.
+type DependencyService interface {
+	LinkTasks(taskID, dependsOnID string) error
+	UnlinkTasks(taskID, dependsOnID string) error
+	Graph() (*models.TaskGraph, error)
+}
+
+// dependencyService implements the DependencyService interface.
+type dependencyService struct {
+	tasks        repository.TaskRepository       // Repository for the linked tasks.
+	dependencies repository.DependencyRepository // Repository for dependencies.
+}
+
+// NewDependencyService creates a new dependency service.
+func NewDependencyService(tasks repository.TaskRepository, dependencies repository.DependencyRepository) DependencyService {
+	return &dependencyService{tasks: tasks, dependencies: dependencies}
+}
+
+// LinkTasks makes a task wait until another one is done.
+// Links that would make a task depend on itself, directly or through other tasks, are rejected.
+func (s *dependencyService) LinkTasks(taskID, dependsOnID string) error {
+	if taskID == "" || dependsOnID == "" {
+		return errors.New("task ID and the ID of the task it depends on are required")
+	}
+	for _, id := range []string{taskID, dependsOnID} {
+		if _, err := s.tasks.GetByID(id); err != nil {
+			return err
+		}
+	}
+	return s.dependencies.Link(taskID, dependsOnID)
+}
+
+// UnlinkTasks removes a dependency between two tasks.
+func (s *dependencyService) UnlinkTasks(taskID, dependsOnID string) error {
+	if taskID == "" || dependsOnID == "" {
+		return errors.New("task ID and the ID of the task it depends on are required")
+	}
+	return s.dependencies.Unlink(taskID, dependsOnID)
+}
+
+// Graph returns the active tasks that take part in dependencies together with the dependencies.
+func (s *dependencyService) Graph() (*models.TaskGraph, error) {
+	edges, err := s.dependencies.ListActive()
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &models.TaskGraph{Nodes: []*models.Task{}, Edges: []*models.Dependency{}}
+	seen := make(map[string]bool)
+	for _, edge := range edges {
+		for _, id := range []string{edge.DependsOnID, edge.TaskID} {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			task, err := s.tasks.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			graph.Nodes = append(graph.Nodes, task)
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+	return graph, nil
+}
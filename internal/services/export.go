@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// Formats of exported and imported task data
+const (
+	FormatJSON    = "json"    // A JSON array of tasks
+	FormatNDJSON  = "ndjson"  // One JSON task per line
+	FormatCSV     = "csv"     // One row per task with a header row; notes are not included
+	FormatTodoTxt = "todotxt" // One todo.txt line per task, see todoTxtLine; only tasks and their tags are included
+
+	// Formats of other applications, which are only imported
+	FormatTodoist    = "todoist"     // Todoist Sync API data, see eachTodoistRecord
+	FormatTodoistCSV = "todoist-csv" // A Todoist CSV export or a backup archive of them, see eachTodoistCSVRecord
+	FormatTrello     = "trello"      // A Trello board export, see eachTrelloRecord
+)
+
+// ErrInvalidFormat is returned for an unknown export or import format
+var ErrInvalidFormat = errors.New("invalid format, expected json, ndjson, csv or todotxt; imports also accept todoist, todoist-csv and trello")
+
+// ExportedTask is a task with its related data, as written by an export and read by an import.
+// Projects are referenced by name and dependencies by the IDs of the exported tasks, since IDs differ between instances.
+type ExportedTask struct {
+	ID        string            `json:"id,omitempty"`
+	Date      string            `json:"date"`
+	Title     string            `json:"title"`
+	Comment   string            `json:"comment,omitempty"`
+	Repeat    string            `json:"repeat,omitempty"`
+	Priority  string            `json:"priority,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	CreatedAt string            `json:"created_at,omitempty"`
+	Project   string            `json:"project,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Checklist []ExportedItem    `json:"checklist,omitempty"`
+	Notes     []string          `json:"notes,omitempty"`
+	DependsOn []string          `json:"depends_on,omitempty"`
+
+	untranslated []string // Data imported from another application that has no equivalent, reported as warnings
+}
+
+// ExportedItem is a checklist item of an exported task.
+type ExportedItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done,omitempty"`
+}
+
+// csvColumns - the fixed CSV columns, followed by one column per custom field
+var csvColumns = []string{"id", "date", "title", "comment", "repeat", "priority", "status", "created_at", "project", "tags", "checklist", "depends_on"}
+
+// ExportService provides an interface for exporting all tasks.
+type ExportService interface {
+	Export(w io.Writer, format string) error
+}
+
+// exportService implements the ExportService interface.
+type exportService struct {
+	tasks        repository.TaskRepository       // Repository the tasks are streamed from.
+	projects     repository.ProjectRepository    // Repository for project names.
+	checklists   repository.ChecklistRepository  // Repository for checklist items.
+	notes        repository.NoteRepository       // Repository for notes.
+	dependencies repository.DependencyRepository // Repository for dependencies.
+	fields       repository.FieldRepository      // Repository for custom field definitions, which become CSV columns.
+}
+
+// NewExportService creates a new export service.
+func NewExportService(tasks repository.TaskRepository, projects repository.ProjectRepository, checklists repository.ChecklistRepository,
+	notes repository.NoteRepository, dependencies repository.DependencyRepository, fields repository.FieldRepository) ExportService {
+	return &exportService{
+		tasks:        tasks,
+		projects:     projects,
+		checklists:   checklists,
+		notes:        notes,
+		dependencies: dependencies,
+		fields:       fields,
+	}
+}
+
+// Export writes all tasks outside the trash with their tags, custom fields, checklists, notes and dependencies.
+// Tasks are streamed one by one, so the export does not have to fit in memory.
+func (s *exportService) Export(w io.Writer, format string) error {
+	var write func(task *ExportedTask) error
+	finish := func() error { return nil }
+	related := true
+
+	switch format {
+	case FormatJSON, FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		write = func(task *ExportedTask) error { return encoder.Encode(task) }
+		if format == FormatJSON {
+			if _, err := io.WriteString(w, "[\n"); err != nil {
+				return err
+			}
+			first := true
+			write = func(task *ExportedTask) error {
+				if !first {
+					if _, err := io.WriteString(w, ","); err != nil {
+						return err
+					}
+				}
+				first = false
+				return encoder.Encode(task)
+			}
+			finish = func() error {
+				_, err := io.WriteString(w, "]\n")
+				return err
+			}
+		}
+
+	case FormatCSV:
+		fields, err := s.fields.List()
+		if err != nil {
+			return err
+		}
+		header := append([]string{}, csvColumns...)
+		for _, field := range fields {
+			header = append(header, repository.SortFieldPrefix+field.Name)
+		}
+
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		write = func(task *ExportedTask) error {
+			return writer.Write(csvRecord(task, fields))
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+
+	case FormatTodoTxt:
+		related = false
+		write = func(task *ExportedTask) error {
+			if line := todoTxtLine(task); line != "" {
+				_, err := io.WriteString(w, line+"\n")
+				return err
+			}
+			return nil
+		}
+
+	default:
+		return ErrInvalidFormat
+	}
+
+	projects, err := s.projects.List(true)
+	if err != nil {
+		return err
+	}
+	projectNames := make(map[string]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+
+	dependencies, err := s.dependencies.List()
+	if err != nil {
+		return err
+	}
+	dependsOn := make(map[string][]string)
+	for _, dependency := range dependencies {
+		dependsOn[dependency.TaskID] = append(dependsOn[dependency.TaskID], dependency.DependsOnID)
+	}
+
+	err = s.tasks.Each(func(task *models.Task) error {
+		exported := exportedTask(task)
+		exported.DependsOn = dependsOn[task.ID]
+		if task.ProjectID != nil {
+			exported.Project = projectNames[*task.ProjectID]
+		}
+		if !related {
+			return write(exported)
+		}
+
+		items, err := s.checklists.List(task.ID)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			exported.Checklist = append(exported.Checklist, ExportedItem{Text: item.Text, Done: item.Done})
+		}
+
+		notes, err := s.notes.List(task.ID)
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			exported.Notes = append(exported.Notes, note.Text)
+		}
+		return write(exported)
+	})
+	if err != nil {
+		return err
+	}
+	return finish()
+}
+
+// exportedTask converts a task without its related data
+func exportedTask(task *models.Task) *ExportedTask {
+	exported := &ExportedTask{
+		ID:        task.ID,
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		Status:    task.Status,
+		CreatedAt: task.CreatedAt,
+		Tags:      task.Tags,
+		Fields:    task.Fields,
+	}
+	if task.Priority != nil && *task.Priority != models.PriorityNone {
+		exported.Priority = task.Priority.String()
+	}
+	return exported
+}
+
+// csvRecord flattens a task into a CSV row: tags and dependencies are separated by spaces
+// and checklist items are put on separate lines, marked "[x]" when done and "[ ]" otherwise
+func csvRecord(task *ExportedTask, fields []*models.CustomField) []string {
+	checklist := make([]string, 0, len(task.Checklist))
+	for _, item := range task.Checklist {
+		mark := "[ ] "
+		if item.Done {
+			mark = "[x] "
+		}
+		checklist = append(checklist, mark+item.Text)
+	}
+
+	record := []string{
+		task.ID, task.Date, task.Title, task.Comment, task.Repeat, task.Priority, task.Status, task.CreatedAt, task.Project,
+		strings.Join(task.Tags, " "), strings.Join(checklist, "\n"), strings.Join(task.DependsOn, " "),
+	}
+	for _, field := range fields {
+		record = append(record, task.Fields[field.Name])
+	}
+	return record
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// maxFieldTextLength is the maximum length of a text field value in characters
+const maxFieldTextLength = 1000
+
+// ErrInvalidField is returned when a custom field value does not match the field definition
+var ErrInvalidField = errors.New("invalid custom field")
+
+// fieldNamePattern matches custom field names, which are used as query parameter suffixes
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// FieldService provides an interface for custom field definitions.
+type FieldService interface {
+	CreateField(field *models.CustomField) (string, error)
+	GetField(id string) (*models.CustomField, error)
+	UpdateField(field *models.CustomField) error
+	DeleteField(id string) error
+	ListFields() ([]*models.CustomField, error)
+}
+
+// fieldService implements the FieldService interface.
+type fieldService struct {
+	repo repository.FieldRepository // Repository for custom field definitions.
+}
+
+// NewFieldService creates a new custom field service.
+func NewFieldService(repo repository.FieldRepository) FieldService {
+	return &fieldService{repo: repo}
+}
+
+// CreateField creates a new custom field and returns its ID.
+func (s *fieldService) CreateField(field *models.CustomField) (string, error) {
+	if err := validateField(field); err != nil {
+		return "", err
+	}
+	return s.repo.Create(field)
+}
+
+// GetField returns a custom field by its ID.
+func (s *fieldService) GetField(id string) (*models.CustomField, error) {
+	return s.repo.GetByID(id)
+}
+
+// UpdateField renames a custom field or changes its options; the type of a field cannot be changed.
+func (s *fieldService) UpdateField(field *models.CustomField) error {
+	if field.ID == "" {
+		return errors.New("custom field ID is required")
+	}
+
+	current, err := s.repo.GetByID(field.ID)
+	if err != nil {
+		return err
+	}
+	if field.Type == "" {
+		field.Type = current.Type
+	}
+	if field.Type != current.Type {
+		return errors.New("the type of a custom field cannot be changed")
+	}
+
+	if err := validateField(field); err != nil {
+		return err
+	}
+	return s.repo.Update(field)
+}
+
+// DeleteField deletes a custom field together with its values.
+func (s *fieldService) DeleteField(id string) error {
+	if id == "" {
+		return errors.New("custom field ID is required")
+	}
+	return s.repo.Delete(id)
+}
+
+// ListFields returns all custom fields ordered by name.
+func (s *fieldService) ListFields() ([]*models.CustomField, error) {
+	return s.repo.List()
+}
+
+// validateField checks a custom field definition and normalizes its name and options.
+func validateField(field *models.CustomField) error {
+	field.Name = strings.ToLower(strings.TrimSpace(field.Name))
+	if !fieldNamePattern.MatchString(field.Name) {
+		return errors.New("invalid field name, expected lowercase letters, digits and underscores starting with a letter")
+	}
+
+	switch field.Type {
+	case models.FieldText, models.FieldNumber, models.FieldDate:
+		if len(field.Options) > 0 {
+			return errors.New("only enum fields have options")
+		}
+		field.Options = nil
+	case models.FieldEnum:
+		seen := make(map[string]bool, len(field.Options))
+		options := make([]string, 0, len(field.Options))
+		for _, option := range field.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[option] {
+				continue
+			}
+			seen[option] = true
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return errors.New("enum fields need at least one option")
+		}
+		field.Options = options
+	default:
+		return errors.New("invalid field type, expected text, number, date or enum")
+	}
+	return nil
+}
+
+// normalizeFieldValues checks custom field values against the field definitions and brings them to canonical form.
+// Empty values are dropped. A nil map stays nil, so that callers can tell "no change" from "no values".
+func normalizeFieldValues(fields repository.FieldRepository, values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(values))
+	for name, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		field, err := fields.GetByName(name)
+		if errors.Is(err, repository.ErrFieldNotFound) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidField, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value, err = normalizeFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[field.Name] = value
+	}
+	return normalized, nil
+}
+
+// normalizeFieldValue checks a single value against its field definition.
+func normalizeFieldValue(field *models.CustomField, value string) (string, error) {
+	switch field.Type {
+	case models.FieldNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q expects a number", ErrInvalidField, field.Name)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case models.FieldDate:
+		if _, err := time.Parse(dateFormat, value); err != nil {
+			return "", fmt.Errorf("%w: %q expects a date in the format 20060102", ErrInvalidField, field.Name)
+		}
+	case models.FieldEnum:
+		for _, option := range field.Options {
+			if option == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %q expects one of %s", ErrInvalidField, field.Name, strings.Join(field.Options, ", "))
+	default:
+		if utf8.RuneCountInString(value) > maxFieldTextLength {
+			return "", fmt.Errorf("%w: %q is too long", ErrInvalidField, field.Name)
+		}
+	}
+	return value, nil
+}
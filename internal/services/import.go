@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/ical"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// SourceICal is the source name under which the UIDs of imported iCalendar entries are recorded
+const SourceICal = "ical"
+
+var (
+	// ErrInvalidCalendar is returned when the imported data is not a valid iCalendar object
+	ErrInvalidCalendar = errors.New("invalid iCalendar data")
+	// ErrInvalidImport is returned when imported data cannot be read at all, e.g. a CSV file without a title column
+	ErrInvalidImport = errors.New("invalid import data")
+)
+
+// ImportOptions controls how entries are imported.
+type ImportOptions struct {
+	DryRun    bool              // Only report what would be imported
+	ProjectID string            // Project the imported tasks are added to
+	Columns   map[string]string // CSV columns by task attribute, e.g. "title" or "field.estimate"; unmapped attributes use columns of the same name
+}
+
+// ImportedTask is a task created from an imported entry.
+type ImportedTask struct {
+	ExternalID string       `json:"external_id,omitempty"` // ID of the entry in the source, e.g. the iCalendar UID
+	Task       *models.Task `json:"task"`                  // The task; its ID is empty in a dry run
+}
+
+// ImportIssue describes an entry that was skipped or imported only partially.
+type ImportIssue struct {
+	Row        int    `json:"row,omitempty"`         // Number of the record in the imported file, starting at 1
+	ExternalID string `json:"external_id,omitempty"` // ID of the entry in the source
+	Title      string `json:"title,omitempty"`       // Title of the entry
+	Rule       string `json:"rule,omitempty"`        // Repetition rule that could not be converted
+	Reason     string `json:"reason"`                // Why the entry was skipped or the rule was dropped
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	DryRun      bool            `json:"dry_run"`
+	Created     []*ImportedTask `json:"created"`           // Tasks created, or that would be created in a dry run
+	Skipped     []*ImportIssue  `json:"skipped"`           // Entries that were not imported
+	Unconverted []*ImportIssue  `json:"unconverted_rules"` // Entries imported without their repetition rule
+	Warnings    []*ImportIssue  `json:"warnings"`          // Entries imported without some of their related data
+}
+
+// newImportReport creates an empty report
+func newImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{
+		DryRun:      dryRun,
+		Created:     []*ImportedTask{},
+		Skipped:     []*ImportIssue{},
+		Unconverted: []*ImportIssue{},
+		Warnings:    []*ImportIssue{},
+	}
+}
+
+// ImportService provides an interface for importing tasks from other applications.
+type ImportService interface {
+	ImportICal(ctx context.Context, r io.Reader, options ImportOptions) (*ImportReport, error)
+	ImportTasks(ctx context.Context, r io.Reader, format string, options ImportOptions) (*ImportReport, error)
+}
+
+// importService implements the ImportService interface.
+type importService struct {
+	transactor   repository.Transactor           // Creates the imported tasks together with their links.
+	options      TaskOptions                     // Settings for handling the imported tasks.
+	projects     ProjectService                  // Service for the projects tasks are imported into.
+	dependencies DependencyService               // Service for dependencies between imported tasks.
+	externalIDs  repository.ExternalIDRepository // Links between imported entries and tasks, used to skip re-imports.
+}
+
+// NewImportService creates a new import service creating tasks with the given options.
+func NewImportService(transactor repository.Transactor, options TaskOptions, projects ProjectService,
+	dependencies DependencyService, externalIDs repository.ExternalIDRepository) ImportService {
+	return &importService{
+		transactor:   transactor,
+		options:      options,
+		projects:     projects,
+		dependencies: dependencies,
+		externalIDs:  externalIDs,
+	}
+}
+
+// ImportICal creates tasks from the VTODO and VEVENT entries of an iCalendar object.
+// DTSTART, or DUE for to-dos without a start, becomes the task date and RRULE the repetition rule.
+// Entries whose UID was imported before are skipped, as are one-off events that already took place.
+// Rules without an equivalent are reported, and the task is created without repetition.
+func (s *importService) ImportICal(ctx context.Context, r io.Reader, options ImportOptions) (*ImportReport, error) {
+	roots, err := ical.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	var entries []*ical.Component
+	for _, root := range roots {
+		if root.Name == "VCALENDAR" {
+			entries = append(entries, root.Components...)
+		} else {
+			entries = append(entries, root)
+		}
+	}
+
+	report := newImportReport(options.DryRun)
+	today := time.Now().UTC().Format(dateFormat)
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		if entry.Name != "VTODO" && entry.Name != "VEVENT" {
+			continue
+		}
+
+		uid := entry.Value("UID")
+		task, reason := icalTask(entry)
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, &ImportIssue{ExternalID: uid, Title: task.Title, Reason: reason})
+		}
+
+		switch {
+		case reason != "":
+			skip(reason)
+			continue
+		case entry.Get("RECURRENCE-ID") != nil:
+			skip("changes a single occurrence of a repeating entry")
+			continue
+		case uid != "" && seen[uid]:
+			skip("duplicate UID in the same calendar")
+			continue
+		}
+		seen[uid] = true
+
+		if uid != "" {
+			id, err := s.externalIDs.TaskID(SourceICal, uid)
+			if err != nil {
+				return nil, err
+			}
+			if id != "" {
+				skip("already imported as task " + id)
+				continue
+			}
+		}
+
+		var unconverted *ImportIssue
+		if rule := entry.Value("RRULE"); rule != "" {
+			repeat, err := timeutils.FromRRule(task.Date, rule)
+			if err != nil {
+				unconverted = &ImportIssue{ExternalID: uid, Title: task.Title, Rule: rule, Reason: err.Error()}
+			}
+			task.Repeat = repeat
+		}
+		if entry.Name == "VEVENT" && task.Repeat == "" && task.Date != "" && task.Date < today {
+			skip("event is in the past")
+			continue
+		}
+
+		if options.ProjectID != "" {
+			projectID := options.ProjectID
+			task.ProjectID = &projectID
+		}
+
+		// The UID is linked in the transaction creating the task, so that a task is never left without it
+		id, err := s.createTask(ctx, task, options.DryRun, func(tx *repository.Tx, id string) error {
+			if uid == "" {
+				return nil
+			}
+			return tx.ExternalIDs.Link(SourceICal, uid, id)
+		})
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		task.ID = id
+		report.Created = append(report.Created, &ImportedTask{ExternalID: uid, Task: task})
+		if unconverted != nil {
+			report.Unconverted = append(report.Unconverted, unconverted)
+		}
+	}
+	return report, nil
+}
+
+// errDryRun rolls back the transaction of a task created in a dry run
+var errDryRun = errors.New("dry run")
+
+// createTask creates a task through the task service and runs fn with its ID in the same transaction.
+// In a dry run the transaction is rolled back, so the task goes through the same checks as in a real import
+// without being saved, and the returned ID is empty.
+func (s *importService) createTask(ctx context.Context, task *models.Task, dryRun bool, fn func(tx *repository.Tx, id string) error) (string, error) {
+	var id string
+	err := s.transactor.Transact(func(tx *repository.Tx) error {
+		var err error
+		if id, err = newTxTaskService(tx, s.options).CreateTask(ctx, task); err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(tx, id); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if dryRun && errors.Is(err, errDryRun) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// icalTask converts an iCalendar entry into a task without its repetition rule.
+// A non-empty reason explains why the entry cannot be imported.
+func icalTask(entry *ical.Component) (*models.Task, string) {
+	task := &models.Task{
+		Title:   strings.TrimSpace(entry.Text("SUMMARY")),
+		Comment: entry.Text("DESCRIPTION"),
+	}
+	if task.Title == "" {
+		return task, "missing SUMMARY"
+	}
+
+	start := entry.Value("DTSTART")
+	if start == "" {
+		start = entry.Value("DUE")
+	}
+	if start != "" {
+		// Dates and date-times both start with the date; the time of day is dropped
+		if _, err := time.Parse(dateFormat, start[:min(len(start), 8)]); err != nil {
+			return task, "invalid DTSTART " + strconv.Quote(start)
+		}
+		task.Date = start[:8]
+	}
+
+	if categories := entry.Value("CATEGORIES"); categories != "" {
+		for _, category := range splitEscaped(categories) {
+			if tag := importTag(ical.UnescapeText(category)); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+
+	if value, err := strconv.Atoi(entry.Value("PRIORITY")); err == nil && value > 0 {
+		priority := taskPriority(value)
+		task.Priority = &priority
+	}
+
+	switch entry.Value("STATUS") {
+	case "IN-PROCESS":
+		task.Status = models.StatusInProgress
+	case "COMPLETED":
+		task.Status = models.StatusDone
+	case "CANCELLED":
+		task.Status = models.StatusCancelled
+	}
+	return task, ""
+}
+
+// taskPriority maps an iCalendar priority from 1 (highest) to 9 (lowest) to a task priority
+func taskPriority(value int) models.Priority {
+	switch {
+	case value <= 2:
+		return models.PriorityUrgent
+	case value <= 4:
+		return models.PriorityHigh
+	case value == 5:
+		return models.PriorityMedium
+	}
+	return models.PriorityLow
+}
+
+// importTag turns a category or label name of another application into a tag, replacing spaces and commas with dashes
+func importTag(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	}), "-")
+}
+
+// splitEscaped splits a list of text values on commas that are not escaped
+func splitEscaped(value string) []string {
+	var (
+		parts []string
+		start int
+	)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// importDateLayouts - date formats accepted in imported data, besides the scheduler's own
+var importDateLayouts = []string{dateFormat, "2006-01-02", "02.01.2006"}
+
+// recordFunc receives an imported record, or the error that made the record unreadable.
+// A record with a *ruleError is imported without its repetition rule.
+type recordFunc func(row int, record *ExportedTask, err error) error
+
+// ImportTasks creates tasks from data in one of the export formats, together with their related data.
+// Every record goes through TaskService.CreateTask, in a dry run too; records that fail are listed in the report
+// and the rest are imported.
+// Projects are matched by name and created when missing, and dependencies are linked once all records are imported.
+func (s *importService) ImportTasks(ctx context.Context, r io.Reader, format string, options ImportOptions) (*ImportReport, error) {
+	var each func(r io.Reader, fn recordFunc) error
+	switch format {
+	case FormatJSON:
+		each = eachJSONRecord
+	case FormatNDJSON:
+		each = eachNDJSONRecord
+	case FormatCSV:
+		each = func(r io.Reader, fn recordFunc) error {
+			return eachCSVRecord(r, options.Columns, fn)
+		}
+	case FormatTodoTxt:
+		each = eachTodoTxtRecord
+	case FormatTodoist:
+		each = eachTodoistRecord
+	case FormatTodoistCSV:
+		each = eachTodoistCSVRecord
+	case FormatTrello:
+		each = eachTrelloRecord
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	importer := &taskImporter{
+		importService: s,
+		ctx:           ctx,
+		options:       options,
+		report:        newImportReport(options.DryRun),
+		ids:           make(map[string]string),
+	}
+	if err := each(r, importer.add); err != nil {
+		return nil, err
+	}
+	importer.link()
+	return importer.report, nil
+}
+
+// taskImporter keeps the state of a single import
+type taskImporter struct {
+	*importService
+	ctx        context.Context
+	options    ImportOptions
+	report     *ImportReport
+	projectIDs map[string]string // Project IDs by name, loaded with the first record that names a project
+	ids        map[string]string // IDs of the created tasks by their IDs in the imported data
+	pending    []*pendingLinks   // Dependencies to link once every task exists
+}
+
+// pendingLinks - dependencies of an imported task, by the IDs in the imported data
+type pendingLinks struct {
+	row    int
+	record *ExportedTask
+	taskID string
+}
+
+// add imports a single record
+func (imp *taskImporter) add(row int, record *ExportedTask, err error) error {
+	issue := func(reason string) {
+		issue := &ImportIssue{Row: row, Reason: reason}
+		if record != nil {
+			issue.ExternalID, issue.Title = record.ID, record.Title
+		}
+		imp.report.Skipped = append(imp.report.Skipped, issue)
+	}
+	var unconverted *ruleError
+	if err != nil && !(errors.As(err, &unconverted) && record != nil) {
+		issue(err.Error())
+		return nil
+	}
+
+	task, err := imp.task(record)
+	if err != nil {
+		issue(err.Error())
+		return nil
+	}
+	warn := func(reason string) {
+		imp.report.Warnings = append(imp.report.Warnings, &ImportIssue{Row: row, ExternalID: record.ID, Title: record.Title, Reason: reason})
+	}
+	created := func() {
+		imp.report.Created = append(imp.report.Created, &ImportedTask{ExternalID: record.ID, Task: task})
+		if unconverted != nil {
+			imp.report.Unconverted = append(imp.report.Unconverted, &ImportIssue{
+				Row: row, ExternalID: record.ID, Title: record.Title, Rule: unconverted.Rule, Reason: unconverted.Reason,
+			})
+		}
+		for _, reason := range record.untranslated {
+			warn(reason)
+		}
+	}
+	// The task is created with its checklist and notes in one transaction, which a dry run rolls back,
+	// so that the preview reports exactly what the import does
+	var warnings []string
+	id, err := imp.createTask(imp.ctx, task, imp.options.DryRun, func(tx *repository.Tx, id string) error {
+		checklists := NewChecklistService(tx.Tasks, tx.Checklists)
+		for _, item := range record.Checklist {
+			added, err := checklists.AddItem(id, item.Text)
+			if err == nil && item.Done {
+				_, err = checklists.ToggleItem(added.ID)
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("checklist item %q: %v", item.Text, err))
+			}
+		}
+		notes := NewNoteService(tx.Tasks, tx.Notes)
+		for _, note := range record.Notes {
+			if _, err := notes.AddNote(id, note); err != nil {
+				warnings = append(warnings, "note: "+err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) && imp.options.ProjectID != "" {
+			return err
+		}
+		issue(err.Error())
+		return nil
+	}
+	task.ID = id
+	created()
+	for _, reason := range warnings {
+		warn(reason)
+	}
+
+	if imp.options.DryRun {
+		return nil
+	}
+	if record.ID != "" {
+		imp.ids[record.ID] = id
+	}
+	if len(record.DependsOn) > 0 {
+		imp.pending = append(imp.pending, &pendingLinks{row: row, record: record, taskID: id})
+	}
+	return nil
+}
+
+// task converts a record into a task, resolving the project by name
+func (imp *taskImporter) task(record *ExportedTask) (*models.Task, error) {
+	task, err := recordTask(record)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := imp.options.ProjectID
+	if projectID == "" && record.Project != "" {
+		if projectID, err = imp.project(record.Project); err != nil {
+			return nil, err
+		}
+	}
+	if projectID != "" {
+		task.ProjectID = &projectID
+	}
+	return task, nil
+}
+
+// recordTask converts a record into a task without a project
+func recordTask(record *ExportedTask) (*models.Task, error) {
+	title := strings.TrimSpace(record.Title)
+	if title == "" {
+		return nil, errors.New("task title is required")
+	}
+	date, err := importDate(record.Date)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := models.ParsePriority(strings.ToLower(strings.TrimSpace(record.Priority)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Task{
+		Date:     date,
+		Title:    title,
+		Comment:  record.Comment,
+		Repeat:   strings.TrimSpace(record.Repeat),
+		Priority: &priority,
+		Status:   strings.TrimSpace(record.Status),
+		Tags:     record.Tags,
+		Fields:   record.Fields,
+	}, nil
+}
+
+// project returns the ID of the project with the given name, creating the project if there is none.
+// In a dry run missing projects are not created and the task is shown without a project.
+func (imp *taskImporter) project(name string) (string, error) {
+	if imp.projectIDs == nil {
+		projects, err := imp.projects.ListProjects(true)
+		if err != nil {
+			return "", err
+		}
+		imp.projectIDs = make(map[string]string, len(projects))
+		for _, project := range projects {
+			imp.projectIDs[project.Name] = project.ID
+		}
+	}
+	if id, ok := imp.projectIDs[name]; ok || imp.options.DryRun {
+		return id, nil
+	}
+
+	id, err := imp.projects.CreateProject(&models.Project{Name: name})
+	if err != nil {
+		return "", fmt.Errorf("project %q: %w", name, err)
+	}
+	imp.projectIDs[name] = id
+	return id, nil
+}
+
+// link creates the dependencies between imported tasks; links that fail are reported as warnings
+func (imp *taskImporter) link() {
+	for _, links := range imp.pending {
+		for _, dependsOn := range links.record.DependsOn {
+			id, ok := imp.ids[dependsOn]
+			reason := ""
+			if !ok {
+				reason = fmt.Sprintf("dependency on task %s, which was not imported", dependsOn)
+			} else if err := imp.dependencies.LinkTasks(links.taskID, id); err != nil {
+				reason = fmt.Sprintf("dependency on task %s: %v", dependsOn, err)
+			}
+			if reason != "" {
+				imp.report.Warnings = append(imp.report.Warnings, &ImportIssue{
+					Row: links.row, ExternalID: links.record.ID, Title: links.record.Title, Reason: reason,
+				})
+			}
+		}
+	}
+}
+
+// importDate converts a date in one of the accepted formats into the scheduler's format; an empty date stays empty
+func importDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	for _, layout := range importDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(dateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
+
+// eachJSONRecord reads a JSON array of tasks
+func eachJSONRecord(r io.Reader, fn recordFunc) error {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return fmt.Errorf("%w: expected a JSON array of tasks", ErrInvalidImport)
+	}
+
+	for row := 1; decoder.More(); row++ {
+		var record ExportedTask
+		err := decoder.Decode(&record)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			// The value has been read in full, so the next one can still be decoded
+			err = fn(row, nil, err)
+		case err != nil:
+			// A syntax error leaves the rest of the data unreadable
+			return fn(row, nil, err)
+		default:
+			err = fn(row, &record, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachNDJSONRecord reads one JSON task per line; empty lines are skipped
+func eachNDJSONRecord(r io.Reader, fn recordFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record ExportedTask
+		var err error
+		if err = json.Unmarshal(line, &record); err != nil {
+			err = fn(row, nil, err)
+		} else {
+			err = fn(row, &record, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// maxImportLine is the maximum length of an NDJSON line
+const maxImportLine = 1 << 20
+
+// eachCSVRecord reads CSV rows with a header row, using the column mapping to find the task attributes
+func eachCSVRecord(r io.Reader, mapping map[string]string, fn recordFunc) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: missing CSV header: %v", ErrInvalidImport, err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns, err := csvColumnIndex(header, mapping)
+	if err != nil {
+		return err
+	}
+
+	// The header is row 1
+	for row := 2; ; row++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if err := fn(row, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(row, csvTask(values, columns), nil); err != nil {
+			return err
+		}
+	}
+}
+
+// csvColumnIndex finds the column of every task attribute; attributes without a column are left out
+func csvColumnIndex(header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, attribute := range csvColumns {
+		if i, ok := positions[attribute]; ok {
+			columns[attribute] = i
+		}
+	}
+	for name, i := range positions {
+		if strings.HasPrefix(name, repository.SortFieldPrefix) {
+			columns[name] = i
+		}
+	}
+
+	for attribute, column := range mapping {
+		known := strings.HasPrefix(attribute, repository.SortFieldPrefix)
+		for _, c := range csvColumns {
+			known = known || c == attribute
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown task attribute %q in the column mapping", ErrInvalidImport, attribute)
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("%w: column %q not found", ErrInvalidImport, column)
+		}
+		columns[attribute] = i
+	}
+
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("%w: missing title column", ErrInvalidImport)
+	}
+	return columns, nil
+}
+
+// csvTask builds a record from a CSV row, reversing csvRecord
+func csvTask(values []string, columns map[string]int) *ExportedTask {
+	get := func(attribute string) string {
+		if i, ok := columns[attribute]; ok && i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+	list := func(attribute string) []string {
+		return strings.FieldsFunc(get(attribute), func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	record := &ExportedTask{
+		ID:        strings.TrimSpace(get("id")),
+		Date:      get("date"),
+		Title:     get("title"),
+		Comment:   get("comment"),
+		Repeat:    get("repeat"),
+		Priority:  get("priority"),
+		Status:    get("status"),
+		Project:   strings.TrimSpace(get("project")),
+		Tags:      list("tags"),
+		DependsOn: list("depends_on"),
+	}
+
+	for _, line := range strings.Split(get("checklist"), "\n") {
+		line = strings.TrimSpace(line)
+		item := ExportedItem{Text: line}
+		switch {
+		case strings.HasPrefix(line, "[x] "), strings.HasPrefix(line, "[X] "):
+			item = ExportedItem{Text: strings.TrimSpace(line[4:]), Done: true}
+		case strings.HasPrefix(line, "[ ] "):
+			item.Text = strings.TrimSpace(line[4:])
+		}
+		if item.Text != "" {
+			record.Checklist = append(record.Checklist, item)
+		}
+	}
+
+	for attribute := range columns {
+		name, ok := strings.CutPrefix(attribute, repository.SortFieldPrefix)
+		if value := strings.TrimSpace(get(attribute)); ok && value != "" {
+			if record.Fields == nil {
+				record.Fields = make(map[string]string)
+			}
+			record.Fields[name] = value
+		}
+	}
+	return record
+}
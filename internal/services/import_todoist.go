@@ -0,0 +1,501 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// todoistTime matches a time of day at the end of a due string, as in "every day at 9am" or "tomorrow 18:30"
+var todoistTime = regexp.MustCompile(`(?:\s+at)?\s+\d{1,2}(?::\d{2})?\s*(?:am|pm)$|\s+at\s+\d{1,2}(?::\d{2})?$|\s+\d{1,2}:\d{2}$`)
+
+// todoistDateLayouts - formats of due strings with a month name; dates without a year are the next such day
+var todoistDateLayouts = []string{"Jan 2 2006", "2 Jan 2006", "January 2 2006", "2 January 2006", "Jan 2", "2 Jan", "January 2", "2 January"}
+
+// todoistPriorities - task priorities by Todoist priority as stored by the API, where 4 is "p1";
+// CSV files number priorities the other way round
+var todoistPriorities = map[int]models.Priority{4: models.PriorityUrgent, 3: models.PriorityHigh, 2: models.PriorityMedium}
+
+// todoistID is an ID of Todoist data; older versions of the API use numbers, newer ones strings
+type todoistID string
+
+// UnmarshalJSON accepts both forms of the ID
+func (id *todoistID) UnmarshalJSON(data []byte) error {
+	*id = todoistID(strings.Trim(string(data), `"`))
+	if *id == "null" {
+		*id = ""
+	}
+	return nil
+}
+
+// todoistData - the parts of Todoist Sync API data that are imported
+type todoistData struct {
+	Projects []struct {
+		ID           todoistID `json:"id"`
+		Name         string    `json:"name"`
+		InboxProject bool      `json:"inbox_project"`
+	} `json:"projects"`
+	Sections []struct {
+		ID   todoistID `json:"id"`
+		Name string    `json:"name"`
+	} `json:"sections"`
+	Items []*todoistItem `json:"items"`
+	Notes []struct {
+		ItemID         todoistID       `json:"item_id"`
+		Content        string          `json:"content"`
+		FileAttachment json.RawMessage `json:"file_attachment"`
+		IsDeleted      bool            `json:"is_deleted"`
+	} `json:"notes"`
+}
+
+// todoistItem - a Todoist task
+type todoistItem struct {
+	ID             todoistID       `json:"id"`
+	Content        string          `json:"content"`
+	Description    string          `json:"description"`
+	ProjectID      todoistID       `json:"project_id"`
+	SectionID      todoistID       `json:"section_id"`
+	ParentID       todoistID       `json:"parent_id"`
+	Labels         []string        `json:"labels"`
+	Priority       int             `json:"priority"`
+	Checked        bool            `json:"checked"`
+	IsCompleted    bool            `json:"is_completed"` // The REST API's name for "checked"
+	IsDeleted      bool            `json:"is_deleted"`
+	ResponsibleUID todoistID       `json:"responsible_uid"`
+	AssigneeID     todoistID       `json:"assignee_id"` // The REST API's name for "responsible_uid"
+	Duration       json.RawMessage `json:"duration"`
+	Due            *struct {
+		Date        string `json:"date"`
+		IsRecurring bool   `json:"is_recurring"`
+		String      string `json:"string"`
+	} `json:"due"`
+}
+
+// eachTodoistRecord reads Todoist Sync API data, or the task array returned by the REST API.
+// Projects, labels, priorities, due dates and comments are converted; subtasks become checklist items
+// of their top-level task and sections are dropped.
+func eachTodoistRecord(r io.Reader, fn recordFunc) error {
+	var data todoistData
+	reader := bufio.NewReader(r)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		return fmt.Errorf("%w: expected Todoist data: %v", ErrInvalidImport, err)
+	}
+	if first == '[' {
+		err = json.NewDecoder(reader).Decode(&data.Items)
+	} else {
+		err = json.NewDecoder(reader).Decode(&data)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: expected Todoist data: %v", ErrInvalidImport, err)
+	}
+
+	projects := make(map[todoistID]string, len(data.Projects))
+	for _, project := range data.Projects {
+		if !project.InboxProject {
+			projects[project.ID] = project.Name
+		}
+	}
+	sections := make(map[todoistID]string, len(data.Sections))
+	for _, section := range data.Sections {
+		sections[section.ID] = section.Name
+	}
+
+	items := make(map[todoistID]*todoistItem, len(data.Items))
+	for _, item := range data.Items {
+		items[item.ID] = item
+	}
+	// topLevel returns the task a subtask belongs to, following parents up to the top
+	topLevel := func(item *todoistItem) *todoistItem {
+		for depth := 0; item.ParentID != "" && items[item.ParentID] != nil && depth < len(items); depth++ {
+			item = items[item.ParentID]
+		}
+		return item
+	}
+
+	records := make(map[todoistID]*ExportedTask)
+	rows := make(map[todoistID]int) // Rows are numbered by the position of the task in the data
+	var order []todoistID
+	today := time.Now().UTC()
+	for i, item := range data.Items {
+		if item.IsDeleted || item.ParentID != "" {
+			continue
+		}
+		record := &ExportedTask{
+			ID:       string(item.ID),
+			Title:    item.Content,
+			Comment:  item.Description,
+			Priority: todoistPriorities[item.Priority].String(),
+			Project:  projects[item.ProjectID],
+		}
+		if item.Checked || item.IsCompleted {
+			record.Status = models.StatusDone
+		}
+		for _, label := range item.Labels {
+			record.Tags = append(record.Tags, importTag(label))
+		}
+		if name := sections[item.SectionID]; name != "" {
+			record.untranslated = append(record.untranslated, fmt.Sprintf("section %q has no equivalent", name))
+		}
+		if item.ResponsibleUID != "" || item.AssigneeID != "" {
+			record.untranslated = append(record.untranslated, "the assignee has no equivalent")
+		}
+		if len(item.Duration) > 0 && string(item.Duration) != "null" {
+			record.untranslated = append(record.untranslated, "the duration has no equivalent")
+		}
+		records[item.ID] = record
+		rows[item.ID] = i + 1
+		order = append(order, item.ID)
+	}
+
+	for _, item := range data.Items {
+		if item.IsDeleted || item.ParentID == "" {
+			continue
+		}
+		record := records[topLevel(item).ID]
+		if record == nil {
+			continue
+		}
+		record.Checklist = append(record.Checklist, ExportedItem{Text: item.Content, Done: item.Checked || item.IsCompleted})
+		if item.Due != nil || len(item.Labels) > 0 || item.Description != "" {
+			record.untranslated = append(record.untranslated,
+				fmt.Sprintf("subtask %q became a checklist item without its due date, labels and description", item.Content))
+		}
+	}
+
+	for _, note := range data.Notes {
+		record := records[note.ItemID]
+		if item := items[note.ItemID]; record == nil && item != nil {
+			record = records[topLevel(item).ID]
+		}
+		if record == nil || note.IsDeleted {
+			continue
+		}
+		if strings.TrimSpace(note.Content) != "" {
+			record.Notes = append(record.Notes, note.Content)
+		}
+		if len(note.FileAttachment) > 0 && string(note.FileAttachment) != "null" {
+			record.untranslated = append(record.untranslated, "the file attached to a comment is not imported")
+		}
+	}
+
+	for _, id := range order {
+		record, item := records[id], items[id]
+		var err error
+		if item.Due != nil {
+			record.Date, record.Repeat, err = todoistDue(item.Due.Date, item.Due.String, item.Due.IsRecurring, today)
+		}
+		var unconverted *ruleError
+		if err != nil && !errors.As(err, &unconverted) {
+			record.untranslated = append(record.untranslated, err.Error())
+			err = nil
+		}
+		if err := fn(rows[id], record, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachTodoistCSVRecord reads a CSV file exported from a Todoist project, or a Todoist backup archive
+// with one such file per project, named after the project.
+// Tasks indented under another task become its checklist items, and comments become notes.
+func eachTodoistCSVRecord(r io.Reader, fn recordFunc) error {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(4); !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return eachTodoistCSVFile(reader, "", fn)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("%w: invalid Todoist backup: %v", ErrInvalidImport, err)
+	}
+	files := make([]*zip.File, 0, len(archive.File))
+	for _, file := range archive.File {
+		if strings.EqualFold(path.Ext(file.Name), ".csv") {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	for _, file := range files {
+		// Backups name files "Project name [1234567890].csv"
+		project := strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+		if i := strings.LastIndex(project, " ["); i > 0 && strings.HasSuffix(project, "]") {
+			project = project[:i]
+		}
+		if project == "Inbox" {
+			project = ""
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidImport, file.Name, err)
+		}
+		err = eachTodoistCSVFile(content, project, fn)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// eachTodoistCSVFile reads the tasks of a single Todoist CSV file into the named project
+func eachTodoistCSVFile(r io.Reader, project string, fn recordFunc) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: missing CSV header: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["TYPE"]; !ok {
+		return fmt.Errorf("%w: missing TYPE column of a Todoist CSV file", ErrInvalidImport)
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return fmt.Errorf("%w: missing CONTENT column of a Todoist CSV file", ErrInvalidImport)
+	}
+
+	var (
+		record  *ExportedTask
+		pending error // Error returned with the record
+		row     int   // Row of the record
+	)
+	flush := func() error {
+		if record == nil {
+			return nil
+		}
+		err := fn(row, record, pending)
+		record, pending = nil, nil
+		return err
+	}
+
+	today := time.Now().UTC()
+	// The header is row 1
+	for current := 2; ; current++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+		if err != nil {
+			if err := fn(current, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(values) {
+				return strings.TrimSpace(values[i])
+			}
+			return ""
+		}
+
+		switch kind := strings.ToLower(get("TYPE")); {
+		case kind == "note" && record != nil:
+			if note := get("CONTENT"); note != "" {
+				record.Notes = append(record.Notes, note)
+			}
+
+		case kind == "task" && record != nil && get("INDENT") != "" && get("INDENT") != "1":
+			title, _ := todoistLabels(get("CONTENT"))
+			record.Checklist = append(record.Checklist, ExportedItem{Text: title})
+			if get("DATE") != "" || get("DESCRIPTION") != "" {
+				record.untranslated = append(record.untranslated,
+					fmt.Sprintf("subtask %q became a checklist item without its due date and description", title))
+			}
+
+		case kind == "task":
+			if err := flush(); err != nil {
+				return err
+			}
+			title, tags := todoistLabels(get("CONTENT"))
+			record = &ExportedTask{Title: title, Comment: get("DESCRIPTION"), Project: project, Tags: tags}
+			row = current
+			// CSV files number priorities from 1 for "p1" to 4 for "p4"
+			if priority, err := strconv.Atoi(get("PRIORITY")); err == nil {
+				record.Priority = todoistPriorities[5-priority].String()
+			}
+			if get("RESPONSIBLE") != "" {
+				record.untranslated = append(record.untranslated, "the assignee has no equivalent")
+			}
+			if get("DURATION") != "" {
+				record.untranslated = append(record.untranslated, "the duration has no equivalent")
+			}
+			if due := get("DATE"); due != "" {
+				record.Date, record.Repeat, pending = todoistDue("", due, false, today)
+				var unconverted *ruleError
+				if pending != nil && !errors.As(pending, &unconverted) {
+					record.untranslated = append(record.untranslated, pending.Error())
+					pending = nil
+				}
+			}
+
+		case kind == "section":
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := fn(current, nil, fmt.Errorf("section %q has no equivalent; its tasks are imported without it", get("CONTENT"))); err != nil {
+				return err
+			}
+
+		case kind != "":
+			if err := fn(current, nil, fmt.Errorf("unknown row type %q", kind)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// todoistLabels takes the "@label" words, which CSV files keep in the task content, out of the title
+func todoistLabels(content string) (string, []string) {
+	var title, tags []string
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && word[0] == '@' {
+			tags = append(tags, importTag(word[1:]))
+		} else {
+			title = append(title, word)
+		}
+	}
+	return strings.Join(title, " "), tags
+}
+
+// todoistDue converts a Todoist due date into a task date and repetition rule.
+// date is the next occurrence, which API data has and CSV files do not, and phrase is the due string
+// as typed in Todoist, such as "every! 2 weeks" or "tomorrow at 9am"; times of day are dropped.
+// A recurrence without an equivalent is reported as a *ruleError, other phrases that are not understood
+// as a plain error; the date is returned in both cases when it is known.
+func todoistDue(date, phrase string, recurring bool, today time.Time) (string, string, error) {
+	if date != "" {
+		var err error
+		if date, err = importDate(date[:min(len(date), 10)]); err != nil {
+			return "", "", err
+		}
+	}
+	phrase = strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+	phrase = strings.ReplaceAll(phrase, "every!", "every")
+	phrase = todoistTime.ReplaceAllString(phrase, "")
+
+	words := strings.Fields(phrase)
+	recurring = recurring || strings.Contains(" "+phrase+" ", " every ") || (len(words) == 1 && strings.HasSuffix(phrase, "ly"))
+	if !recurring {
+		if date != "" || phrase == "" {
+			return date, "", nil
+		}
+		if parsed, err := importDate(phrase); err == nil {
+			return parsed, "", nil
+		}
+		if parsed, ok := todoistMonthDate(phrase, today); ok {
+			return parsed, "", nil
+		}
+		if parsed, repeat, ok := parseQuickDate(phrase, today); ok && repeat == "" {
+			return parsed, "", nil
+		}
+		return "", "", fmt.Errorf("due date %q is not recognized", phrase)
+	}
+
+	anchor := today
+	if date != "" {
+		anchor, _ = time.Parse(dateFormat, date)
+	}
+	if next, repeat, ok := parseQuickDate(phrase, anchor); ok && repeat != "" {
+		if date == "" {
+			date = next
+		}
+		return date, repeat, nil
+	}
+	repeat, err := timeutils.FromRecurrence(anchor.Format(dateFormat), todoistRecurrence(words))
+	if err != nil {
+		return date, "", &ruleError{Rule: phrase, Reason: err.Error()}
+	}
+	return date, repeat, nil
+}
+
+// todoistRecurrence rewrites Todoist recurrences that list weekdays or days of the month,
+// such as "every mon, fri" or "every 1st and 15th", in the form read by timeutils.FromRecurrence
+func todoistRecurrence(words []string) string {
+	var listed []string
+	for _, word := range words {
+		if word = strings.Trim(word, ","); word != "" && word != "and" && word != "day" {
+			listed = append(listed, word)
+		}
+	}
+	if len(listed) < 2 || listed[0] != "every" {
+		return strings.Join(words, " ")
+	}
+	listed = listed[1:]
+	if len(listed) == 1 && listed[0] == "workday" {
+		return "every weekday"
+	}
+
+	weekdays, ordinals := make([]string, 0, len(listed)), make([]string, 0, len(listed))
+	for _, word := range listed {
+		if weekday, ok := weekdayWord(word, true); ok {
+			weekdays = append(weekdays, time.Weekday(weekday%7).String())
+		}
+		if ordinalPattern.MatchString(word) || word == "last" {
+			ordinals = append(ordinals, word)
+		}
+	}
+	switch {
+	case len(weekdays) == len(listed):
+		return "every week on " + strings.Join(weekdays, ", ")
+	case len(ordinals) == len(listed):
+		return "every month on the " + strings.Join(ordinals, ", ")
+	}
+	return strings.Join(words, " ")
+}
+
+// todoistMonthDate parses dates with a month name such as "Jan 10" or "10 January 2030"
+func todoistMonthDate(phrase string, today time.Time) (string, bool) {
+	phrase = strings.ReplaceAll(phrase, ",", "")
+	for _, layout := range todoistDateLayouts {
+		date, err := time.Parse(layout, phrase)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			date = date.AddDate(today.Year(), 0, 0)
+			if date.Before(today.Truncate(24 * time.Hour)) {
+				date = date.AddDate(1, 0, 0)
+			}
+		}
+		return date.Format(dateFormat), true
+	}
+	return "", false
+}
+
+// peekNonSpace returns the first byte of the data that is not white space, without consuming it
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' && b != 0xef && b != 0xbb && b != 0xbf {
+			return b, reader.UnreadByte()
+		}
+	}
+}
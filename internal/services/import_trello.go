@@ -0,0 +1,166 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// trelloBoard - the parts of a Trello board export that are imported
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string   `json:"id"`
+		Name        string   `json:"name"`
+		Desc        string   `json:"desc"`
+		Closed      bool     `json:"closed"`
+		Due         *string  `json:"due"`
+		DueComplete bool     `json:"dueComplete"`
+		Start       *string  `json:"start"`
+		IDList      string   `json:"idList"`
+		IDMembers   []string `json:"idMembers"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+		Badges struct {
+			Attachments int `json:"attachments"`
+		} `json:"badges"`
+		CustomFieldItems []json.RawMessage `json:"customFieldItems"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+	} `json:"actions"`
+}
+
+// eachTrelloRecord reads the cards of a Trello board export. The board becomes the project, the list
+// and the labels of a card its tags, the due date its date and comments its notes; checklists are merged
+// into one. Archived cards and cards of archived lists are skipped.
+func eachTrelloRecord(r io.Reader, fn recordFunc) error {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return fmt.Errorf("%w: expected a Trello board export: %v", ErrInvalidImport, err)
+	}
+	if board.Cards == nil {
+		return fmt.Errorf("%w: expected a Trello board export with cards", ErrInvalidImport)
+	}
+
+	lists := make(map[string]string, len(board.Lists))
+	closedLists := make(map[string]bool)
+	for _, list := range board.Lists {
+		lists[list.ID] = list.Name
+		closedLists[list.ID] = list.Closed
+	}
+
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	checklists := make(map[string][]ExportedItem)
+	for _, checklist := range board.Checklists {
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			checklists[checklist.IDCard] = append(checklists[checklist.IDCard], ExportedItem{Text: item.Name, Done: item.State == "complete"})
+		}
+	}
+
+	// Actions are listed newest first
+	comments := make(map[string][]string)
+	for i := len(board.Actions) - 1; i >= 0; i-- {
+		if action := board.Actions[i]; action.Type == "commentCard" && action.Data.Text != "" {
+			comments[action.Data.Card.ID] = append(comments[action.Data.Card.ID], action.Data.Text)
+		}
+	}
+
+	for i, card := range board.Cards {
+		record := &ExportedTask{
+			ID:        card.ID,
+			Title:     card.Name,
+			Comment:   card.Desc,
+			Project:   board.Name,
+			Checklist: checklists[card.ID],
+			Notes:     comments[card.ID],
+		}
+		var err error
+		switch {
+		case card.Closed:
+			err = errors.New("the card is archived")
+		case closedLists[card.IDList]:
+			err = fmt.Errorf("list %q is archived", lists[card.IDList])
+		}
+		if err != nil {
+			if err := fn(i+1, record, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tag := importTag(lists[card.IDList]); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			if tag := importTag(name); tag != "" {
+				record.Tags = append(record.Tags, tag)
+			}
+		}
+
+		if card.Due != nil {
+			due, err := time.Parse(time.RFC3339, *card.Due)
+			if err != nil {
+				if err := fn(i+1, record, fmt.Errorf("invalid due date %q", *card.Due)); err != nil {
+					return err
+				}
+				continue
+			}
+			// Due dates are stored in UTC and shown in local time
+			record.Date = due.Local().Format(dateFormat)
+		}
+		if card.DueComplete {
+			record.Status = models.StatusDone
+		}
+
+		if card.Start != nil {
+			record.untranslated = append(record.untranslated, "the start date has no equivalent")
+		}
+		if len(card.IDMembers) > 0 {
+			record.untranslated = append(record.untranslated, "card members have no equivalent")
+		}
+		if card.Badges.Attachments > 0 {
+			record.untranslated = append(record.untranslated, fmt.Sprintf("%d attachments are not imported", card.Badges.Attachments))
+		}
+		if len(card.CustomFieldItems) > 0 {
+			record.untranslated = append(record.untranslated, "custom field values are not imported")
+		}
+		if err := fn(i+1, record, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
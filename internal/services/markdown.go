@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// markdownCheckbox matches a Markdown list item with a checkbox: the indentation and list marker, the mark and the text
+var markdownCheckbox = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+)\[(.)\]\s+(.*)$`)
+
+// Task fields of the Obsidian Tasks plugin
+const (
+	markdownDue        = "📅"
+	markdownRecurrence = "🔁"
+	markdownDone       = "✅"
+	markdownCancelled  = "❌"
+	markdownID         = "🆔"
+)
+
+// markdownPriorities - priority emojis of the Obsidian Tasks plugin; "lowest" is imported as low
+var markdownPriorities = map[string]models.Priority{
+	"🔺": models.PriorityUrgent,
+	"⏫": models.PriorityHigh,
+	"🔼": models.PriorityMedium,
+	"🔽": models.PriorityLow,
+	"⏬": models.PriorityLow,
+}
+
+// markdownKeptFields - fields of the Obsidian Tasks plugin without a task attribute, which are kept as they are:
+// scheduled, start and created dates, dependencies and the action on completion
+var markdownKeptFields = map[string]bool{"⏳": true, "🛫": true, "➕": true, "⛔": true, "🏁": true}
+
+// markdownMarks - checkbox marks by task status
+var markdownMarks = map[string]string{
+	models.StatusTodo:       " ",
+	models.StatusInProgress: "/",
+	models.StatusDone:       "x",
+	models.StatusCancelled:  "-",
+}
+
+// markdownTask is a checkbox item of a Markdown file, in the format of the Obsidian Tasks plugin:
+// "- [ ] Water the plants #home 🔼 🔁 every week 📅 2025-01-10 🆔 a1b2c3".
+type markdownTask struct {
+	Prefix        string   // Indentation and list marker
+	Status        string   // Status given by the checkbox mark
+	Title         string   // Text without tags and fields
+	Tags          []string // "#tags", without the "#"
+	Priority      models.Priority
+	Recurrence    string   // Recurrence text, e.g. "every week"
+	Date          string   // Due date in the format 20060102
+	DoneDate      string   // Completion date in the format 2006-01-02
+	CancelledDate string   // Cancellation date in the format 2006-01-02
+	Kept          []string // Other fields, such as "⏳ 2025-01-09"
+	ID            string   // Stable ID linking the item to a task
+}
+
+// parseMarkdownTask reads a checkbox item from a line of a Markdown file.
+// It returns nil for lines that are not checkbox items or have an unknown mark.
+func parseMarkdownTask(line string) (*markdownTask, error) {
+	match := markdownCheckbox.FindStringSubmatch(line)
+	if match == nil {
+		return nil, nil
+	}
+	item := &markdownTask{Prefix: match[1]}
+	for status, mark := range markdownMarks {
+		if strings.EqualFold(match[2], mark) {
+			item.Status = status
+		}
+	}
+	if item.Status == "" {
+		return nil, nil
+	}
+
+	words := strings.Fields(match[3])
+	var title []string
+	for i := 0; i < len(words); i++ {
+		word := strings.TrimSuffix(words[i], "\ufe0f")
+		// value returns the words up to the next field
+		value := func() string {
+			start := i + 1
+			for i+1 < len(words) && !isMarkdownField(words[i+1]) {
+				i++
+			}
+			return strings.Join(words[start:i+1], " ")
+		}
+
+		switch priority, ok := markdownPriorities[word]; {
+		case ok:
+			item.Priority = priority
+		case word == markdownDue:
+			value := value()
+			date, err := time.Parse(todoTxtDate, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date %q", value)
+			}
+			item.Date = date.Format(dateFormat)
+		case word == markdownRecurrence:
+			item.Recurrence = value()
+		case word == markdownDone:
+			item.DoneDate = value()
+		case word == markdownCancelled:
+			item.CancelledDate = value()
+		case word == markdownID:
+			item.ID = value()
+		case markdownKeptFields[word]:
+			item.Kept = append(item.Kept, word+" "+value())
+		case len(word) > 1 && word[0] == '#':
+			item.Tags = append(item.Tags, word[1:])
+		default:
+			title = append(title, words[i])
+		}
+	}
+	item.Title = strings.Join(title, " ")
+	return item, nil
+}
+
+// isMarkdownField reports whether a word starts a task field
+func isMarkdownField(word string) bool {
+	word = strings.TrimSuffix(word, "\ufe0f")
+	_, priority := markdownPriorities[word]
+	return priority || markdownKeptFields[word] ||
+		word == markdownDue || word == markdownRecurrence || word == markdownDone || word == markdownCancelled || word == markdownID
+}
+
+// String formats the item as a line of a Markdown file
+func (item *markdownTask) String() string {
+	parts := []string{item.Prefix + "[" + markdownMarks[item.Status] + "]", item.Title}
+	for _, tag := range item.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	for emoji, priority := range markdownPriorities {
+		// "⏬" is only read, low priority is written as "🔽"
+		if priority == item.Priority && emoji != "⏬" {
+			parts = append(parts, emoji)
+		}
+	}
+	if item.Recurrence != "" {
+		parts = append(parts, markdownRecurrence, item.Recurrence)
+	}
+	parts = append(parts, item.Kept...)
+	if date, err := time.Parse(dateFormat, item.Date); err == nil {
+		parts = append(parts, markdownDue, date.Format(todoTxtDate))
+	}
+	if item.DoneDate != "" {
+		parts = append(parts, markdownDone, item.DoneDate)
+	}
+	if item.CancelledDate != "" {
+		parts = append(parts, markdownCancelled, item.CancelledDate)
+	}
+	if item.ID != "" {
+		parts = append(parts, markdownID, item.ID)
+	}
+	return strings.Join(parts, " ")
+}
+
+// task converts the item into a task. The repetition rule is empty when the recurrence
+// has no equivalent, which is reported as a *ruleError together with the task.
+func (item *markdownTask) task() (*models.Task, error) {
+	if item.Title == "" {
+		return nil, errors.New("task title is required")
+	}
+	priority := item.Priority
+	task := &models.Task{
+		Date:     item.Date,
+		Title:    item.Title,
+		Priority: &priority,
+		Status:   item.Status,
+		Tags:     item.Tags,
+	}
+	if task.Tags == nil {
+		task.Tags = []string{}
+	}
+
+	if item.Recurrence != "" {
+		repeat, err := timeutils.FromRecurrence(item.Date, item.Recurrence)
+		if err != nil {
+			return task, &ruleError{Rule: item.Recurrence, Reason: err.Error()}
+		}
+		task.Repeat = repeat
+	}
+	return task, nil
+}
+
+// update changes the item to match the task, keeping its list marker, kept fields and ID.
+// Completion and cancellation dates are set to today when the task reaches that status.
+func (item *markdownTask) update(task *models.Task) {
+	today := time.Now().Format(todoTxtDate)
+	if task.Status == models.StatusDone && item.Status != models.StatusDone {
+		item.DoneDate = today
+	}
+	if task.Status != models.StatusDone {
+		item.DoneDate = ""
+	}
+	if task.Status == models.StatusCancelled && item.Status != models.StatusCancelled {
+		item.CancelledDate = today
+	}
+	if task.Status != models.StatusCancelled {
+		item.CancelledDate = ""
+	}
+
+	item.Status = task.Status
+	item.Title = strings.Join(strings.Fields(task.Title), " ")
+	item.Tags = task.Tags
+	item.Priority = models.PriorityNone
+	if task.Priority != nil {
+		item.Priority = *task.Priority
+	}
+	item.Date = task.Date
+	if recurrence, ok := timeutils.Recurrence(task.Date, task.Repeat); ok {
+		item.Recurrence = recurrence
+	} else {
+		item.Recurrence = ""
+	}
+}
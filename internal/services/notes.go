@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// maxNoteLength limits the length of a note text
+const maxNoteLength = 5000
+
+// NoteService provides an interface for the notes of tasks.
+type NoteService interface {
+	ListNotes(taskID string) ([]*models.Note, error)
+	AddNote(taskID, text string) (*models.Note, error)
+	EditNote(id, text string) (*models.Note, error)
+	DeleteNote(id string) error
+}
+
+// noteService implements the NoteService interface.
+type noteService struct {
+	tasks repository.TaskRepository // Repository for the tasks owning the notes.
+	notes repository.NoteRepository // Repository for notes.
+}
+
+// NewNoteService creates a new note service.
+func NewNoteService(tasks repository.TaskRepository, notes repository.NoteRepository) NoteService {
+	return &noteService{tasks: tasks, notes: notes}
+}
+
+// ListNotes returns the notes of an active task, oldest first.
+func (s *noteService) ListNotes(taskID string) ([]*models.Note, error) {
+	if err := s.activeTask(taskID); err != nil {
+		return nil, err
+	}
+	return s.notes.List(taskID)
+}
+
+// AddNote adds a note to an active task.
+func (s *noteService) AddNote(taskID, text string) (*models.Note, error) {
+	if err := s.activeTask(taskID); err != nil {
+		return nil, err
+	}
+
+	text, err := noteText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &models.Note{TaskID: taskID, Text: text}
+	id, err := s.notes.Create(note)
+	if err != nil {
+		return nil, err
+	}
+	return s.notes.GetByID(id)
+}
+
+// EditNote replaces the text of a note and returns the edited note.
+func (s *noteService) EditNote(id, text string) (*models.Note, error) {
+	if _, err := s.note(id); err != nil {
+		return nil, err
+	}
+
+	text, err := noteText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.notes.Update(id, text); err != nil {
+		return nil, err
+	}
+	return s.notes.GetByID(id)
+}
+
+// DeleteNote removes a note from an active task.
+func (s *noteService) DeleteNote(id string) error {
+	if _, err := s.note(id); err != nil {
+		return err
+	}
+	return s.notes.Delete(id)
+}
+
+// note returns a note of an active task.
+func (s *noteService) note(id string) (*models.Note, error) {
+	if id == "" {
+		return nil, errors.New("note ID is required")
+	}
+
+	note, err := s.notes.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.activeTask(note.TaskID); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// activeTask checks that a task exists and is not in the trash.
+func (s *noteService) activeTask(taskID string) error {
+	if taskID == "" {
+		return errors.New("task ID is required")
+	}
+	_, err := s.tasks.GetByID(taskID)
+	return err
+}
+
+// noteText trims a note text and checks its length.
+func noteText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("note text is required")
+	}
+	if len([]rune(text)) > maxNoteLength {
+		return "", errors.New("note text is too long")
+	}
+	return text, nil
+}
@@ -0,0 +1,21 @@
+package services
+
+import "time"
+
+// runPeriodically calls fn right away and then at the given interval, in the background until the stop channel is closed
+func runPeriodically(interval time.Duration, stop <-chan struct{}, fn func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		fn()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// What happens to the tasks of a deleted project
+const (
+	ProjectTasksMove    = "move"    // Move the tasks to another project or out of any project
+	ProjectTasksArchive = "archive" // Keep the tasks and archive the project instead of deleting it
+)
+
+// ErrProjectNotEmpty is returned when a project with tasks is deleted without choosing what to do with them
+var ErrProjectNotEmpty = errors.New("project has tasks, choose to archive the project or move its tasks")
+
+// colorPattern matches colors in the format "#rrggbb"
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ProjectService provides an interface for project operations.
+type ProjectService interface {
+	CreateProject(project *models.Project) (string, error)
+	GetProject(id string) (*models.Project, error)
+	UpdateProject(project *models.Project) error
+	ListProjects(includeArchived bool) ([]*models.Project, error)
+	DeleteProject(id, tasks, targetID string) error
+	RestoreProject(id string) error
+}
+
+// projectService implements the ProjectService interface.
+type projectService struct {
+	repo       repository.ProjectRepository // Repository for projects.
+	transactor repository.Transactor        // Runs multi-step changes atomically.
+}
+
+// NewProjectService creates a new project service.
+func NewProjectService(repo repository.ProjectRepository, transactor repository.Transactor) ProjectService {
+	return &projectService{repo: repo, transactor: transactor}
+}
+
+// CreateProject creates a new project and returns its ID.
+func (s *projectService) CreateProject(project *models.Project) (string, error) {
+	if err := validateProject(project); err != nil {
+		return "", err
+	}
+	return s.repo.Create(project)
+}
+
+// GetProject returns a project by its ID.
+func (s *projectService) GetProject(id string) (*models.Project, error) {
+	return s.repo.GetByID(id)
+}
+
+// UpdateProject updates an existing project.
+func (s *projectService) UpdateProject(project *models.Project) error {
+	if project.ID == "" {
+		return errors.New("project ID is required")
+	}
+	if err := validateProject(project); err != nil {
+		return err
+	}
+	return s.repo.Update(project)
+}
+
+// ListProjects returns projects ordered by name.
+func (s *projectService) ListProjects(includeArchived bool) ([]*models.Project, error) {
+	return s.repo.List(includeArchived)
+}
+
+// DeleteProject deletes a project.
+// A project with tasks can only be archived (ProjectTasksArchive)
+// or deleted after moving its tasks to the target project (ProjectTasksMove, an empty target removes the tasks from any project).
+// Without an option only a project no task refers to is deleted; done, cancelled and deleted tasks count too.
+func (s *projectService) DeleteProject(id, tasks, targetID string) error {
+	if id == "" {
+		return errors.New("project ID is required")
+	}
+
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+
+	switch tasks {
+	case ProjectTasksArchive:
+		return s.repo.SetArchived(id, true)
+	case ProjectTasksMove:
+		if targetID == id {
+			return errors.New("cannot move tasks to the project being deleted")
+		}
+		if targetID != "" {
+			target, err := s.repo.GetByID(targetID)
+			if err != nil {
+				return err
+			}
+			if target.ArchivedAt != "" {
+				return errors.New("target project is archived")
+			}
+		}
+	case "":
+		// Checked in the transaction below, as the task count of the project leaves out closed and deleted tasks
+	default:
+		return errors.New("unknown option for project tasks")
+	}
+
+	return s.transactor.Transact(func(tx *repository.Tx) error {
+		if tasks == ProjectTasksMove {
+			// Deleted tasks in the trash are moved as well, so that restoring them does not refer to a missing project
+			if _, err := tx.Projects.MoveTasks(id, targetID); err != nil {
+				return err
+			}
+		} else {
+			count, err := tx.Projects.CountTasks(id)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrProjectNotEmpty
+			}
+		}
+		return tx.Projects.Delete(id)
+	})
+}
+
+// RestoreProject unarchives a project.
+func (s *projectService) RestoreProject(id string) error {
+	if id == "" {
+		return errors.New("project ID is required")
+	}
+	return s.repo.SetArchived(id, false)
+}
+
+// validateProject checks the project fields and normalizes the name.
+func validateProject(project *models.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		return errors.New("project name is required")
+	}
+
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		return errors.New("invalid color, expected #rrggbb")
+	}
+
+	if project.DefaultRepeat != "" {
+		today := time.Now().UTC()
+		if _, err := timeutils.NextDate(today, today.Format(dateFormat), project.DefaultRepeat); err != nil {
+			return errors.New("invalid repeat rule")
+		}
+	}
+	return nil
+}
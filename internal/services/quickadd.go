@@ -0,0 +1,541 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+)
+
+// ErrQuickTitle is returned when nothing is left for the title after the date, repeat rule, tags and priority are extracted
+var ErrQuickTitle = errors.New("task title is required")
+
+// QuickTask is the structure extracted from a one-line task entry.
+type QuickTask struct {
+	Title    string           `json:"title"`              // Text left after extracting everything else
+	Date     string           `json:"date,omitempty"`     // Task date in the format "20060102"
+	Repeat   string           `json:"repeat,omitempty"`   // Repetition rule
+	Tags     []string         `json:"tags,omitempty"`     // Tags given as "#tag"
+	Priority *models.Priority `json:"priority,omitempty"` // Priority given as "!high"
+}
+
+// QuickAddService provides an interface for creating tasks from a single line of text.
+type QuickAddService interface {
+	Parse(line string) (*QuickTask, error)
+	AddTask(ctx context.Context, line string) (string, *QuickTask, error)
+}
+
+// quickAddService implements the QuickAddService interface.
+type quickAddService struct {
+	tasks TaskService // Creates the parsed tasks.
+}
+
+// NewQuickAddService creates a new quick-add service.
+func NewQuickAddService(tasks TaskService) QuickAddService {
+	return &quickAddService{tasks: tasks}
+}
+
+// Parse extracts the task structure from a line without creating the task.
+func (s *quickAddService) Parse(line string) (*QuickTask, error) {
+	return parseQuickTask(line, time.Now().UTC())
+}
+
+// AddTask creates a task from a line and returns its ID together with the parsed structure.
+// The structure reflects the task as it was created, e.g. with the date moved forward by the repeat rule.
+func (s *quickAddService) AddTask(ctx context.Context, line string) (string, *QuickTask, error) {
+	parsed, err := s.Parse(line)
+	if err != nil {
+		return "", nil, err
+	}
+
+	task := &models.Task{
+		Title:    parsed.Title,
+		Date:     parsed.Date,
+		Repeat:   parsed.Repeat,
+		Tags:     parsed.Tags,
+		Priority: parsed.Priority,
+	}
+	id, err := s.tasks.CreateTask(ctx, task)
+	if err != nil {
+		return "", nil, err
+	}
+
+	parsed.Date = task.Date
+	parsed.Tags = task.Tags
+	return id, parsed, nil
+}
+
+// Kinds of repetition whose rule depends on the task date
+const (
+	repeatWeekly  = "weekly"
+	repeatMonthly = "monthly"
+)
+
+var (
+	// ordinalPattern matches days of the month such as "5th" or "5-го"
+	ordinalPattern = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th|-го|-е)$`)
+	// fullDatePattern matches dates such as "05.03.2025" or "05.03"
+	fullDatePattern = regexp.MustCompile(`^(\d{1,2})\.(\d{2})(?:\.(\d{4}))?$`)
+	// isoDatePattern matches dates such as "2025-03-05"
+	isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// quickPriorities - priorities written after "!"
+var quickPriorities = map[string]models.Priority{
+	"none": models.PriorityNone, "low": models.PriorityLow, "medium": models.PriorityMedium,
+	"high": models.PriorityHigh, "urgent": models.PriorityUrgent,
+	"1": models.PriorityLow, "2": models.PriorityMedium, "3": models.PriorityHigh, "4": models.PriorityUrgent,
+	"низкий": models.PriorityLow, "средний": models.PriorityMedium, "высокий": models.PriorityHigh, "срочно": models.PriorityUrgent,
+}
+
+// quickWeekdays - full weekday names in English and Russian (including the accusative case), Monday is 1
+var quickWeekdays = map[string]int{
+	"monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6, "sunday": 7,
+	"понедельник": 1, "вторник": 2, "среда": 3, "среду": 3, "четверг": 4,
+	"пятница": 5, "пятницу": 5, "суббота": 6, "субботу": 6, "воскресенье": 7,
+}
+
+// quickShortWeekdays - abbreviated weekday names, recognized only after "on", "next" or "every"
+var quickShortWeekdays = map[string]int{
+	"mon": 1, "tue": 2, "tues": 2, "wed": 3, "thu": 4, "thur": 4, "thurs": 4, "fri": 5, "sat": 6, "sun": 7,
+	"пн": 1, "вт": 2, "ср": 3, "чт": 4, "пт": 5, "сб": 6, "вс": 7,
+}
+
+// quickPluralWeekdays - weekdays in the plural, as in "on mondays" or "по понедельникам"
+var quickPluralWeekdays = map[string]int{
+	"mondays": 1, "tuesdays": 2, "wednesdays": 3, "thursdays": 4, "fridays": 5, "saturdays": 6, "sundays": 7,
+	"понедельникам": 1, "вторникам": 2, "средам": 3, "четвергам": 4, "пятницам": 5, "субботам": 6, "воскресеньям": 7,
+}
+
+// quickUnits - time units after a number, as in "in 3 days" or "через 2 недели"
+var quickUnits = map[string]string{
+	"day": "d", "days": "d", "week": "w", "weeks": "w", "month": "m", "months": "m", "year": "y", "years": "y",
+	"день": "d", "дня": "d", "дней": "d", "неделю": "w", "недели": "w", "недель": "w",
+	"месяц": "m", "месяца": "m", "месяцев": "m", "год": "y", "года": "y", "лет": "y",
+}
+
+// quickNumbers - numbers written as words
+var quickNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"один": 1, "одну": 1, "одно": 1, "два": 2, "две": 2, "три": 3, "четыре": 4, "пять": 5,
+	"шесть": 6, "семь": 7, "восемь": 8, "девять": 9, "десять": 10,
+}
+
+// Words introducing dates and repetition
+var (
+	quickOn    = map[string]bool{"on": true, "в": true, "во": true}
+	quickNext  = map[string]bool{"next": true, "следующий": true, "следующую": true, "следующее": true, "следующая": true}
+	quickEvery = map[string]bool{"every": true, "каждый": true, "каждую": true, "каждое": true, "каждые": true, "каждая": true}
+	quickIn    = map[string]bool{"in": true, "через": true}
+)
+
+// quickParser holds the state of parsing a single line
+type quickParser struct {
+	today    time.Time
+	date     time.Time
+	hasDate  bool
+	repeat   string
+	priority *models.Priority
+	tags     []string
+}
+
+// parseQuickTask extracts the title, date, repeat rule, tags and priority from a line.
+// Words that do not belong to any of these stay in the title in their original form.
+func parseQuickTask(line string, now time.Time) (*QuickTask, error) {
+	p := &quickParser{today: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+
+	tokens := strings.Fields(line)
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		words[i] = strings.Trim(strings.ToLower(token), ",.;:?")
+	}
+
+	var title []string
+	for i := 0; i < len(tokens); {
+		if n := p.match(tokens[i], words[i:]); n > 0 {
+			i += n
+			continue
+		}
+		title = append(title, tokens[i])
+		i++
+	}
+
+	task := &QuickTask{Title: strings.Join(title, " "), Priority: p.priority}
+	if task.Title == "" {
+		return nil, ErrQuickTitle
+	}
+
+	tags, err := normalizeTags(p.tags)
+	if err != nil {
+		return nil, err
+	}
+	task.Tags = tags
+	task.Date, task.Repeat = p.result()
+	return task, nil
+}
+
+// parseQuickDate reads a phrase consisting only of a date and repetition, such as "every friday" or "tomorrow".
+// The last value is false when some words are not recognized.
+func parseQuickDate(phrase string, now time.Time) (string, string, bool) {
+	p := &quickParser{today: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+	words := strings.Fields(strings.ToLower(phrase))
+	for i := range words {
+		words[i] = strings.Trim(words[i], ",.;:?")
+	}
+
+	for i := 0; i < len(words); {
+		n := 0
+		if p.repeat == "" {
+			n = p.matchRepeat(words[i:])
+		}
+		if n == 0 && !p.hasDate {
+			n = p.matchDate(words[i:])
+		}
+		if n == 0 {
+			return "", "", false
+		}
+		i += n
+	}
+	date, repeat := p.result()
+	return date, repeat, len(words) > 0
+}
+
+// result returns the parsed date and repeat rule.
+// Weekly and monthly rules take the weekday or day of the month from the task date.
+func (p *quickParser) result() (string, string) {
+	anchor := p.today
+	if p.hasDate {
+		anchor = p.date
+	}
+	switch p.repeat {
+	case repeatWeekly:
+		p.repeat = fmt.Sprintf("w %d", isoWeekday(anchor))
+	case repeatMonthly:
+		p.repeat = fmt.Sprintf("m %d", anchor.Day())
+	}
+
+	date := ""
+	if p.hasDate {
+		date = p.date.Format(dateFormat)
+	}
+	return date, p.repeat
+}
+
+// match recognizes a tag, priority, repeat rule or date at the start of words and returns the number of words it takes
+func (p *quickParser) match(token string, words []string) int {
+	switch {
+	case strings.HasPrefix(token, "#") && len(token) > 1:
+		p.tags = append(p.tags, strings.TrimRight(token[1:], ",.;:"))
+		return 1
+	case strings.HasPrefix(token, "!") && p.priority == nil:
+		if priority, ok := quickPriorities[strings.TrimLeft(words[0], "!")]; ok {
+			p.priority = &priority
+			return 1
+		}
+	}
+
+	if p.repeat == "" {
+		if n := p.matchRepeat(words); n > 0 {
+			return n
+		}
+	}
+	if !p.hasDate {
+		if n := p.matchDate(words); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// matchRepeat recognizes repetition such as "daily", "every 3 days", "every friday" or "каждый месяц"
+func (p *quickParser) matchRepeat(words []string) int {
+	switch words[0] {
+	case "daily", "ежедневно":
+		p.repeat = "d 1"
+		return 1
+	case "weekly", "еженедельно":
+		p.repeat = repeatWeekly
+		return 1
+	case "monthly", "ежемесячно":
+		p.repeat = repeatMonthly
+		return 1
+	case "yearly", "annually", "ежегодно":
+		p.repeat = "y"
+		return 1
+	}
+
+	if len(words) < 2 {
+		return 0
+	}
+
+	// "on mondays", "по понедельникам"
+	if words[0] == "on" || words[0] == "по" {
+		if weekday, ok := quickPluralWeekdays[words[1]]; ok {
+			p.setWeeklyRepeat(weekday)
+			return 2
+		}
+		return 0
+	}
+
+	if !quickEvery[words[0]] {
+		return 0
+	}
+
+	if weekday, ok := weekdayWord(words[1], true); ok {
+		p.setWeeklyRepeat(weekday)
+		return 2
+	}
+	if words[1] == "other" && len(words) > 2 {
+		switch quickUnits[words[2]] {
+		case "d":
+			p.repeat = "d 2"
+			return 3
+		case "w":
+			p.repeat = "d 14"
+			return 3
+		}
+		return 0
+	}
+
+	count, n := 1, 1
+	if number, ok := quickNumber(words[1]); ok && len(words) > 2 {
+		count, n = number, 2
+	}
+	switch unit := quickUnits[words[n]]; {
+	case unit == "d":
+		p.repeat = fmt.Sprintf("d %d", count)
+	case unit == "w" && count == 1:
+		p.repeat = repeatWeekly
+	case unit == "w":
+		p.repeat = fmt.Sprintf("d %d", 7*count)
+	case unit == "m" && count == 1:
+		p.repeat = repeatMonthly
+	case unit == "y" && count == 1:
+		p.repeat = "y"
+	default:
+		return 0
+	}
+	return n + 1
+}
+
+// setWeeklyRepeat repeats the task on a weekday and moves an unset date to its next occurrence
+func (p *quickParser) setWeeklyRepeat(weekday int) {
+	p.repeat = fmt.Sprintf("w %d", weekday)
+	if !p.hasDate {
+		p.setDate(nextWeekday(p.today, weekday, true))
+	}
+}
+
+// matchDate recognizes dates such as "tomorrow", "next friday", "in 3 days", "5th", "05.03.2025" or "послезавтра"
+func (p *quickParser) matchDate(words []string) int {
+	switch words[0] {
+	case "today", "сегодня":
+		p.setDate(p.today)
+		return 1
+	case "tomorrow", "завтра":
+		p.setDate(p.today.AddDate(0, 0, 1))
+		return 1
+	case "послезавтра":
+		p.setDate(p.today.AddDate(0, 0, 2))
+		return 1
+	}
+
+	if date, ok := p.explicitDate(words[0]); ok {
+		p.setDate(date)
+		return 1
+	}
+	if weekday, ok := weekdayWord(words[0], false); ok {
+		p.setDate(nextWeekday(p.today, weekday, false))
+		return 1
+	}
+	if len(words) < 2 {
+		return 0
+	}
+
+	// Prepositions are taken only together with the date that follows them
+	if quickOn[words[0]] {
+		if weekday, ok := weekdayWord(words[1], true); ok {
+			p.setDate(nextWeekday(p.today, weekday, false))
+			return 2
+		}
+		if n := p.matchDate(words[1:]); n > 0 {
+			return n + 1
+		}
+		return 0
+	}
+
+	if len(words) > 2 && words[0] == "day" && words[1] == "after" && words[2] == "tomorrow" {
+		p.setDate(p.today.AddDate(0, 0, 2))
+		return 3
+	}
+	if words[0] == "the" {
+		if date, ok := p.ordinalDate(words[1]); ok {
+			p.setDate(date)
+			return 2
+		}
+		return 0
+	}
+
+	// "5 числа", "5-го числа"
+	if words[1] == "числа" {
+		if day, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(words[0], "-го"), "-е")); err == nil {
+			if date, ok := p.dayOfMonth(day); ok {
+				p.setDate(date)
+				return 2
+			}
+		}
+		return 0
+	}
+
+	// "next friday", "next week", "следующую пятницу"
+	if quickNext[words[0]] {
+		if weekday, ok := weekdayWord(words[1], true); ok {
+			p.setDate(nextWeekday(p.today, weekday, false))
+			return 2
+		}
+		switch quickUnits[words[1]] {
+		case "w":
+			p.setDate(p.today.AddDate(0, 0, 7))
+			return 2
+		case "m":
+			p.setDate(p.today.AddDate(0, 1, 0))
+			return 2
+		}
+		return 0
+	}
+
+	// "на следующей неделе"
+	if len(words) > 2 && words[0] == "на" && words[1] == "следующей" && words[2] == "неделе" {
+		p.setDate(p.today.AddDate(0, 0, 7))
+		return 3
+	}
+
+	// "in 3 days", "in a week", "через неделю", "через 2 месяца"
+	if quickIn[words[0]] {
+		count, n := 1, 1
+		if number, ok := quickNumber(words[1]); ok && len(words) > 2 {
+			count, n = number, 2
+		}
+		switch quickUnits[words[n]] {
+		case "d":
+			p.setDate(p.today.AddDate(0, 0, count))
+		case "w":
+			p.setDate(p.today.AddDate(0, 0, 7*count))
+		case "m":
+			p.setDate(p.today.AddDate(0, count, 0))
+		case "y":
+			p.setDate(p.today.AddDate(count, 0, 0))
+		default:
+			return 0
+		}
+		return n + 1
+	}
+	return 0
+}
+
+// explicitDate parses dates written with digits; dates without a year are the next such day
+func (p *quickParser) explicitDate(word string) (time.Time, bool) {
+	if date, ok := p.ordinalDate(word); ok {
+		return date, true
+	}
+
+	if isoDatePattern.MatchString(word) {
+		date, err := time.Parse("2006-01-02", word)
+		return date, err == nil
+	}
+
+	m := fullDatePattern.FindStringSubmatch(word)
+	if m == nil {
+		return time.Time{}, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	year := p.today.Year()
+	if m[3] != "" {
+		year, _ = strconv.Atoi(m[3])
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, false
+	}
+	if m[3] == "" && date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, true
+}
+
+// ordinalDate parses a day of the month such as "5th"
+func (p *quickParser) ordinalDate(word string) (time.Time, bool) {
+	m := ordinalPattern.FindStringSubmatch(word)
+	if m == nil {
+		return time.Time{}, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	return p.dayOfMonth(day)
+}
+
+// dayOfMonth returns the next date, starting today, falling on the given day of the month
+func (p *quickParser) dayOfMonth(day int) (time.Time, bool) {
+	if day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	for months := 0; months < 12; months++ {
+		first := time.Date(p.today.Year(), p.today.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+		date := first.AddDate(0, 0, day-1)
+		if date.Month() == first.Month() && !date.Before(p.today) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// setDate records the task date
+func (p *quickParser) setDate(date time.Time) {
+	p.date = date
+	p.hasDate = true
+}
+
+// weekdayWord recognizes a weekday name; abbreviations are accepted only when allowed
+func weekdayWord(word string, allowShort bool) (int, bool) {
+	if weekday, ok := quickWeekdays[word]; ok {
+		return weekday, true
+	}
+	if allowShort {
+		weekday, ok := quickShortWeekdays[word]
+		return weekday, ok
+	}
+	return 0, false
+}
+
+// quickNumber recognizes a positive number written with digits or as a word
+func quickNumber(word string) (int, bool) {
+	if number, ok := quickNumbers[word]; ok {
+		return number, true
+	}
+	number, err := strconv.Atoi(word)
+	return number, err == nil && number > 0
+}
+
+// nextWeekday returns the next date falling on the weekday (Monday is 1), optionally including today
+func nextWeekday(today time.Time, weekday int, includeToday bool) time.Time {
+	days := (weekday - isoWeekday(today) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// isoWeekday returns the weekday of a date with Monday as 1 and Sunday as 7
+func isoWeekday(date time.Time) int {
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return weekday
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTagLength is the maximum length of a tag in characters
+const maxTagLength = 50
+
+// ErrInvalidTag is returned when a tag cannot be used
+var ErrInvalidTag = errors.New("invalid tag")
+
+// normalizeTags validates tags and brings them to canonical form:
+// lowercase, without the leading "#", without duplicates and sorted.
+// A nil slice stays nil, so that callers can tell "no change" from "no tags".
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tag cannot be empty", ErrInvalidTag)
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q is too long", ErrInvalidTag, tag)
+		}
+		if strings.IndexFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) >= 0 {
+			return nil, fmt.Errorf("%w: %q contains spaces or commas", ErrInvalidTag, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/VladimirVereshchagin/scheduler/internal/models"
@@ -13,28 +15,126 @@ import (
 const dateFormat = "20060102"
 
 // TaskService provides an interface for task operations.
+// Operations that modify tasks take a context carrying the actor for the audit trail,
+// and record the change in it in the same transaction.
 type TaskService interface {
-	CreateTask(task *models.Task) (string, error)
+	CreateTask(ctx context.Context, task *models.Task) (string, error)
 	GetTaskByID(id string) (*models.Task, error)
-	UpdateTask(task *models.Task) error
-	DeleteTask(id string) error
-	ListTasks(search string, limit int) ([]*models.Task, error)
-	MarkTaskDone(id string) error
+	UpdateTask(ctx context.Context, task *models.Task) error
+	DeleteTask(ctx context.Context, id string, version int64) error
+	ListTasks(filter repository.TaskFilter) ([]*models.Task, error)
+	ListTags() ([]*models.TagCount, error)
+	MarkTaskDone(ctx context.Context, id string, opts DoneOptions) ([]*models.Task, error)
+	SetStatus(ctx context.Context, id, status string, version int64) error
 	CalculateNextDate(nowStr, dateStr, repeat string) (string, error)
+	ListTrash(limit int) ([]*models.Task, error)
+	RestoreTask(ctx context.Context, id string) error
+	PurgeTask(ctx context.Context, id string) error
+	PurgeTrash(retention time.Duration) (int64, error)
+	TaskHistory(id string, limit int) ([]*models.Completion, error)
+	CompletionLog(from, to string, limit int) ([]*models.Completion, error)
+}
+
+var (
+	// ErrInvalidStatus is returned for statuses other than todo, in_progress, done and cancelled
+	ErrInvalidStatus = errors.New("invalid status, expected todo, in_progress, done or cancelled")
+	// ErrInvalidTransition is returned when a task cannot move from its current status to the requested one
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// TaskOptions holds settings that change how tasks are handled.
+type TaskOptions struct {
+	DeleteOnDone bool // Move completed one-off tasks to the trash instead of keeping them as done
+}
+
+// DoneOptions holds optional parameters for completing a task.
+type DoneOptions struct {
+	Version     int64     // Expected task version, 0 means any
+	CompletedAt time.Time // Actual completion time, zero means now
+	Note        string    // Note stored with the completion record
 }
 
 // taskService implements the TaskService interface.
 type taskService struct {
-	repo repository.TaskRepository // Repository for interacting with the database.
+	repo         repository.TaskRepository       // Repository for interacting with the database.
+	completions  repository.CompletionRepository // Repository for the completion history.
+	audit        repository.AuditRepository      // Repository for the audit trail of changes.
+	projects     repository.ProjectRepository    // Repository for the projects tasks belong to.
+	checklists   repository.ChecklistRepository  // Repository for the checklists of tasks.
+	dependencies repository.DependencyRepository // Repository for dependencies between tasks.
+	fields       repository.FieldRepository      // Repository for custom field definitions.
+	transactor   repository.Transactor           // Runs multi-step changes atomically; nil when the repositories are bound to a transaction.
+	options      TaskOptions                     // Settings for handling tasks.
 }
 
 // NewTaskService creates a new task service.
-func NewTaskService(repo repository.TaskRepository) TaskService {
-	return &taskService{repo: repo}
+func NewTaskService(
+	repo repository.TaskRepository,
+	completions repository.CompletionRepository,
+	audit repository.AuditRepository,
+	projects repository.ProjectRepository,
+	checklists repository.ChecklistRepository,
+	dependencies repository.DependencyRepository,
+	fields repository.FieldRepository,
+	transactor repository.Transactor,
+	options TaskOptions,
+) TaskService {
+	return &taskService{
+		repo:         repo,
+		completions:  completions,
+		audit:        audit,
+		projects:     projects,
+		checklists:   checklists,
+		dependencies: dependencies,
+		fields:       fields,
+		transactor:   transactor,
+		options:      options,
+	}
+}
+
+// transact runs fn with the service bound to a new transaction, so that a change made in several steps
+// is either saved completely or not at all. Services whose repositories already belong to a transaction
+// run fn directly.
+func (s *taskService) transact(fn func(s *taskService) error) error {
+	if s.transactor == nil {
+		return fn(s)
+	}
+	return s.transactor.Transact(func(tx *repository.Tx) error {
+		return fn(newTxTaskService(tx, s.options))
+	})
+}
+
+// newTxTaskService creates a task service whose changes belong to a transaction,
+// for services that make further changes together with the task.
+func newTxTaskService(tx *repository.Tx, options TaskOptions) *taskService {
+	return &taskService{
+		repo:         tx.Tasks,
+		completions:  tx.Completions,
+		audit:        tx.Audit,
+		projects:     tx.Projects,
+		checklists:   tx.Checklists,
+		dependencies: tx.Dependencies,
+		fields:       tx.Fields,
+		options:      options,
+	}
 }
 
 // CreateTask creates a new task and returns its ID.
-func (s *taskService) CreateTask(task *models.Task) (string, error) {
+// Tasks created in a project without a repeat rule inherit the project's default rule.
+// New tasks have the todo status unless another status is given.
+func (s *taskService) CreateTask(ctx context.Context, task *models.Task) (string, error) {
+	if task.Status != "" && !models.IsValidStatus(task.Status) {
+		return "", ErrInvalidStatus
+	}
+
+	project, err := s.taskProject(task)
+	if err != nil {
+		return "", err
+	}
+	if project != nil && task.Repeat == "" {
+		task.Repeat = project.DefaultRepeat
+	}
+
 	now := time.Now().UTC()
 	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
@@ -65,7 +165,43 @@ func (s *taskService) CreateTask(task *models.Task) (string, error) {
 		task.Date = now.Format(dateFormat)
 	}
 
-	return s.repo.Create(task)
+	tags, err := normalizeTags(task.Tags)
+	if err != nil {
+		return "", err
+	}
+
+	task.Tags = tags
+
+	fields, err := normalizeFieldValues(s.fields, task.Fields)
+	if err != nil {
+		return "", err
+	}
+	task.Fields = fields
+
+	// The task is created together with its tags, custom field values and audit entry,
+	// so that a failure does not leave it behind without them
+	var id string
+	err = s.transact(func(s *taskService) error {
+		var err error
+		if id, err = s.repo.Create(task); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := s.repo.SetTags(id, tags); err != nil {
+				return err
+			}
+		}
+		if len(fields) > 0 {
+			if err := s.repo.SetFields(id, fields); err != nil {
+				return err
+			}
+		}
+		return s.record(ctx, ActionCreate, id, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
 }
 
 // GetTaskByID returns a task by its ID.
@@ -74,11 +210,17 @@ func (s *taskService) GetTaskByID(id string) (*models.Task, error) {
 }
 
 // UpdateTask updates an existing task.
-func (s *taskService) UpdateTask(task *models.Task) error {
+// A non-zero task.Version makes the update conditional on the stored version.
+// The status is not changed; it is changed with SetStatus and MarkTaskDone.
+func (s *taskService) UpdateTask(ctx context.Context, task *models.Task) error {
 	if task.ID == "" {
 		return errors.New("task ID is required")
 	}
 
+	if _, err := s.taskProject(task); err != nil {
+		return err
+	}
+
 	now := time.Now().UTC()
 	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 
@@ -108,47 +250,248 @@ func (s *taskService) UpdateTask(task *models.Task) error {
 		task.Date = now.Format(dateFormat)
 	}
 
-	return s.repo.Update(task)
+	tags, err := normalizeTags(task.Tags)
+	if err != nil {
+		return err
+	}
+
+	task.Tags = tags
+
+	fields, err := normalizeFieldValues(s.fields, task.Fields)
+	if err != nil {
+		return err
+	}
+	task.Fields = fields
+
+	// Tags and custom field values are replaced only when they were provided, in the same transaction as the task
+	return s.transact(func(s *taskService) error {
+		before := s.snapshot(task.ID)
+		if err := s.repo.Update(task); err != nil {
+			return err
+		}
+		if task.Tags != nil {
+			if err := s.repo.SetTags(task.ID, tags); err != nil {
+				return err
+			}
+		}
+		if task.Fields != nil {
+			if err := s.repo.SetFields(task.ID, fields); err != nil {
+				return err
+			}
+		}
+		return s.record(ctx, ActionUpdate, task.ID, before)
+	})
 }
 
-// DeleteTask deletes a task by its ID.
-func (s *taskService) DeleteTask(id string) error {
+// taskProject returns the project a task is being assigned to, or nil if there is none.
+// Tasks cannot be added to archived projects.
+func (s *taskService) taskProject(task *models.Task) (*models.Project, error) {
+	if task.ProjectID == nil || *task.ProjectID == "" {
+		return nil, nil
+	}
+
+	project, err := s.projects.GetByID(*task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.ArchivedAt != "" {
+		return nil, errors.New("project is archived")
+	}
+	return project, nil
+}
+
+// DeleteTask moves a task to the trash by its ID.
+// A non-zero version makes the deletion conditional on the stored version.
+func (s *taskService) DeleteTask(ctx context.Context, id string, version int64) error {
 	if id == "" {
 		return errors.New("task ID is required")
 	}
-	return s.repo.Delete(id)
+	return s.transact(func(s *taskService) error {
+		before := s.snapshot(id)
+		if err := s.repo.Delete(id, version); err != nil {
+			return err
+		}
+		return s.record(ctx, ActionDelete, id, before)
+	})
+}
+
+// ListTasks returns a list of tasks matching the filter.
+func (s *taskService) ListTasks(filter repository.TaskFilter) ([]*models.Task, error) {
+	for _, status := range filter.Statuses {
+		if !models.IsValidStatus(status) {
+			return nil, ErrInvalidStatus
+		}
+	}
+
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+
+	fields, err := normalizeFieldValues(s.fields, filter.Fields)
+	if err != nil {
+		return nil, err
+	}
+	filter.Fields = fields
+	return s.repo.List(filter)
 }
 
-// ListTasks returns a list of tasks with optional search and limit parameters.
-func (s *taskService) ListTasks(search string, limit int) ([]*models.Task, error) {
-	return s.repo.List(search, limit)
+// ListTags returns all tags with the number of tasks using them.
+func (s *taskService) ListTags() ([]*models.TagCount, error) {
+	return s.repo.ListTags()
 }
 
-// MarkTaskDone marks a task as done.
-func (s *taskService) MarkTaskDone(id string) error {
+// MarkTaskDone marks a task as done and records the completion in the history.
+// One-off tasks get the done status (or are moved to the trash with TaskOptions.DeleteOnDone),
+// repeating tasks are rolled forward to the next date as todo with their checklist unchecked.
+// It returns the dependent tasks that are no longer blocked.
+func (s *taskService) MarkTaskDone(ctx context.Context, id string, opts DoneOptions) ([]*models.Task, error) {
 	if id == "" {
-		return errors.New("task ID is required")
+		return nil, errors.New("task ID is required")
+	}
+
+	completedAt := opts.CompletedAt
+	if completedAt.IsZero() {
+		completedAt = time.Now()
 	}
+	if completedAt.After(time.Now()) {
+		return nil, errors.New("completion time cannot be in the future")
+	}
+
+	// The task is read and changed in one transaction with the completion record and the audit entry,
+	// so that a task is never rolled forward without its completion appearing in the history
+	var unblocked []*models.Task
+	err := s.transact(func(s *taskService) error {
+		task, err := s.repo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		before := *task
+
+		if opts.Version != 0 && task.Version != opts.Version {
+			return repository.ErrVersionConflict
+		}
+		if models.IsClosedStatus(task.Status) {
+			return fmt.Errorf("%w: task is already %s", ErrInvalidTransition, task.Status)
+		}
+
+		blocked, err := s.blockedDependents(id)
+		if err != nil {
+			return err
+		}
+
+		completion := &models.Completion{
+			TaskID:        task.ID,
+			Title:         task.Title,
+			ScheduledDate: task.Date,
+			CompletedAt:   completedAt.UTC().Format(time.RFC3339),
+			Note:          opts.Note,
+		}
+
+		// The writes are guarded by the version that was read, so a concurrent modification results
+		// in a conflict instead of being overwritten
+		switch {
+		case task.Repeat == "" && s.options.DeleteOnDone:
+			err = s.repo.Delete(id, task.Version)
+		case task.Repeat == "":
+			err = s.repo.SetStatus(id, models.StatusDone, task.Version)
+		default:
+			now := time.Now().UTC()
+			now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+			var nextDate string
+			nextDate, err = timeutils.NextDate(now, task.Date, task.Repeat)
+			if err != nil {
+				return err
+			}
+
+			task.Date = nextDate
+			if err = s.repo.Update(task); err == nil && task.Status != models.StatusTodo {
+				err = s.repo.SetStatus(id, models.StatusTodo, task.Version)
+			}
+			if err == nil {
+				err = s.checklists.Reset(id)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.completions.Create(completion); err != nil {
+			return err
+		}
+		if err := s.record(ctx, ActionDone, id, &before); err != nil {
+			return err
+		}
 
-	task, err := s.repo.GetByID(id)
+		for _, dependent := range blocked {
+			dependent, err := s.repo.GetByID(dependent.ID)
+			if err != nil {
+				return err
+			}
+			if !dependent.Blocked {
+				unblocked = append(unblocked, dependent)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return unblocked, nil
+}
 
-	if task.Repeat == "" {
-		return s.repo.Delete(id)
+// blockedDependents returns the active tasks that depend on a task and are currently blocked.
+func (s *taskService) blockedDependents(id string) ([]*models.Task, error) {
+	ids, err := s.dependencies.Dependents(id)
+	if err != nil {
+		return nil, err
 	}
 
-	now := time.Now().UTC()
-	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var blocked []*models.Task
+	for _, dependentID := range ids {
+		dependent, err := s.repo.GetByID(dependentID)
+		if err != nil {
+			return nil, err
+		}
+		if dependent.Blocked {
+			blocked = append(blocked, dependent)
+		}
+	}
+	return blocked, nil
+}
 
-	nextDate, err := timeutils.NextDate(now, task.Date, task.Repeat)
-	if err != nil {
-		return err
+// SetStatus moves a task to another status.
+// Tasks are completed with MarkTaskDone, which also records the completion and rolls repeating tasks forward.
+func (s *taskService) SetStatus(ctx context.Context, id, status string, version int64) error {
+	if id == "" {
+		return errors.New("task ID is required")
+	}
+	if !models.IsValidStatus(status) {
+		return ErrInvalidStatus
 	}
+	if status == models.StatusDone {
+		return errors.New("tasks are completed with MarkTaskDone")
+	}
+
+	return s.transact(func(s *taskService) error {
+		task, err := s.repo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && task.Version != version {
+			return repository.ErrVersionConflict
+		}
+		if !models.CanTransition(task.Status, status) {
+			return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, task.Status, status)
+		}
 
-	task.Date = nextDate
-	return s.repo.Update(task)
+		if err := s.repo.SetStatus(id, status, task.Version); err != nil {
+			return err
+		}
+		return s.record(ctx, ActionStatus, id, task)
+	})
 }
 
 // CalculateNextDate calculates the next task date based on the provided parameters.
@@ -164,3 +507,72 @@ func (s *taskService) CalculateNextDate(nowStr, dateStr, repeat string) (string,
 
 	return timeutils.NextDate(now, dateStr, repeat)
 }
+
+// ListTrash returns deleted tasks that have not been purged yet.
+func (s *taskService) ListTrash(limit int) ([]*models.Task, error) {
+	return s.repo.ListDeleted(limit)
+}
+
+// RestoreTask moves a task from the trash back to the active list.
+func (s *taskService) RestoreTask(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.New("task ID is required")
+	}
+	return s.transact(func(s *taskService) error {
+		if err := s.repo.Restore(id); err != nil {
+			return err
+		}
+		return s.record(ctx, ActionRestore, id, nil)
+	})
+}
+
+// PurgeTask permanently deletes a task from the trash.
+func (s *taskService) PurgeTask(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.New("task ID is required")
+	}
+	return s.transact(func(s *taskService) error {
+		if err := s.repo.Purge(id); err != nil {
+			return err
+		}
+		return s.record(ctx, ActionPurge, id, nil)
+	})
+}
+
+// PurgeTrash permanently deletes tasks that have been in the trash longer than the retention period.
+// A zero retention empties the trash completely.
+func (s *taskService) PurgeTrash(retention time.Duration) (int64, error) {
+	return s.repo.PurgeDeletedBefore(time.Now().Add(-retention))
+}
+
+// TaskHistory returns the completion history of a task, most recent first.
+func (s *taskService) TaskHistory(id string, limit int) ([]*models.Completion, error) {
+	if id == "" {
+		return nil, errors.New("task ID is required")
+	}
+	return s.completions.List(repository.CompletionFilter{TaskID: id, Limit: limit})
+}
+
+// CompletionLog returns completions of all tasks within an optional range.
+// Both bounds are in the format "20060102" (inclusive) or RFC 3339.
+func (s *taskService) CompletionLog(from, to string, limit int) ([]*models.Completion, error) {
+	filter := repository.CompletionFilter{Limit: limit}
+
+	if from != "" {
+		bound, err := parseBound(from, false)
+		if err != nil {
+			return nil, errors.New("invalid 'from' parameter")
+		}
+		filter.From = bound
+	}
+
+	if to != "" {
+		bound, err := parseBound(to, true)
+		if err != nil {
+			return nil, errors.New("invalid 'to' parameter")
+		}
+		filter.To = bound
+	}
+
+	return s.completions.List(filter)
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// Built-in template placeholders
+const (
+	PlaceholderDate   = "date"   // Date of the created task
+	PlaceholderAnchor = "anchor" // Anchor date the task date is computed from
+)
+
+// placeholderDateFormat is the format dates are substituted in
+const placeholderDateFormat = "02.01.2006"
+
+// ErrMissingPlaceholder is returned when a template is instantiated without a value for one of its placeholders
+var ErrMissingPlaceholder = errors.New("missing value for placeholder")
+
+// placeholderPattern matches placeholders such as {{date}} or {{ sprint }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// InstantiateOptions controls how a template is turned into a task.
+type InstantiateOptions struct {
+	Anchor string            // Anchor date in the format "20060102"; today by default
+	Values map[string]string // Values of the placeholders other than the built-in ones
+}
+
+// TemplateService provides an interface for task templates.
+type TemplateService interface {
+	CreateTemplate(template *models.Template) (string, error)
+	GetTemplate(id string) (*models.Template, error)
+	UpdateTemplate(template *models.Template) error
+	DeleteTemplate(id string) error
+	ListTemplates() ([]*models.Template, error)
+	Instantiate(ctx context.Context, id string, options InstantiateOptions) (string, error)
+}
+
+// templateService implements the TemplateService interface.
+type templateService struct {
+	repo       repository.TemplateRepository // Repository for templates.
+	transactor repository.Transactor         // Creates the tasks together with their checklists.
+	options    TaskOptions                   // Settings for handling the created tasks.
+}
+
+// NewTemplateService creates a new template service creating tasks with the given options.
+func NewTemplateService(repo repository.TemplateRepository, transactor repository.Transactor, options TaskOptions) TemplateService {
+	return &templateService{repo: repo, transactor: transactor, options: options}
+}
+
+// CreateTemplate creates a new template and returns its ID.
+func (s *templateService) CreateTemplate(template *models.Template) (string, error) {
+	if err := validateTemplate(template); err != nil {
+		return "", err
+	}
+	return s.repo.Create(template)
+}
+
+// GetTemplate returns a template by its ID.
+func (s *templateService) GetTemplate(id string) (*models.Template, error) {
+	return s.repo.GetByID(id)
+}
+
+// UpdateTemplate replaces an existing template.
+func (s *templateService) UpdateTemplate(template *models.Template) error {
+	if template.ID == "" {
+		return errors.New("template ID is required")
+	}
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	return s.repo.Update(template)
+}
+
+// DeleteTemplate deletes a template; tasks created from it are kept.
+func (s *templateService) DeleteTemplate(id string) error {
+	if id == "" {
+		return errors.New("template ID is required")
+	}
+	return s.repo.Delete(id)
+}
+
+// ListTemplates returns templates ordered by name.
+func (s *templateService) ListTemplates() ([]*models.Template, error) {
+	return s.repo.List()
+}
+
+// Instantiate creates a task from a template and returns its ID.
+// The task date is the anchor date shifted by the template's date offset,
+// and the placeholders are replaced in the title, comment, tags and checklist.
+func (s *templateService) Instantiate(ctx context.Context, id string, options InstantiateOptions) (string, error) {
+	template, err := s.repo.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	anchor := time.Now().UTC()
+	if options.Anchor != "" {
+		anchor, err = time.Parse(dateFormat, options.Anchor)
+		if err != nil {
+			return "", errors.New("invalid anchor date format")
+		}
+	}
+	date := anchor.AddDate(0, 0, template.DateOffset)
+
+	values := make(map[string]string, len(options.Values)+2)
+	for name, value := range options.Values {
+		values[name] = value
+	}
+	values[PlaceholderDate] = date.Format(placeholderDateFormat)
+	values[PlaceholderAnchor] = anchor.Format(placeholderDateFormat)
+
+	// All placeholders are replaced before anything is created, so a missing value leaves no partial task behind
+	var missing []string
+	replace := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			value, ok := values[name]
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+
+	task := &models.Task{
+		Date:      date.Format(dateFormat),
+		Title:     replace(template.Title),
+		Comment:   replace(template.Comment),
+		Repeat:    template.Repeat,
+		Priority:  template.Priority,
+		ProjectID: template.ProjectID,
+	}
+	for _, tag := range template.Tags {
+		task.Tags = append(task.Tags, replace(tag))
+	}
+	checklist := make([]string, 0, len(template.Checklist))
+	for _, item := range template.Checklist {
+		checklist = append(checklist, replace(item))
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingPlaceholder, strings.Join(uniqueSorted(missing), ", "))
+	}
+
+	// The task is created in one transaction with its checklist, so that a failing item leaves nothing behind
+	var taskID string
+	err = s.transactor.Transact(func(tx *repository.Tx) error {
+		tasks := newTxTaskService(tx, s.options)
+		checklists := NewChecklistService(tx.Tasks, tx.Checklists)
+
+		var err error
+		if taskID, err = tasks.CreateTask(ctx, task); err != nil {
+			return err
+		}
+		for _, item := range checklist {
+			if _, err := checklists.AddItem(taskID, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return taskID, nil
+}
+
+// validateTemplate checks a template before it is saved.
+func validateTemplate(template *models.Template) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("template name is required")
+	}
+
+	template.Title = strings.TrimSpace(template.Title)
+	if template.Title == "" {
+		return errors.New("template title is required")
+	}
+
+	if template.Repeat != "" {
+		today := time.Now().UTC()
+		if _, err := timeutils.NextDate(today, today.Format(dateFormat), template.Repeat); err != nil {
+			return errors.New("invalid repeat rule")
+		}
+	}
+
+	tags, err := normalizeTags(template.Tags)
+	if err != nil {
+		return err
+	}
+	template.Tags = tags
+
+	items := make([]string, 0, len(template.Checklist))
+	for _, item := range template.Checklist {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return errors.New("checklist item text is required")
+		}
+		if len([]rune(item)) > maxChecklistItemLength {
+			return errors.New("checklist item text is too long")
+		}
+		items = append(items, item)
+	}
+	template.Checklist = items
+	return nil
+}
+
+// uniqueSorted returns the distinct strings in sorted order.
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/timeutils"
+)
+
+// todoTxtDate - date format of todo.txt
+const todoTxtDate = "2006-01-02"
+
+// todoTxtPriorities - todo.txt priorities by task priority; lower letters than "D" are imported as low
+var todoTxtPriorities = map[models.Priority]string{
+	models.PriorityUrgent: "A",
+	models.PriorityHigh:   "B",
+	models.PriorityMedium: "C",
+	models.PriorityLow:    "D",
+}
+
+// Extensions of todo.txt lines understood by the scheduler
+const (
+	todoTxtDue      = "due"    // Task date
+	todoTxtRec      = "rec"    // Recurrence, see timeutils.Rec
+	todoTxtRepeat   = "repeat" // Repetition rule without a "rec:" equivalent, with spaces replaced by "_"
+	todoTxtPriority = "pri"    // Priority of a completed task, which loses its "(A)" prefix
+	todoTxtID       = "tid"    // Task ID, which links lines to tasks when syncing
+)
+
+// ruleError is returned with an imported record whose repetition rule could not be converted.
+// The record is imported without the rule.
+type ruleError struct {
+	Rule   string
+	Reason string
+}
+
+// Error returns the reason the rule was dropped
+func (e *ruleError) Error() string {
+	return e.Reason
+}
+
+// todoTxtLine formats a task as a todo.txt line:
+// "x" for completed tasks, the priority, the creation date, the title, tags and the extensions.
+// Tags starting with "@" are contexts, other tags become "+projects".
+// Cancelled tasks have no todo.txt equivalent, so an empty line is returned for them.
+func todoTxtLine(task *ExportedTask) string {
+	if task.Status == models.StatusCancelled {
+		return ""
+	}
+
+	var parts []string
+	priority, _ := models.ParsePriority(task.Priority)
+	letter := todoTxtPriorities[priority]
+	created := ""
+	if createdAt, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		created = createdAt.Format(todoTxtDate)
+	}
+
+	if task.Status == models.StatusDone {
+		parts = append(parts, "x")
+		// A creation date must follow a completion date, which is approximated by the task date
+		if date, err := time.Parse(dateFormat, task.Date); err == nil && created != "" {
+			parts = append(parts, date.Format(todoTxtDate), created)
+		}
+	} else {
+		if letter != "" {
+			parts = append(parts, "("+letter+")")
+		}
+		if created != "" {
+			parts = append(parts, created)
+		}
+	}
+	parts = append(parts, strings.Join(strings.Fields(task.Title), " "))
+
+	for _, tag := range task.Tags {
+		if !strings.HasPrefix(tag, "@") && !strings.HasPrefix(tag, "+") {
+			tag = "+" + tag
+		}
+		parts = append(parts, tag)
+	}
+
+	if date, err := time.Parse(dateFormat, task.Date); err == nil {
+		parts = append(parts, todoTxtDue+":"+date.Format(todoTxtDate))
+	}
+	if task.Repeat != "" {
+		if rec, ok := timeutils.Rec(task.Date, task.Repeat); ok {
+			parts = append(parts, todoTxtRec+":"+rec)
+		} else {
+			parts = append(parts, todoTxtRepeat+":"+strings.ReplaceAll(task.Repeat, " ", "_"))
+		}
+	}
+	if task.Status == models.StatusDone && letter != "" {
+		parts = append(parts, todoTxtPriority+":"+letter)
+	}
+	if task.ID != "" {
+		parts = append(parts, todoTxtID+":"+task.ID)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseTodoTxtLine reads a task from a todo.txt line, reversing todoTxtLine.
+// "+project" and "@context" words become tags and are removed from the title, as are the known extensions;
+// other "key:value" words, such as links, stay in the title.
+// When the recurrence cannot be converted, the record is returned together with a *ruleError.
+func parseTodoTxtLine(line string) (*ExportedTask, error) {
+	words := strings.Fields(line)
+	record := &ExportedTask{Status: models.StatusTodo}
+
+	if len(words) > 0 && words[0] == "x" {
+		record.Status = models.StatusDone
+		words = words[1:]
+		// Completion and creation dates
+		for i := 0; i < 2 && len(words) > 0 && isTodoTxtDate(words[0]); i++ {
+			words = words[1:]
+		}
+	}
+	// Lines completed by prefixing "x" keep their priority and creation date
+	if len(words) > 0 && len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' {
+		record.Priority = letterPriority(words[0][1])
+		words = words[1:]
+		if len(words) > 0 && isTodoTxtDate(words[0]) {
+			words = words[1:]
+		}
+	} else if record.Status != models.StatusDone && len(words) > 0 && isTodoTxtDate(words[0]) {
+		words = words[1:]
+	}
+
+	var title []string
+	var rec string
+	for _, word := range words {
+		key, value, _ := strings.Cut(word, ":")
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			record.Tags = append(record.Tags, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			record.Tags = append(record.Tags, word)
+		case value == "":
+			title = append(title, word)
+		case key == todoTxtDue:
+			date, err := time.Parse(todoTxtDate, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date %q", value)
+			}
+			record.Date = date.Format(dateFormat)
+		case key == todoTxtRec:
+			rec = value
+		case key == todoTxtRepeat:
+			record.Repeat = strings.ReplaceAll(value, "_", " ")
+		case key == todoTxtPriority && len(value) == 1:
+			record.Priority = letterPriority(value[0])
+		case key == todoTxtID:
+			record.ID = value
+		default:
+			title = append(title, word)
+		}
+	}
+	record.Title = strings.Join(title, " ")
+
+	if rec != "" && record.Repeat == "" {
+		repeat, err := timeutils.FromRec(record.Date, rec)
+		if err != nil {
+			return record, &ruleError{Rule: todoTxtRec + ":" + rec, Reason: err.Error()}
+		}
+		record.Repeat = repeat
+	}
+	return record, nil
+}
+
+// letterPriority converts a todo.txt priority letter into a priority name
+func letterPriority(letter byte) string {
+	switch {
+	case letter == 'A':
+		return models.PriorityUrgent.String()
+	case letter == 'B':
+		return models.PriorityHigh.String()
+	case letter == 'C':
+		return models.PriorityMedium.String()
+	case letter >= 'D' && letter <= 'Z':
+		return models.PriorityLow.String()
+	}
+	return ""
+}
+
+// isTodoTxtDate reports whether a word is a todo.txt date
+func isTodoTxtDate(word string) bool {
+	_, err := time.Parse(todoTxtDate, word)
+	return err == nil
+}
+
+// eachTodoTxtRecord reads one task per line of a todo.txt file; empty lines are skipped
+func eachTodoTxtRecord(r io.Reader, fn recordFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		record, err := parseTodoTxtLine(line)
+		if err := fn(row, record, err); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
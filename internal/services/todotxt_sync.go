@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/auth"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// todoTxtActor - actor recorded in the audit trail for changes made in the synced todo.txt file
+const todoTxtActor = "todo.txt"
+
+// TodoTxtSync provides an interface for keeping a todo.txt file and the tasks in step.
+type TodoTxtSync interface {
+	Sync(ctx context.Context) error
+}
+
+// todoTxtSync implements the TodoTxtSync interface.
+type todoTxtSync struct {
+	path  string                    // Path of the todo.txt file.
+	tasks TaskService               // Service changes made in the file are applied through.
+	repo  repository.TaskRepository // Repository the tasks written to the file are read from.
+
+	mu      sync.Mutex        // Serializes syncs.
+	written []byte            // Contents of the file as last written.
+	lines   map[string]string // Lines as last written by task ID; nil before the first sync.
+}
+
+// NewTodoTxtSync creates a sync of the tasks with the todo.txt file at the given path.
+func NewTodoTxtSync(path string, tasks TaskService, repo repository.TaskRepository) TodoTxtSync {
+	return &todoTxtSync{
+		path:  path,
+		tasks: tasks,
+		repo:  repo,
+	}
+}
+
+// Sync applies the changes made in the file since the last sync to the tasks and rewrites the file from the tasks.
+// Lines are linked to tasks by their "tid:" extension. A line that differs from the one last written updates its task,
+// a line without an ID creates a task and a line that was removed moves its task to the trash.
+// When a task changed both in the file and in the scheduler, the file wins.
+// A file without any task lines, such as one read while an editor is saving it, deletes nothing.
+// Before the first sync nothing is known about earlier contents, so lines that differ from their task update it
+// and removed lines are left alone.
+func (s *todoTxtSync) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	ids, tasks, err := s.current()
+	if err != nil {
+		return err
+	}
+	if data != nil && (s.lines == nil || !bytes.Equal(data, s.written)) {
+		s.apply(ctx, data, tasks)
+		if ids, tasks, err = s.current(); err != nil {
+			return err
+		}
+	}
+	return s.write(data, ids, tasks)
+}
+
+// current loads the tasks that appear in the file, in file order
+func (s *todoTxtSync) current() ([]string, map[string]*models.Task, error) {
+	var ids []string
+	tasks := make(map[string]*models.Task)
+	err := s.repo.Each(func(task *models.Task) error {
+		if task.Status != models.StatusCancelled {
+			ids = append(ids, task.ID)
+			tasks[task.ID] = task
+		}
+		return nil
+	})
+	return ids, tasks, err
+}
+
+// apply applies the lines of the file to the tasks; lines that cannot be applied are logged and skipped
+func (s *todoTxtSync) apply(ctx context.Context, data []byte, tasks map[string]*models.Task) {
+	seen := make(map[string]bool)
+	parsed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		record, err := parseTodoTxtLine(line)
+		var unconverted *ruleError
+		if err != nil && !(errors.As(err, &unconverted) && record != nil) {
+			log.Printf("Skipping line %d of %s: %v", row, s.path, err)
+			continue
+		}
+		if unconverted != nil {
+			log.Printf("Dropping the recurrence of line %d of %s: %v", row, s.path, err)
+		}
+		parsed++
+
+		if record.ID != "" {
+			seen[record.ID] = true
+			if line == s.lines[record.ID] {
+				continue
+			}
+		}
+
+		task, ok := tasks[record.ID]
+		switch {
+		case ok:
+			err = s.update(ctx, task, record, unconverted != nil)
+		case record.ID != "" && s.lines[record.ID] != "":
+			// The task was deleted or cancelled in the scheduler since the last sync
+			continue
+		default:
+			err = s.create(ctx, record)
+		}
+		if err != nil {
+			log.Printf("Error applying line %d of %s: %v", row, s.path, err)
+		}
+	}
+
+	// Removing every task at once is far more likely to be a truncated read than an edit
+	if parsed == 0 && len(s.lines) > 0 {
+		log.Printf("Not deleting any tasks: %s has no task lines", s.path)
+		return
+	}
+	for id := range s.lines {
+		if task, ok := tasks[id]; ok && !seen[id] {
+			if err := s.tasks.DeleteTask(ctx, id, task.Version); err != nil {
+				log.Printf("Error deleting task %s removed from %s: %v", id, s.path, err)
+			}
+		}
+	}
+}
+
+// create creates a task from a line without a known task
+func (s *todoTxtSync) create(ctx context.Context, record *ExportedTask) error {
+	task, err := recordTask(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.tasks.CreateTask(ctx, task)
+	return err
+}
+
+// update changes a task to match its line; the repetition rule is kept when the line's could not be converted
+func (s *todoTxtSync) update(ctx context.Context, task *models.Task, record *ExportedTask, keepRepeat bool) error {
+	changed, err := recordTask(record)
+	if err != nil {
+		return err
+	}
+
+	updated := *task
+	updated.Title = changed.Title
+	if changed.Date != "" {
+		updated.Date = changed.Date
+	}
+	if !keepRepeat {
+		updated.Repeat = changed.Repeat
+	}
+	updated.Priority = changed.Priority
+	updated.Tags = changed.Tags
+	if updated.Tags == nil {
+		updated.Tags = []string{}
+	}
+
+	if todoTxtLine(exportedTask(task)) != todoTxtLine(exportedTask(&updated)) {
+		if err := s.tasks.UpdateTask(ctx, &updated); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case changed.Status == models.StatusDone && task.Status != models.StatusDone:
+		_, err = s.tasks.MarkTaskDone(ctx, task.ID, DoneOptions{})
+	case changed.Status != models.StatusDone && task.Status == models.StatusDone:
+		err = s.tasks.SetStatus(ctx, task.ID, models.StatusTodo, 0)
+	}
+	return err
+}
+
+// write rewrites the file from the tasks when its contents differ
+func (s *todoTxtSync) write(data []byte, ids []string, tasks map[string]*models.Task) error {
+	var content bytes.Buffer
+	lines := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if line := todoTxtLine(exportedTask(tasks[id])); line != "" {
+			content.WriteString(line + "\n")
+			lines[id] = line
+		}
+	}
+
+	if !bytes.Equal(content.Bytes(), data) {
+		// The file may have been edited since it was read; the edit is picked up by the next sync instead
+		if current, err := os.ReadFile(s.path); err == nil && !bytes.Equal(current, data) {
+			return nil
+		}
+		if err := writeFileAtomic(s.path, content.Bytes()); err != nil {
+			return err
+		}
+	}
+	s.written, s.lines = content.Bytes(), lines
+	return nil
+}
+
+// writeFileAtomic replaces a file through a temporary file in the same directory,
+// so that readers never see it half-written
+func writeFileAtomic(path string, data []byte) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// StartTodoTxtSync syncs the tasks with a todo.txt file at the given interval.
+// The sync runs in the background until the stop channel is closed.
+func StartTodoTxtSync(todoTxt TodoTxtSync, interval time.Duration, stop <-chan struct{}) {
+	ctx := auth.WithActor(context.Background(), auth.Actor{Name: todoTxtActor})
+	run := func() {
+		if err := todoTxt.Sync(ctx); err != nil {
+			log.Println("Error syncing the todo.txt file:", err)
+		}
+	}
+
+	runPeriodically(interval, stop, run)
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// trashCleanupInterval - how often expired tasks are purged from the trash
+const trashCleanupInterval = time.Hour
+
+// StartTrashCleanup periodically purges tasks that have been in the trash longer than the retention period.
+// The cleanup runs in the background until the stop channel is closed.
+func StartTrashCleanup(service TaskService, retention time.Duration, stop <-chan struct{}) {
+	purge := func() {
+		purged, err := service.PurgeTrash(retention)
+		if err != nil {
+			log.Println("Error purging the trash:", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("Purged %d tasks from the trash", purged)
+		}
+	}
+
+	runPeriodically(trashCleanupInterval, stop, purge)
+}
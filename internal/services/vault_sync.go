@@ -0,0 +1,413 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VladimirVereshchagin/scheduler/internal/auth"
+	"github.com/VladimirVereshchagin/scheduler/internal/models"
+	"github.com/VladimirVereshchagin/scheduler/internal/repository"
+)
+
+// SourceMarkdown is the source name under which the IDs of tasks in Markdown files are recorded
+const SourceMarkdown = "markdown"
+
+// vaultActor - actor recorded in the audit trail for changes made in the Markdown files
+const vaultActor = "vault"
+
+// markdownIDAlphabet - characters of generated Markdown IDs, which are six characters long like those of the Tasks plugin
+const markdownIDAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// VaultSync provides an interface for keeping a directory of Markdown files and the tasks in step.
+type VaultSync interface {
+	Sync(ctx context.Context) error
+}
+
+// vaultSync implements the VaultSync interface.
+type vaultSync struct {
+	dir         string                          // Directory of the Markdown files.
+	inbox       string                          // File new tasks are added to, relative to the directory.
+	tasks       TaskService                     // Service changes made in the files are applied through.
+	repo        repository.TaskRepository       // Repository the tasks written to the files are read from.
+	externalIDs repository.ExternalIDRepository // Links between Markdown IDs and tasks.
+
+	mu    sync.Mutex        // Serializes syncs.
+	lines map[string]string // Items as last synced by Markdown ID; nil before the first sync.
+}
+
+// vaultFile is a Markdown file read during a sync
+type vaultFile struct {
+	path    string
+	data    []byte
+	lines   []string
+	newline string
+	changed bool
+	links   []*vaultLink // IDs added to the file, linked once it is written
+}
+
+// vaultLink is a Markdown ID added to a file during a sync
+type vaultLink struct {
+	id     string
+	taskID string        // Task the ID belongs to; empty for a new item, whose task is created once the ID is in the file
+	item   *markdownTask // New item the task is created from
+}
+
+// NewVaultSync creates a sync of the tasks with the Markdown files in a directory.
+func NewVaultSync(dir, inbox string, tasks TaskService, repo repository.TaskRepository, externalIDs repository.ExternalIDRepository) VaultSync {
+	return &vaultSync{
+		dir:         dir,
+		inbox:       inbox,
+		tasks:       tasks,
+		repo:        repo,
+		externalIDs: externalIDs,
+	}
+}
+
+// Sync applies the checkbox items of the Markdown files to the tasks and writes the tasks back to the files.
+// Items are linked to tasks by a "🆔" ID, which is added to new items, so moving items between files
+// or renaming files does not create duplicates. An open item without an ID creates a task, an item that changed
+// since the last sync updates its task and an item whose ID disappeared from all files moves its task to the trash.
+// Items of tasks changed in the scheduler are rewritten, and open tasks that are not in any file yet
+// are appended to the inbox file. When a task changed both in a file and in the scheduler, the file wins.
+// New IDs are linked to their tasks only once their file is written, and the tasks of new items are created then,
+// so that a file edited during the sync does not leave a task linked to an ID that is in no file.
+func (s *vaultSync) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	tasks := make(map[string]*models.Task)
+	err = s.repo.Each(func(task *models.Task) error {
+		tasks[task.ID] = task
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	links, err := s.externalIDs.ExternalIDs(SourceMarkdown)
+	if err != nil {
+		return err
+	}
+	taskIDs := make(map[string]string, len(links))
+	for taskID, id := range links {
+		taskIDs[id] = taskID
+	}
+
+	lines := make(map[string]string)
+	for _, file := range files {
+		inFence := false
+		for i, line := range file.lines {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				inFence = !inFence
+			}
+			if inFence {
+				continue
+			}
+			item, err := parseMarkdownTask(line)
+			if err != nil {
+				log.Printf("Skipping line %d of %s: %v", i+1, file.path, err)
+				continue
+			}
+			if item == nil || (item.ID == "" && (item.Status == models.StatusDone || item.Status == models.StatusCancelled)) {
+				// Items that were closed before they were synced are left alone
+				continue
+			}
+
+			if item.ID == "" {
+				if item.ID, err = s.newID(taskIDs); err != nil {
+					return err
+				}
+				taskIDs[item.ID] = ""
+				file.links = append(file.links, &vaultLink{id: item.ID, item: item})
+			} else if err := s.syncItem(ctx, item, tasks, taskIDs); err != nil {
+				log.Printf("Error syncing line %d of %s: %v", i+1, file.path, err)
+			}
+			lines[item.ID] = item.String()
+			if lines[item.ID] != line {
+				file.lines[i] = lines[item.ID]
+				file.changed = true
+			}
+		}
+	}
+
+	for id, taskID := range taskIDs {
+		_, seen := lines[id]
+		if task, ok := tasks[taskID]; ok && !seen && s.lines != nil && s.lines[id] != "" {
+			if err := s.tasks.DeleteTask(ctx, taskID, task.Version); err != nil {
+				log.Printf("Error deleting task %s removed from the Markdown files: %v", taskID, err)
+			}
+			delete(tasks, taskID)
+		}
+	}
+
+	if files, err = s.addNewTasks(files, taskIDs, lines); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.changed {
+			// A file edited since it was read is picked up by the next sync instead, without the IDs added to it
+			if current, err := os.ReadFile(file.path); err == nil && !bytes.Equal(current, file.data) {
+				for _, link := range file.links {
+					delete(lines, link.id)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(file.path), 0o755); err != nil {
+				return err
+			}
+			if err := writeFileAtomic(file.path, []byte(strings.Join(file.lines, file.newline))); err != nil {
+				return err
+			}
+		}
+		for _, link := range file.links {
+			if err := s.link(ctx, link); err != nil {
+				log.Printf("Error linking item %s of %s: %v", link.id, file.path, err)
+			}
+		}
+	}
+	s.lines = lines
+	return nil
+}
+
+// link records the task of an ID that was written to a file, creating the task of a new item.
+// An ID that fails to be linked stays in the file and its task is created by the next sync.
+func (s *vaultSync) link(ctx context.Context, link *vaultLink) error {
+	if link.taskID == "" {
+		created, err := link.item.task()
+		var unconverted *ruleError
+		if errors.As(err, &unconverted) {
+			log.Printf("Dropping the recurrence of %q: %v", link.item.Title, err)
+		} else if err != nil {
+			return err
+		}
+		if link.taskID, err = s.tasks.CreateTask(ctx, created); err != nil {
+			return err
+		}
+	}
+	return s.externalIDs.Link(SourceMarkdown, link.id, link.taskID)
+}
+
+// syncItem applies an item with an ID to its task, creating the task if the ID is not linked to one,
+// and updates the item from the task
+func (s *vaultSync) syncItem(ctx context.Context, item *markdownTask, tasks map[string]*models.Task, taskIDs map[string]string) error {
+	taskID, linked := taskIDs[item.ID]
+	task, exists := tasks[taskID]
+
+	switch {
+	case !linked:
+		if item.Status == models.StatusDone || item.Status == models.StatusCancelled {
+			return nil
+		}
+		created, err := item.task()
+		var unconverted *ruleError
+		if errors.As(err, &unconverted) {
+			log.Printf("Dropping the recurrence of %q: %v", item.Title, err)
+		} else if err != nil {
+			return err
+		}
+		if taskID, err = s.tasks.CreateTask(ctx, created); err != nil {
+			return err
+		}
+		if err := s.externalIDs.Link(SourceMarkdown, item.ID, taskID); err != nil {
+			return err
+		}
+		taskIDs[item.ID] = taskID
+
+	case !exists:
+		// The task was deleted in the scheduler; completed items may belong to tasks moved to the trash on completion
+		if item.Status == models.StatusTodo || item.Status == models.StatusInProgress {
+			item.Status = models.StatusCancelled
+			item.CancelledDate = time.Now().Format(todoTxtDate)
+		}
+		return nil
+
+	default:
+		current := item.String()
+		synced := *item
+		synced.update(task)
+		changed := current != synced.String()
+		if s.lines != nil {
+			changed = current != s.lines[item.ID]
+		}
+		if !changed {
+			item.update(task)
+			return nil
+		}
+		if err := s.apply(ctx, task, item); err != nil {
+			return err
+		}
+	}
+
+	task, err := s.tasks.GetTaskByID(taskID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Tasks completed with TODO_DONE_MODE=delete move to the trash
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tasks[taskID] = task
+	item.update(task)
+	return nil
+}
+
+// apply changes a task to match its item.
+// A repetition rule without a recurrence equivalent is kept unless the item has a recurrence.
+func (s *vaultSync) apply(ctx context.Context, task *models.Task, item *markdownTask) error {
+	changed, err := item.task()
+	var unconverted *ruleError
+	if errors.As(err, &unconverted) {
+		log.Printf("Dropping the recurrence of %q: %v", item.Title, err)
+	} else if err != nil {
+		return err
+	}
+
+	updated := *task
+	updated.Title = changed.Title
+	if changed.Date != "" {
+		updated.Date = changed.Date
+	}
+	if item.Recurrence != "" && unconverted == nil {
+		updated.Repeat = changed.Repeat
+	} else if item.Recurrence == "" {
+		synced := *item
+		synced.update(task)
+		if synced.Recurrence != "" {
+			updated.Repeat = ""
+		}
+	}
+	updated.Priority = changed.Priority
+	updated.Tags = changed.Tags
+
+	if err := s.tasks.UpdateTask(ctx, &updated); err != nil {
+		return err
+	}
+
+	switch {
+	case item.Status == task.Status:
+	case item.Status == models.StatusDone:
+		_, err = s.tasks.MarkTaskDone(ctx, task.ID, DoneOptions{})
+	default:
+		err = s.tasks.SetStatus(ctx, task.ID, item.Status, 0)
+	}
+	return err
+}
+
+// addNewTasks appends the open tasks that are not linked to an item to the inbox file,
+// which is created when it does not exist
+func (s *vaultSync) addNewTasks(files []*vaultFile, taskIDs map[string]string, lines map[string]string) ([]*vaultFile, error) {
+	var inbox *vaultFile
+	path := filepath.Join(s.dir, s.inbox)
+	for _, file := range files {
+		if file.path == path {
+			inbox = file
+		}
+	}
+
+	linked := make(map[string]bool, len(taskIDs))
+	for _, taskID := range taskIDs {
+		linked[taskID] = true
+	}
+
+	err := s.repo.Each(func(task *models.Task) error {
+		if linked[task.ID] || (task.Status != models.StatusTodo && task.Status != models.StatusInProgress) {
+			return nil
+		}
+		id, err := s.newID(taskIDs)
+		if err != nil {
+			return err
+		}
+		taskIDs[id] = task.ID
+
+		item := &markdownTask{Prefix: "- ", ID: id}
+		item.update(task)
+		if inbox == nil {
+			inbox = &vaultFile{path: path, lines: []string{""}, newline: "\n"}
+			files = append(files, inbox)
+		}
+		// Items go before the trailing newline of the file
+		if inbox.lines[len(inbox.lines)-1] != "" {
+			inbox.lines = append(inbox.lines, "")
+		}
+		last := len(inbox.lines) - 1
+		inbox.lines = append(inbox.lines[:last], item.String(), "")
+		inbox.changed = true
+		inbox.links = append(inbox.links, &vaultLink{id: id, taskID: task.ID})
+		lines[id] = item.String()
+		return nil
+	})
+	return files, err
+}
+
+// newID generates a Markdown ID that is not in use
+func (s *vaultSync) newID(taskIDs map[string]string) (string, error) {
+	for {
+		random := make([]byte, 6)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		for i, b := range random {
+			random[i] = markdownIDAlphabet[int(b)%len(markdownIDAlphabet)]
+		}
+		if _, ok := taskIDs[string(random)]; !ok {
+			return string(random), nil
+		}
+	}
+}
+
+// read reads the Markdown files of the directory; hidden directories such as ".obsidian" and ".trash" are skipped
+func (s *vaultSync) read() ([]*vaultFile, error) {
+	var files []*vaultFile
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != s.dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file := &vaultFile{path: path, data: data, newline: "\n"}
+		if bytes.Contains(data, []byte("\r\n")) {
+			file.newline = "\r\n"
+		}
+		file.lines = strings.Split(string(data), file.newline)
+		files = append(files, file)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, os.MkdirAll(s.dir, 0o755)
+	}
+	return files, err
+}
+
+// StartVaultSync syncs the tasks with a directory of Markdown files at the given interval.
+// The sync runs in the background until the stop channel is closed.
+func StartVaultSync(vault VaultSync, interval time.Duration, stop <-chan struct{}) {
+	ctx := auth.WithActor(context.Background(), auth.Actor{Name: vaultActor})
+	runPeriodically(interval, stop, func() {
+		if err := vault.Sync(ctx); err != nil {
+			log.Println("Error syncing the Markdown files:", err)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package timeutils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// businessDays - weekly rule of the todo.txt "b" unit, Monday to Friday
+const businessDays = "w 1,2,3,4,5"
+
+// Rec converts a repetition rule into the value of a todo.txt "rec:" extension for a task on the given date.
+// The "+" prefix is always used, since our rules count from the task date rather than from the completion date.
+// The second value is false when the rule has no equivalent, such as "w 1,3" or "m -1".
+func Rec(dateStr, repeat string) (string, bool) {
+	date, err := time.Parse("20060102", dateStr)
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case repeat == "y":
+		return "+1y", true
+
+	case repeat == businessDays:
+		return "+1b", true
+
+	case strings.HasPrefix(repeat, "d "):
+		days, err := strconv.Atoi(repeat[2:])
+		if err != nil || days < 1 || days > 400 {
+			return "", false
+		}
+		if days%7 == 0 {
+			return fmt.Sprintf("+%dw", days/7), true
+		}
+		return fmt.Sprintf("+%dd", days), true
+
+	case strings.HasPrefix(repeat, "m "):
+		days, months, err := parseMonthRule(repeat[2:])
+		if err != nil || len(days) != 1 || days[0] != date.Day() {
+			return "", false
+		}
+		if len(months) == 0 {
+			return "+1m", true
+		}
+		// Months at a fixed interval that divides the year, one of them being the month of the task
+		sort.Ints(months)
+		interval := 12 / len(months)
+		if 12%len(months) != 0 || !isValidDayMonth(date, days, months) {
+			return "", false
+		}
+		for i := 1; i < len(months); i++ {
+			if months[i]-months[i-1] != interval {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("+%dm", interval), true
+	}
+	return "", false
+}
+
+// FromRec converts the value of a todo.txt "rec:" extension, such as "1w" or "+3m", into a repetition rule
+// for a task on the given date. Monthly rules repeat on the day of the month of the date.
+// Intervals without an equivalent, such as every two years, are reported as errors.
+func FromRec(dateStr, rec string) (string, error) {
+	value := strings.TrimPrefix(strings.ToLower(rec), "+")
+	if len(value) < 2 {
+		return "", fmt.Errorf("invalid recurrence %q", rec)
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("invalid recurrence %q", rec)
+	}
+
+	unit := value[len(value)-1]
+	switch {
+	case unit == 'd' && n <= 400:
+		return fmt.Sprintf("d %d", n), nil
+	case unit == 'w' && n*7 <= 400:
+		return fmt.Sprintf("d %d", n*7), nil
+	case unit == 'b' && n == 1:
+		return businessDays, nil
+	case unit == 'y' && n == 1:
+		return "y", nil
+	case unit == 'm' && n <= 12 && 12%n == 0:
+		date, err := time.Parse("20060102", dateStr)
+		if err != nil {
+			return "", fmt.Errorf("monthly recurrence %q needs a due date", rec)
+		}
+		if n == 1 {
+			return fmt.Sprintf("m %d", date.Day()), nil
+		}
+		months := make([]int, 0, 12/n)
+		for month := (int(date.Month())-1)%n + 1; month <= 12; month += n {
+			months = append(months, month)
+		}
+		return fmt.Sprintf("m %d %s", date.Day(), joinInts(months)), nil
+	case strings.ContainsRune("dwbmy", rune(unit)):
+		return "", fmt.Errorf("recurrence %q is not supported", rec)
+	}
+	return "", fmt.Errorf("invalid recurrence %q", rec)
+}
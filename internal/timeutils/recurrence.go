@@ -0,0 +1,218 @@
+package timeutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekdayNames - English weekday names, Monday is 1
+var weekdayNames = map[int]string{1: "Monday", 2: "Tuesday", 3: "Wednesday", 4: "Thursday", 5: "Friday", 6: "Saturday", 7: "Sunday"}
+
+// Recurrence converts a repetition rule into the recurrence text of the Obsidian Tasks plugin,
+// such as "every 2 weeks" or "every month on the 1st, 15th", for a task on the given date.
+// The second value is false when the rule has no equivalent.
+func Recurrence(dateStr, repeat string) (string, bool) {
+	date, err := time.Parse("20060102", dateStr)
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case repeat == "y":
+		return "every year", true
+
+	case repeat == businessDays:
+		return "every weekday", true
+
+	case strings.HasPrefix(repeat, "d "):
+		days, err := strconv.Atoi(repeat[2:])
+		switch {
+		case err != nil || days < 1 || days > 400:
+			return "", false
+		case days == 1:
+			return "every day", true
+		case days == 7:
+			return "every week", true
+		case days%7 == 0:
+			return fmt.Sprintf("every %d weeks", days/7), true
+		}
+		return fmt.Sprintf("every %d days", days), true
+
+	case strings.HasPrefix(repeat, "w "):
+		daysOfWeek := parseDaysOfWeek(repeat[2:])
+		if daysOfWeek == nil {
+			return "", false
+		}
+		names := make([]string, 0, len(daysOfWeek))
+		for _, day := range sortedKeys(daysOfWeek) {
+			names = append(names, weekdayNames[day])
+		}
+		return "every week on " + strings.Join(names, ", "), true
+
+	case strings.HasPrefix(repeat, "m "):
+		days, months, err := parseMonthRule(repeat[2:])
+		if err != nil {
+			return "", false
+		}
+		if len(months) == 0 {
+			ordinals := make([]string, 0, len(days))
+			for _, day := range days {
+				ordinals = append(ordinals, dayOrdinal(day))
+			}
+			return "every month on the " + strings.Join(ordinals, ", "), true
+		}
+		// Months at a fixed interval repeat on the day of the task, as in todo.txt
+		rec, ok := Rec(dateStr, repeat)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("every %s months on the %s", strings.Trim(rec, "+m"), dayOrdinal(date.Day())), true
+	}
+	return "", false
+}
+
+// FromRecurrence converts the recurrence text of the Obsidian Tasks plugin into a repetition rule
+// for a task on the given date. "when done" is ignored, since our rules always count from the task date.
+// Recurrences without an equivalent, such as "every 2 years", are reported as errors.
+func FromRecurrence(dateStr, text string) (string, error) {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(text, ",", " , ")))
+	if len(words) >= 2 && words[len(words)-2] == "when" && words[len(words)-1] == "done" {
+		words = words[:len(words)-2]
+	}
+	unsupported := fmt.Errorf("recurrence %q is not supported", text)
+	if len(words) < 2 || words[0] != "every" {
+		return "", fmt.Errorf("invalid recurrence %q", text)
+	}
+
+	interval := 1
+	if n, err := strconv.Atoi(words[1]); err == nil {
+		if n < 1 || len(words) < 3 {
+			return "", fmt.Errorf("invalid recurrence %q", text)
+		}
+		interval = n
+		words = words[1:]
+	}
+	unit := strings.TrimSuffix(words[1], "s")
+	var on []string
+	if len(words) > 2 {
+		if words[2] != "on" {
+			return "", unsupported
+		}
+		for _, word := range words[3:] {
+			switch {
+			case word == "," || word == "the" || word == "and":
+			case word == "last" && len(on) > 0 && on[len(on)-1] == "2nd":
+				on[len(on)-1] = "2nd last"
+			default:
+				on = append(on, word)
+			}
+		}
+		if len(on) == 0 {
+			return "", fmt.Errorf("invalid recurrence %q", text)
+		}
+	}
+
+	switch {
+	case unit == "day" && on == nil:
+		return FromRec(dateStr, fmt.Sprintf("%dd", interval))
+
+	case unit == "weekday" && on == nil && interval == 1:
+		return businessDays, nil
+
+	case unit == "week" && on == nil:
+		return FromRec(dateStr, fmt.Sprintf("%dw", interval))
+
+	case weekdayNumber(unit) != 0 && on == nil && interval == 1:
+		return fmt.Sprintf("w %d", weekdayNumber(unit)), nil
+
+	case unit == "week" && interval == 1:
+		days := make([]string, 0, len(on))
+		for _, name := range on {
+			day := weekdayNumber(name)
+			if day == 0 {
+				return "", fmt.Errorf("invalid weekday %q", name)
+			}
+			days = append(days, strconv.Itoa(day))
+		}
+		return "w " + strings.Join(days, ","), nil
+
+	case unit == "month" && on == nil:
+		return FromRec(dateStr, fmt.Sprintf("%dm", interval))
+
+	case unit == "month":
+		days := make([]int, 0, len(on))
+		for _, ordinal := range on {
+			day, err := parseDayOrdinal(ordinal)
+			if err != nil {
+				return "", err
+			}
+			days = append(days, day)
+		}
+		if interval == 1 {
+			return "m " + joinInts(days), nil
+		}
+		// Longer intervals are supported on the day of the task only
+		date, err := time.Parse("20060102", dateStr)
+		if err != nil || len(days) != 1 || days[0] != date.Day() {
+			return "", unsupported
+		}
+		return FromRec(dateStr, fmt.Sprintf("%dm", interval))
+
+	case unit == "year" && on == nil && interval == 1:
+		return "y", nil
+	}
+	return "", unsupported
+}
+
+// dayOrdinal formats a day of the month as an English ordinal, "last" and "2nd last" for -1 and -2
+func dayOrdinal(day int) string {
+	switch day {
+	case -1:
+		return "last"
+	case -2:
+		return "2nd last"
+	}
+	suffix := "th"
+	if day < 11 || day > 13 {
+		switch day % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(day) + suffix
+}
+
+// parseDayOrdinal parses a day of the month written as an ordinal, such as "1st" or "last"
+func parseDayOrdinal(ordinal string) (int, error) {
+	switch ordinal {
+	case "last":
+		return -1, nil
+	case "2nd last":
+		return -2, nil
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if number, ok := strings.CutSuffix(ordinal, suffix); ok {
+			day, err := strconv.Atoi(number)
+			if err == nil && day >= 1 && day <= 31 && dayOrdinal(day) == ordinal {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid day of month %q", ordinal)
+}
+
+// weekdayNumber returns the number of a weekday named in English, or 0
+func weekdayNumber(name string) int {
+	for day, weekday := range weekdayNames {
+		if strings.EqualFold(weekday, name) {
+			return day
+		}
+	}
+	return 0
+}
@@ -0,0 +1,253 @@
+package timeutils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weekdayCodes - iCalendar weekday codes, Monday is 1
+var weekdayCodes = map[int]string{1: "MO", 2: "TU", 3: "WE", 4: "TH", 5: "FR", 6: "SA", 7: "SU"}
+
+// RRule converts a repetition rule into an iCalendar RRULE value for a series starting on the given date.
+// The second value is false when the rule has no exact equivalent, for example when the start date
+// itself does not match the rule, since iCalendar always counts the start date as an occurrence.
+func RRule(dateStr, repeat string) (string, bool) {
+	date, err := time.Parse("20060102", dateStr)
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case repeat == "y":
+		// Our yearly rule moves February 29 to March 1, iCalendar skips the years without it
+		if date.Month() == time.February && date.Day() == 29 {
+			return "", false
+		}
+		return "FREQ=YEARLY", true
+
+	case strings.HasPrefix(repeat, "d "):
+		days, err := strconv.Atoi(repeat[2:])
+		if err != nil || days < 1 || days > 400 {
+			return "", false
+		}
+		if days == 1 {
+			return "FREQ=DAILY", true
+		}
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", days), true
+
+	case strings.HasPrefix(repeat, "w "):
+		daysOfWeek := parseDaysOfWeek(repeat[2:])
+		weekday := int(date.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		if daysOfWeek == nil || !daysOfWeek[weekday] {
+			return "", false
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(sortedCodes(daysOfWeek), ","), true
+
+	case strings.HasPrefix(repeat, "m "):
+		days, months, err := parseMonthRule(repeat[2:])
+		if err != nil || !isValidDayMonth(date, days, months) {
+			return "", false
+		}
+		if len(months) == 0 {
+			return "FREQ=MONTHLY;BYMONTHDAY=" + joinInts(days), true
+		}
+		return "FREQ=YEARLY;BYMONTH=" + joinInts(months) + ";BYMONTHDAY=" + joinInts(days), true
+	}
+	return "", false
+}
+
+// FromRRule converts an iCalendar RRULE value into a repetition rule for a series starting on the given date.
+// Rules with no equivalent, such as "every second Tuesday of the month", are reported as errors.
+// COUNT and UNTIL are not supported either, since our rules never end.
+func FromRRule(dateStr, rrule string) (string, error) {
+	date, err := time.Parse("20060102", dateStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid date format: %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(rrule, "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid rule part %q", part)
+		}
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		interval, err = strconv.Atoi(value)
+		if err != nil || interval < 1 {
+			return "", fmt.Errorf("invalid INTERVAL %q", value)
+		}
+	}
+	freq := parts["FREQ"]
+	delete(parts, "FREQ")
+	delete(parts, "INTERVAL")
+	delete(parts, "WKST")
+
+	for _, key := range []string{"COUNT", "UNTIL"} {
+		if _, ok := parts[key]; ok {
+			return "", fmt.Errorf("%s is not supported, repeating tasks never end", key)
+		}
+	}
+
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	switch freq {
+	case "DAILY":
+		if len(parts) > 0 {
+			return "", fmt.Errorf("FREQ=DAILY with %s is not supported", partNames(parts))
+		}
+		if interval > 400 {
+			return "", fmt.Errorf("INTERVAL %d exceeds 400 days", interval)
+		}
+		return fmt.Sprintf("d %d", interval), nil
+
+	case "WEEKLY":
+		byDay, hasByDay := parts["BYDAY"]
+		delete(parts, "BYDAY")
+		if len(parts) > 0 {
+			return "", fmt.Errorf("FREQ=WEEKLY with %s is not supported", partNames(parts))
+		}
+		days := map[int]bool{weekday: true}
+		if hasByDay {
+			if days, err = parseByDay(byDay); err != nil {
+				return "", err
+			}
+		}
+		if interval == 1 {
+			return "w " + joinInts(sortedKeys(days)), nil
+		}
+		// Every few weeks on a single day is the same as every 7·N days
+		if len(days) == 1 && days[weekday] && interval*7 <= 400 {
+			return fmt.Sprintf("d %d", interval*7), nil
+		}
+		return "", fmt.Errorf("INTERVAL=%d is only supported for a single weekday", interval)
+
+	case "MONTHLY", "YEARLY":
+		if interval != 1 {
+			return "", fmt.Errorf("FREQ=%s with INTERVAL=%d is not supported", freq, interval)
+		}
+		byMonthDay, hasMonthDay := parts["BYMONTHDAY"]
+		byMonth, hasMonth := parts["BYMONTH"]
+		delete(parts, "BYMONTHDAY")
+		delete(parts, "BYMONTH")
+		if len(parts) > 0 {
+			return "", fmt.Errorf("FREQ=%s with %s is not supported", freq, partNames(parts))
+		}
+
+		if freq == "YEARLY" && !hasMonthDay && !hasMonth {
+			return "y", nil
+		}
+		if freq == "MONTHLY" && hasMonth {
+			return "", fmt.Errorf("FREQ=MONTHLY with BYMONTH is not supported")
+		}
+		if freq == "YEARLY" && !hasMonth {
+			return "", fmt.Errorf("FREQ=YEARLY with BYMONTHDAY requires BYMONTH")
+		}
+
+		rule := "m " + strconv.Itoa(date.Day())
+		if hasMonthDay {
+			rule = "m " + byMonthDay
+		}
+		if hasMonth {
+			rule += " " + byMonth
+		}
+		days, months, err := parseMonthRule(rule[2:])
+		if err != nil {
+			return "", err
+		}
+		if !isValidDayMonth(date, days, months) {
+			return "", fmt.Errorf("start date does not match the rule")
+		}
+		return rule, nil
+	}
+	return "", fmt.Errorf("FREQ=%s is not supported", freq)
+}
+
+// parseByDay parses a BYDAY list without ordinals, e.g. "MO,WE"
+func parseByDay(value string) (map[int]bool, error) {
+	days := make(map[int]bool)
+	for _, code := range strings.Split(value, ",") {
+		day := 0
+		for d, c := range weekdayCodes {
+			if c == code {
+				day = d
+			}
+		}
+		if day == 0 {
+			return nil, fmt.Errorf("BYDAY value %q is not supported", code)
+		}
+		days[day] = true
+	}
+	return days, nil
+}
+
+// partNames lists the names of rule parts in order
+func partNames(parts map[string]string) string {
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// sortedKeys returns the weekdays of a set in order
+func sortedKeys(days map[int]bool) []int {
+	keys := make([]int, 0, len(days))
+	for day := range days {
+		keys = append(keys, day)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Occurrences lists the dates of a repeating task from its date up to and including until, at most limit dates
+func Occurrences(dateStr, repeat string, until time.Time, limit int) ([]string, error) {
+	date, err := time.Parse("20060102", dateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %v", err)
+	}
+
+	var dates []string
+	for !date.After(until) && len(dates) < limit {
+		dates = append(dates, date.Format("20060102"))
+
+		next, err := NextDate(date, date.Format("20060102"), repeat)
+		if err != nil {
+			return nil, err
+		}
+		date, _ = time.Parse("20060102", next)
+	}
+	return dates, nil
+}
+
+// sortedCodes returns the iCalendar codes of the weekdays in order
+func sortedCodes(days map[int]bool) []string {
+	weekdays := sortedKeys(days)
+	codes := make([]string, 0, len(weekdays))
+	for _, day := range weekdays {
+		codes = append(codes, weekdayCodes[day])
+	}
+	return codes
+}
+
+// joinInts joins numbers with commas
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
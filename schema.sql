@@ -3,7 +3,149 @@ CREATE TABLE IF NOT EXISTS scheduler (
     date TEXT NOT NULL,
     title TEXT NOT NULL,
     comment TEXT,
-    repeat TEXT DEFAULT '' NOT NULL
+    repeat TEXT DEFAULT '' NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    deleted_at TEXT,
+    project_id INTEGER REFERENCES projects(id),
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT,
+    status TEXT NOT NULL DEFAULT 'todo'
 );
 
-CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
+CREATE INDEX IF NOT EXISTS idx_deleted_at ON scheduler(deleted_at);
+
+CREATE TABLE IF NOT EXISTS completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    scheduled_date TEXT NOT NULL,
+    completed_at TEXT NOT NULL,
+    note TEXT DEFAULT '' NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_completions_task ON completions(task_id);
+CREATE INDEX IF NOT EXISTS idx_completions_completed_at ON completions(completed_at);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    time TEXT NOT NULL,
+    action TEXT NOT NULL,
+    task_id INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    ip TEXT DEFAULT '' NOT NULL,
+    before TEXT DEFAULT '' NOT NULL,
+    after TEXT DEFAULT '' NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_task ON audit_log(task_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log(time);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    color TEXT DEFAULT '' NOT NULL,
+    default_repeat TEXT DEFAULT '' NOT NULL,
+    archived_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_project ON scheduler(project_id);
+CREATE INDEX IF NOT EXISTS idx_priority ON scheduler(priority);
+CREATE INDEX IF NOT EXISTS idx_created_at ON scheduler(created_at);
+CREATE INDEX IF NOT EXISTS idx_status ON scheduler(status);
+
+CREATE TABLE IF NOT EXISTS checklist_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    text TEXT NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_checklist_items_task ON checklist_items(task_id, position);
+
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    depends_on_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    since INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (task_id, depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on ON task_dependencies(depends_on_id);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_task ON attachments(task_id);
+
+CREATE TABLE IF NOT EXISTS attachment_blobs (
+    attachment_id INTEGER PRIMARY KEY,
+    data BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    options TEXT DEFAULT '[]' NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_field_values (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    field_id INTEGER NOT NULL REFERENCES custom_fields(id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_field_values_field ON task_field_values(field_id, value);
+
+CREATE TABLE IF NOT EXISTS task_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_notes_task ON task_notes(task_id, created_at);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    comment TEXT DEFAULT '' NOT NULL,
+    repeat TEXT DEFAULT '' NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    project_id INTEGER,
+    date_offset INTEGER NOT NULL DEFAULT 0,
+    tags TEXT DEFAULT '[]' NOT NULL,
+    checklist TEXT DEFAULT '[]' NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS external_ids (
+    source TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    task_id INTEGER NOT NULL,
+    PRIMARY KEY (source, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_ids_task ON external_ids(task_id);
@@ -15,6 +15,11 @@ import (
 )
 
 func requestJSON(apipath string, values map[string]any, method string) ([]byte, error) {
+	_, body, err := requestWithHeaders(apipath, values, method, nil)
+	return body, err
+}
+
+func requestWithHeaders(apipath string, values map[string]any, method string, headers map[string]string) (*http.Response, []byte, error) {
 	var (
 		data []byte
 		err  error
@@ -23,22 +28,27 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 	if len(values) > 0 {
 		data, err = json.Marshal(values)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
-	var resp *http.Response
 
 	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return sendRequest(req)
+}
 
+func sendRequest(req *http.Request) (*http.Response, []byte, error) {
 	client := &http.Client{}
 	if len(Token) > 0 {
 		jar, err := cookiejar.New(nil)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		jar.SetCookies(req.URL, []*http.Cookie{
 			{
@@ -49,15 +59,16 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 		client.Jar = jar
 	}
 
-	resp, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if resp.Body != nil {
 		defer resp.Body.Close()
 	}
-	return io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
 }
 
 func postJSON(apipath string, values map[string]any, method string) (map[string]any, error) {
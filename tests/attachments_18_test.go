@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is enough for the content type to be detected as image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func uploadAttachment(t *testing.T, id, name string, content []byte) (*http.Response, map[string]any) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", name)
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL("api/task/attachments?id="+id), &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, body, err := sendRequest(req)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return resp, m
+}
+
+func TestAttachments(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{title: "Fix the layout"})
+	screenshot := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 1000)...)
+
+	resp, ret := uploadAttachment(t, id, `C:\shots\screen.png`, screenshot)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, ret)
+	assert.Equal(t, "screen.png", ret["name"])
+	assert.Equal(t, "image/png", ret["content_type"])
+	assert.Equal(t, float64(len(screenshot)), ret["size"])
+	attachment := fmt.Sprint(ret["id"])
+
+	// Limits
+	resp, ret = uploadAttachment(t, id, "notes.png", []byte("plain text pretending to be an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode, ret)
+	resp, ret = uploadAttachment(t, id, "huge.png", append(append([]byte{}, pngHeader...), make([]byte, 11<<20)...))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, ret)
+	resp, ret = uploadAttachment(t, "999999999", "screen.png", screenshot)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, ret)
+
+	body, err := requestJSON("api/task/attachments?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list["attachments"], 1)
+
+	// Download
+	resp, content, err := requestWithHeaders("api/task/attachments/download?attachment="+attachment, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "screen.png")
+	assert.Equal(t, screenshot, content)
+
+	// Deleting a single attachment
+	resp, ret = uploadAttachment(t, id, "report.png", screenshot)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, ret)
+	second := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/attachments?attachment="+second, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	resp, _, err = requestWithHeaders("api/task/attachments/download?attachment="+second, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Attachments are removed when the task is purged from the trash
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	var left int
+	assert.NoError(t, db.Get(&left, `SELECT count(*) FROM attachments WHERE task_id = ?`, id))
+	assert.Equal(t, 0, left)
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type auditEntry struct {
+	Action string            `json:"action"`
+	TaskID string            `json:"task_id"`
+	Actor  string            `json:"actor"`
+	IP     string            `json:"ip"`
+	Before map[string]string `json:"before"`
+	After  map[string]string `json:"after"`
+}
+
+func TestAudit(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+	id := addTask(t, task{
+		date:  now,
+		title: "Prepare the report",
+	})
+
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  now,
+		"title": "Prepare the quarterly report",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task updated successfully", ret["message"])
+
+	_, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+
+	body, err := requestJSON("api/admin/audit?task_id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Entries []auditEntry `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, 3, len(m.Entries))
+	if len(m.Entries) != 3 {
+		return
+	}
+
+	created, updated, done := m.Entries[0], m.Entries[1], m.Entries[2]
+	assert.Equal(t, "create", created.Action)
+	assert.Nil(t, created.Before)
+	assert.Equal(t, "Prepare the report", created.After["title"])
+	assert.NotEmpty(t, created.Actor)
+	assert.NotEmpty(t, created.IP)
+
+	assert.Equal(t, "update", updated.Action)
+	assert.Equal(t, "Prepare the report", updated.Before["title"])
+	assert.Equal(t, "Prepare the quarterly report", updated.After["title"])
+
+	assert.Equal(t, "done", done.Action)
+	assert.Equal(t, "Prepare the quarterly report", done.Before["title"])
+	if DeleteOnDone {
+		assert.Nil(t, done.After)
+	} else {
+		assert.Equal(t, "done", done.After["status"])
+	}
+
+	// The export contains one JSON document per line
+	resp, body, err := requestWithHeaders("api/admin/audit/export?action=update&task_id="+id, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/x-ndjson")
+
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var entry auditEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		assert.Equal(t, "update", entry.Action)
+		lines++
+	}
+	assert.Equal(t, 1, lines)
+
+	ret, err = postJSON("api/admin/audit?from=never", nil, http.MethodGet)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok)
+}
+
+func TestAuditRollback(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+	id := addTask(t, task{date: now, title: "Water the plants"})
+
+	// A change is saved only together with its audit entry
+	restore := failInserts(t, "audit_log", fmt.Sprintf("NEW.action = 'update' AND CAST(NEW.task_id AS TEXT) = '%s'", id))
+	ret, err := postJSON("api/task", map[string]any{"id": id, "date": now, "title": "Water the garden"}, http.MethodPut)
+	restore()
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "Water the plants", ret["title"])
+
+	body, err := requestJSON("api/admin/audit?action=update&task_id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Entries []auditEntry `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Empty(t, m.Entries)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	_, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
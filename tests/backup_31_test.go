@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backupHeader returns the bytes backups start with in the configured format
+func backupHeader() string {
+	switch {
+	case BackupEncrypted:
+		return "SCHDBAK\x01"
+	case BackupCompressed:
+		return "\x1f\x8b"
+	}
+	return "SQLite format 3\x00"
+}
+
+// storedBackup - a backup in the list of stored backups
+type storedBackup struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func TestBackup(t *testing.T) {
+	resp, body, err := requestWithHeaders("api/admin/backup", nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Regexp(t, `attachment; filename="scheduler-\d{8}-\d{6}\.\d{3}\.db`, resp.Header.Get("Content-Disposition"))
+	assert.True(t, len(body) > 100 && string(body[:len(backupHeader())]) == backupHeader(), "The backup is in the configured format")
+
+	if BackupDir == "" {
+		resp, _, err := requestWithHeaders("api/admin/backups", nil, http.MethodPost, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Backups are stored only when a directory is configured")
+		return
+	}
+
+	var created storedBackup
+	for i := 0; i <= BackupKeep; i++ {
+		resp, body, err := requestWithHeaders("api/admin/backups", nil, http.MethodPost, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.NoError(t, json.Unmarshal(body, &created))
+	}
+
+	var list struct {
+		Backups []storedBackup `json:"backups"`
+	}
+	resp, body, err = requestWithHeaders("api/admin/backups", nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, json.Unmarshal(body, &list))
+	if BackupKeep > 0 {
+		assert.Len(t, list.Backups, BackupKeep, "The oldest backups are removed")
+	}
+	if assert.NotEmpty(t, list.Backups) {
+		assert.Equal(t, created, list.Backups[0], "The newest backup comes first")
+	}
+
+	resp, body, err = requestWithHeaders("api/admin/backup?name="+created.Name, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, created.Size, int64(len(body)))
+	assert.Equal(t, fmt.Sprintf(`attachment; filename="%s"`, created.Name), resp.Header.Get("Content-Disposition"))
+
+	for _, name := range []string{"scheduler-20000101-000000.000.db", "../scheduler.db", "." + created.Name} {
+		resp, _, err := requestWithHeaders("api/admin/backup?name="+name, nil, http.MethodGet, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, name)
+	}
+}
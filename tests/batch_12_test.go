@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchResponse struct {
+	Error     string `json:"error"`
+	Committed bool   `json:"committed"`
+	Results   []struct {
+		Index int    `json:"index"`
+		Op    string `json:"op"`
+		ID    string `json:"id"`
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+func runBatch(t *testing.T, values map[string]any) (int, batchResponse) {
+	resp, body, err := requestWithHeaders("api/tasks/batch", values, http.MethodPost, nil)
+	assert.NoError(t, err)
+
+	var ret batchResponse
+	assert.NoError(t, json.Unmarshal(body, &ret))
+	return resp.StatusCode, ret
+}
+
+func TestBatch(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now().Format(`20060102`)
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	// All-or-nothing: one invalid operation rolls back the whole batch
+	status, ret := runBatch(t, map[string]any{
+		"operations": []map[string]any{
+			{"op": "create", "task": map[string]any{"date": now, "title": "Import 1"}},
+			{"op": "create", "task": map[string]any{"date": now, "title": "Import 2"}},
+			{"op": "create", "task": map[string]any{"date": now, "title": "Broken", "repeat": "ooops"}},
+		},
+	})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.False(t, ret.Committed)
+	assert.Equal(t, 3, len(ret.Results))
+	for _, r := range ret.Results {
+		assert.False(t, r.OK)
+		assert.NotEmpty(t, r.Error)
+	}
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// Best effort: valid operations are committed, invalid ones are reported
+	id := addTask(t, task{date: now, title: "Clean up"})
+	status, ret = runBatch(t, map[string]any{
+		"mode": "best_effort",
+		"operations": []map[string]any{
+			{"op": "create", "task": map[string]any{"date": now, "title": "Import 3"}},
+			{"op": "done", "id": id},
+			{"op": "delete", "id": id},
+			{"op": "explode"},
+		},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, ret.Committed)
+	assert.Equal(t, 4, len(ret.Results))
+	if len(ret.Results) != 4 {
+		return
+	}
+	assert.True(t, ret.Results[0].OK)
+	assert.NotEmpty(t, ret.Results[0].ID)
+	assert.True(t, ret.Results[1].OK)
+	assert.Equal(t, !DeleteOnDone, ret.Results[2].OK) // With DeleteOnDone the completed task is already in the trash
+	assert.False(t, ret.Results[3].OK)
+
+	notFoundTask(t, id)
+	body, err := requestJSON("api/task?id="+ret.Results[0].ID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "Import 3", m["title"])
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func davRequest(t *testing.T, method, path, body string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, getURL(strings.TrimPrefix(path, "/")), strings.NewReader(body))
+	assert.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, data, err := sendRequest(req)
+	assert.NoError(t, err)
+	return resp, string(data)
+}
+
+func davTodo(uid, summary, due, extra string) string {
+	return strings.Join([]string{
+		"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//test//EN",
+		"BEGIN:VTODO", "UID:" + uid, "SUMMARY:" + summary, "DUE;VALUE=DATE:" + due, extra,
+		"END:VTODO", "END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+}
+
+func TestCalDAV(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	future := time.Now().AddDate(0, 0, 10)
+	date := future.Format(`20060102`)
+	weekday := strings.ToUpper(future.Weekday().String()[:2])
+	const collection = "/dav/calendars/tasks/"
+
+	resp, _ := davRequest(t, http.MethodOptions, collection, "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("DAV"), "calendar-access")
+
+	// Discovery of the calendar home
+	resp, body := davRequest(t, "PROPFIND", "/dav/", `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:current-user-principal/><c:calendar-home-set/><d:unknown/></d:prop>
+</d:propfind>`, map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, body, "<C:calendar-home-set><D:href>/dav/calendars/</D:href></C:calendar-home-set>")
+	assert.Contains(t, body, "<D:unknown/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status>")
+
+	// Tasks created through the API are listed
+	ret, err := postJSON("api/task", map[string]any{"title": "Run " + suffix, "date": date, "repeat": "d 2"}, http.MethodPost)
+	assert.NoError(t, err)
+	native := fmt.Sprint(ret["id"])
+
+	propfind := `<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/"><d:prop><d:getetag/><cs:getctag/></d:prop></d:propfind>`
+	resp, body = davRequest(t, "PROPFIND", collection, propfind, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, body, "<D:href>"+collection+native+".ics</D:href>")
+	ctag := regexp.MustCompile(`<CS:getctag>([^<]+)</CS:getctag>`).FindStringSubmatch(body)
+	assert.Len(t, ctag, 2)
+
+	resp, body = davRequest(t, http.MethodGet, collection+native+".ics", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+	assert.Contains(t, body, "UID:task-"+native+"@scheduler\r\n")
+	assert.Contains(t, body, "RRULE:FREQ=DAILY;INTERVAL=2\r\n")
+
+	// A client stores a new task under its own name and UID
+	name := "client-" + suffix
+	todo := davTodo("uid-"+suffix, "Water plants "+suffix, date, "RRULE:FREQ=WEEKLY;BYDAY="+weekday+"\r\nPRIORITY:3")
+	resp, _ = davRequest(t, http.MethodPut, collection+name+".ics", todo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, _ = davRequest(t, http.MethodPut, collection+name+".ics", todo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, body = davRequest(t, http.MethodGet, collection+name+".ics", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "UID:uid-"+suffix+"\r\n")
+	assert.Contains(t, body, "PRIORITY:3\r\n")
+	etag := resp.Header.Get("ETag")
+
+	tasks := getTasks(t, url.QueryEscape("Water plants "+suffix))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, date, tasks[0]["date"])
+		assert.Equal(t, "high", tasks[0]["priority"])
+	}
+
+	resp, body = davRequest(t, "PROPFIND", collection, propfind, map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.NotContains(t, body, ctag[0], "The collection tag changes with its content")
+
+	// Updates are guarded by the entity tag
+	renamed := davTodo("uid-"+suffix, "Water all plants "+suffix, date, "RRULE:FREQ=WEEKLY;BYDAY="+weekday)
+	resp, _ = davRequest(t, http.MethodPut, collection+name+".ics", renamed, map[string]string{"If-Match": `"0-0"`})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp, _ = davRequest(t, http.MethodPut, collection+name+".ics", renamed, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Completing a repeating task moves it to its next date
+	completed := davTodo("uid-"+suffix, "Water all plants "+suffix, date, "RRULE:FREQ=WEEKLY;BYDAY="+weekday+"\r\nSTATUS:COMPLETED")
+	resp, _ = davRequest(t, http.MethodPut, collection+name+".ics", completed, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	tasks = getTasks(t, url.QueryEscape("Water all plants "+suffix))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, future.AddDate(0, 0, 7).Format(`20060102`), tasks[0]["date"])
+		assert.Equal(t, "todo", tasks[0]["status"])
+		assert.Equal(t, "none", tasks[0]["priority"], "A missing property clears the value")
+	}
+
+	// Rules without an equivalent and other components are rejected
+	resp, body = davRequest(t, http.MethodPut, collection+"other-"+suffix+".ics",
+		davTodo("other-"+suffix, "Club", date, "RRULE:FREQ=MONTHLY;BYDAY=2TU"), nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, body, "valid-calendar-object-resource")
+	resp, _ = davRequest(t, http.MethodPut, collection+"event-"+suffix+".ics",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:e\r\nSUMMARY:Event\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, body = davRequest(t, "REPORT", collection, `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>`+collection+name+`.ics</d:href>
+  <d:href>`+collection+native+`.ics</d:href>
+  <d:href>`+collection+`missing-`+suffix+`.ics</d:href>
+</c:calendar-multiget>`, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, body, "SUMMARY:Water all plants "+suffix)
+	assert.Contains(t, body, "UID:task-"+native+"@scheduler")
+	assert.Contains(t, body, "<D:href>"+collection+"missing-"+suffix+".ics</D:href><D:status>HTTP/1.1 404 Not Found</D:status>")
+
+	resp, body = davRequest(t, "REPORT", collection, `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter></c:filter>
+</c:calendar-query>`, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.NotContains(t, body, "<D:response>", "Tasks are never events")
+
+	// Deleting moves the tasks to the trash
+	resp, _ = davRequest(t, http.MethodDelete, collection+name+".ics", "", map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	for _, resource := range []string{name, native} {
+		resp, _ = davRequest(t, http.MethodDelete, collection+resource+".ics", "", nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = davRequest(t, http.MethodGet, collection+resource+".ics", "", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	if Token != "" {
+		req, err := http.NewRequest("PROPFIND", getURL("dav/"), nil)
+		assert.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Basic")
+
+		req.SetBasicAuth("reminders", "wrong password")
+		resp, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getFeed(t *testing.T, feedURL, query string) (int, string) {
+	sep := "?"
+	if strings.Contains(feedURL, "?") {
+		sep = "&"
+	}
+	resp, body, err := requestWithHeaders(strings.TrimPrefix(feedURL, "/")+sep+query, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+
+	// Unfold long lines
+	return resp.StatusCode, strings.ReplaceAll(string(body), "\r\n ", "")
+}
+
+func TestCalendarFeed(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := addProject(t, map[string]any{"name": "Calendar " + suffix})
+	future := time.Now().AddDate(0, 0, 10)
+	if future.AddDate(0, 0, 1).Day() == 1 {
+		t.Skip("The test date falls on the last day of a month")
+	}
+
+	add := func(values map[string]any) string {
+		values["project_id"] = project
+		ret, err := postJSON("api/task", values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Nil(t, ret["error"], values)
+		return fmt.Sprint(ret["id"])
+	}
+	oneOff := add(map[string]any{
+		"title": "Dentist; bring the card, please", "comment": "Line one\nLine two",
+		"date": future.Format(`20060102`), "priority": "urgent",
+	})
+	everyOther := add(map[string]any{"title": "Run", "date": future.Format(`20060102`), "repeat": "d 2"})
+	// The date does not match the rule, so the occurrences are listed one by one
+	lastDay := add(map[string]any{"title": "Pay the bills", "date": future.Format(`20060102`), "repeat": "m -1"})
+
+	body, err := requestJSON("api/calendar/token", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var token struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &token))
+	assert.True(t, strings.HasPrefix(token.URL, "/api/calendar.ics"))
+
+	if Token != "" {
+		assert.NotEmpty(t, token.Token)
+		status, _ := getFeed(t, "/api/calendar.ics", "project="+project)
+		assert.Equal(t, http.StatusUnauthorized, status, "The feed requires a feed token")
+
+		// A feed token does not give access to the rest of the API
+		resp, _, err := requestWithHeaders("api/tasks", nil, http.MethodGet, map[string]string{"Cookie": "token=" + token.Token})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	status, feed := getFeed(t, token.URL, "project="+project)
+	assert.Equal(t, http.StatusOK, status, feed)
+	assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(feed, "END:VCALENDAR\r\n"))
+
+	assert.Contains(t, feed, "UID:task-"+oneOff+"@scheduler\r\n")
+	assert.Contains(t, feed, `SUMMARY:Dentist\; bring the card\, please`+"\r\n")
+	assert.Contains(t, feed, `DESCRIPTION:Line one\nLine two`+"\r\n")
+	assert.Contains(t, feed, "DTSTART;VALUE=DATE:"+future.Format(`20060102`)+"\r\n")
+	assert.Contains(t, feed, "DTEND;VALUE=DATE:"+future.AddDate(0, 0, 1).Format(`20060102`)+"\r\n")
+	assert.Contains(t, feed, "PRIORITY:1\r\n")
+
+	assert.Contains(t, feed, "UID:task-"+everyOther+"@scheduler\r\nDTSTAMP:")
+	assert.Contains(t, feed, "RRULE:FREQ=DAILY;INTERVAL=2\r\n")
+
+	assert.NotContains(t, feed, "UID:task-"+lastDay+"@scheduler")
+	assert.Greater(t, strings.Count(feed, "UID:task-"+lastDay+"-"), 10)
+
+	// Tasks as VTODO
+	status, feed = getFeed(t, token.URL, "project="+project+"&type=todo")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, feed, "BEGIN:VTODO\r\n")
+	assert.NotContains(t, feed, "BEGIN:VEVENT")
+	assert.Contains(t, feed, "STATUS:NEEDS-ACTION\r\n")
+	assert.Contains(t, feed, "DUE;VALUE=DATE:"+future.Format(`20060102`)+"\r\n")
+
+	status, _ = getFeed(t, token.URL, "type=journal")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	ret, err := postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+}
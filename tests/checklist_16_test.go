@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type checklistItem struct {
+	ID       string `json:"id"`
+	Position int    `json:"position"`
+	Text     string `json:"text"`
+	Done     bool   `json:"done"`
+}
+
+func getChecklist(t *testing.T, id string) []checklistItem {
+	body, err := requestJSON("api/task/checklist?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]checklistItem
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["items"]
+}
+
+func TestChecklist(t *testing.T) {
+	id := addTask(t, task{title: "Release", repeat: "d 7"})
+
+	var ids []string
+	for _, text := range []string{"tag", "build", "publish"} {
+		ret, err := postJSON("api/task/checklist?id="+id, map[string]any{"text": text}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, text, ret["text"])
+		assert.Equal(t, false, ret["done"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	for _, v := range []struct {
+		path   string
+		values map[string]any
+		status int
+	}{
+		{"api/task/checklist?id=" + id, map[string]any{"text": "  "}, http.StatusBadRequest},
+		{"api/task/checklist?id=999999999", map[string]any{"text": "announce"}, http.StatusNotFound},
+		{"api/task/checklist/reorder?id=" + id, map[string]any{"items": []string{ids[0], ids[1]}}, http.StatusBadRequest},
+		{"api/task/checklist/reorder?id=" + id, map[string]any{"items": []string{ids[0], ids[0], ids[1]}}, http.StatusBadRequest},
+		{"api/task/checklist/reorder?id=" + id, map[string]any{"items": []string{ids[0], ids[1], "999999999"}}, http.StatusBadRequest},
+		{"api/task/checklist/toggle?item=999999999", nil, http.StatusNotFound},
+	} {
+		resp, _, err := requestWithHeaders(v.path, v.values, http.MethodPost, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, v.status, resp.StatusCode, v.path)
+	}
+
+	// Reordering
+	body, err := requestJSON("api/task/checklist/reorder?id="+id, map[string]any{"items": []string{ids[2], ids[0], ids[1]}}, http.MethodPost)
+	assert.NoError(t, err)
+	var reordered map[string][]checklistItem
+	assert.NoError(t, json.Unmarshal(body, &reordered))
+	assert.Equal(t, []string{"publish", "tag", "build"}, checklistTexts(reordered["items"]))
+
+	// Toggling
+	ret, err := postJSON("api/task/checklist/toggle?item="+ids[0], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["done"])
+	ret, err = postJSON("api/task/checklist/toggle?item="+ids[1], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["done"])
+	ret, err = postJSON("api/task/checklist/toggle?item="+ids[1], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, false, ret["done"])
+
+	// Deleting
+	ret, err = postJSON("api/task/checklist?item="+ids[2], nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	items := getChecklist(t, id)
+	assert.Equal(t, []string{"tag", "build"}, checklistTexts(items))
+	assert.True(t, items[0].Done)
+
+	// Rolling a repeating task forward unchecks its checklist
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	for _, item := range getChecklist(t, id) {
+		assert.False(t, item.Done, item.Text)
+	}
+}
+
+func checklistTexts(items []checklistItem) []string {
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	return texts
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addField(t *testing.T, values map[string]any) (int, string) {
+	resp, body, err := requestWithHeaders("api/field", values, http.MethodPost, nil)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return resp.StatusCode, fmt.Sprint(m["id"])
+}
+
+func getFieldTasks(t *testing.T, query string) []map[string]any {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["tasks"]
+}
+
+func TestCustomFields(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano() % 1e9)
+	estimate, due, size, notes := "estimate_"+suffix, "due_"+suffix, "size_"+suffix, "notes_"+suffix
+	project := addProject(t, map[string]any{"name": "Fields " + suffix})
+
+	// Definitions
+	for _, v := range []map[string]any{
+		{"name": estimate, "type": "number"},
+		{"name": due, "type": "date"},
+		{"name": size, "type": "enum", "options": []string{"S", "M", "L", "M"}},
+		{"name": notes, "type": "text"},
+	} {
+		status, _ := addField(t, v)
+		assert.Equal(t, http.StatusOK, status, v)
+	}
+	for _, v := range []struct {
+		values map[string]any
+		status int
+	}{
+		{map[string]any{"name": estimate, "type": "number"}, http.StatusConflict},
+		{map[string]any{"name": "Bad name!", "type": "text"}, http.StatusBadRequest},
+		{map[string]any{"name": "color_" + suffix, "type": "colour"}, http.StatusBadRequest},
+		{map[string]any{"name": "color_" + suffix, "type": "enum"}, http.StatusBadRequest},
+		{map[string]any{"name": "color_" + suffix, "type": "text", "options": []string{"red"}}, http.StatusBadRequest},
+	} {
+		status, _ := addField(t, v.values)
+		assert.Equal(t, v.status, status, v.values)
+	}
+
+	body, err := requestJSON("api/fields", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	for _, field := range list["fields"] {
+		if field["name"] == size {
+			assert.Equal(t, []any{"S", "M", "L"}, field["options"])
+		}
+	}
+
+	// Values are validated against the definitions
+	for _, fields := range []map[string]string{
+		{estimate: "two"},
+		{due: "2024-01-01"},
+		{size: "XL"},
+		{"unknown_" + suffix: "1"},
+	} {
+		ret, err := postJSON("api/task", map[string]any{"title": "Invalid", "project_id": project, "fields": fields}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], fields)
+	}
+
+	ids := map[string]string{}
+	for _, v := range []struct {
+		title  string
+		fields map[string]string
+	}{
+		{"small", map[string]string{estimate: "2.50", size: "S", due: "20300101"}},
+		{"large", map[string]string{estimate: "13", size: "L", notes: "needs review"}},
+		{"unsized", map[string]string{estimate: ""}},
+		{"medium", map[string]string{estimate: "5", size: "M"}},
+	} {
+		ret, err := postJSON("api/task", map[string]any{"title": v.title, "project_id": project, "fields": v.fields}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Nil(t, ret["error"], v.title)
+		ids[v.title] = fmt.Sprint(ret["id"])
+	}
+
+	body, err = requestJSON("api/task?id="+ids["small"], nil, http.MethodGet)
+	assert.NoError(t, err)
+	var small map[string]any
+	assert.NoError(t, json.Unmarshal(body, &small))
+	assert.Equal(t, map[string]any{estimate: "2.5", size: "S", due: "20300101"}, small["fields"])
+
+	titles := func(tasks []map[string]any) []string {
+		result := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			result = append(result, fmt.Sprint(task["title"]))
+		}
+		return result
+	}
+
+	// Filtering and sorting; tasks without a value come last
+	assert.Equal(t, []string{"large"}, titles(getFieldTasks(t, "project="+project+"&field."+size+"=L")))
+	assert.Equal(t, []string{"small"}, titles(getFieldTasks(t, "project="+project+"&field."+estimate+"=2.5")))
+	assert.Equal(t, []string{"small", "medium", "large", "unsized"}, titles(getFieldTasks(t, "project="+project+"&sort=field."+estimate)))
+	assert.Equal(t, []string{"large", "medium", "small", "unsized"}, titles(getFieldTasks(t, "project="+project+"&sort=field."+size+"&order=desc")))
+	for _, query := range []string{"sort=field.unknown_" + suffix, "field.unknown_" + suffix + "=1", "field." + estimate + "=many"} {
+		resp, _, err := requestWithHeaders("api/tasks?"+query, nil, http.MethodGet, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+
+	// Updating without fields keeps them, an empty value removes one
+	now := time.Now().Format(`20060102`)
+	ret, err := postJSON("api/task", map[string]any{"id": ids["large"], "date": now, "title": "large", "project_id": project}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	assert.Equal(t, []string{"large"}, titles(getFieldTasks(t, "project="+project+"&field."+notes+"="+url.QueryEscape("needs review"))))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id": ids["large"], "date": now, "title": "large", "project_id": project,
+		"fields": map[string]string{estimate: "8", notes: ""},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	body, err = requestJSON("api/task?id="+ids["large"], nil, http.MethodGet)
+	assert.NoError(t, err)
+	var large map[string]any
+	assert.NoError(t, json.Unmarshal(body, &large))
+	assert.Equal(t, map[string]any{estimate: "8"}, large["fields"])
+
+	// A task whose field values cannot be saved is neither created nor changed
+	failing := "unsaved " + suffix
+	restore := failInserts(t, "task_field_values", fmt.Sprintf("NEW.value = '%s'", failing))
+	ret, err = postJSON("api/task", map[string]any{
+		"title": "Unsaved " + suffix, "project_id": project, "fields": map[string]string{notes: failing},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+	assert.Empty(t, searchTasks(t, "Unsaved "+suffix))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id": ids["large"], "date": now, "title": "renamed", "project_id": project,
+		"fields": map[string]string{notes: failing},
+	}, http.MethodPut)
+	restore()
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+	body, err = requestJSON("api/task?id="+ids["large"], nil, http.MethodGet)
+	assert.NoError(t, err)
+	large = nil
+	assert.NoError(t, json.Unmarshal(body, &large))
+	assert.Equal(t, "large", large["title"])
+	assert.Equal(t, map[string]any{estimate: "8"}, large["fields"])
+
+	// Archiving keeps the tasks with fields out of the other tests' lists
+	ret, err = postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+}
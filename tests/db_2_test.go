@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
@@ -12,11 +13,17 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64          `db:"id"`
+	Date      string         `db:"date"`
+	Title     string         `db:"title"`
+	Comment   string         `db:"comment"`
+	Repeat    string         `db:"repeat"`
+	Version   int64          `db:"version"`
+	DeletedAt sql.NullString `db:"deleted_at"`
+	ProjectID sql.NullInt64  `db:"project_id"`
+	Priority  int64          `db:"priority"`
+	CreatedAt sql.NullString `db:"created_at"`
+	Status    string         `db:"status"`
 }
 
 func count(db *sqlx.DB) (int, error) {
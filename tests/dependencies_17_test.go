@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func linkTasks(t *testing.T, id, dependsOn, method string) int {
+	resp, _, err := requestWithHeaders("api/task/dependency?id="+id+"&depends_on="+dependsOn, nil, method, nil)
+	assert.NoError(t, err)
+	return resp.StatusCode
+}
+
+func isBlocked(t *testing.T, id string) bool {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["blocked"] == true
+}
+
+func TestDependencies(t *testing.T) {
+	release := addTask(t, task{title: "Release"})
+	build := addTask(t, task{title: "Build"})
+	announce := addTask(t, task{title: `Announce "v2"`})
+
+	assert.Equal(t, http.StatusOK, linkTasks(t, build, release, http.MethodPost))
+	assert.Equal(t, http.StatusOK, linkTasks(t, announce, build, http.MethodPost))
+	assert.Equal(t, http.StatusOK, linkTasks(t, announce, build, http.MethodPost), "Linking twice is allowed")
+
+	// Cycles are rejected
+	assert.Equal(t, http.StatusConflict, linkTasks(t, release, announce, http.MethodPost))
+	assert.Equal(t, http.StatusConflict, linkTasks(t, release, release, http.MethodPost))
+	assert.Equal(t, http.StatusNotFound, linkTasks(t, release, "999999999", http.MethodPost))
+
+	assert.False(t, isBlocked(t, release))
+	assert.True(t, isBlocked(t, build))
+	assert.True(t, isBlocked(t, announce))
+
+	// The blocked flag is part of the task list
+	body, err := requestJSON("api/tasks?search=Build", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	found := false
+	for _, task := range list["tasks"] {
+		if task["id"] == build {
+			found = true
+			assert.Equal(t, true, task["blocked"])
+		}
+	}
+	assert.True(t, found)
+
+	// Graph export
+	body, err = requestJSON("api/tasks/graph", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var graph struct {
+		Nodes []map[string]any    `json:"nodes"`
+		Edges []map[string]string `json:"edges"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &graph))
+	assert.Contains(t, graph.Edges, map[string]string{"task_id": build, "depends_on_id": release})
+	assert.Contains(t, graph.Edges, map[string]string{"task_id": announce, "depends_on_id": build})
+
+	resp, dot, err := requestWithHeaders("api/tasks/graph?format=dot", nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(string(dot), "digraph tasks {"))
+	assert.Contains(t, string(dot), fmt.Sprintf("%q -> %q;", release, build))
+	assert.Contains(t, string(dot), `Announce \"v2\"`)
+
+	// Completing a task reports the tasks it unblocks
+	ret, err := postJSON("api/task/done?id="+release, nil, http.MethodPost)
+	assert.NoError(t, err)
+	unblocked, _ := ret["unblocked"].([]any)
+	if assert.Len(t, unblocked, 1) {
+		assert.Equal(t, build, unblocked[0].(map[string]any)["id"])
+	}
+	assert.False(t, isBlocked(t, build))
+	assert.True(t, isBlocked(t, announce))
+
+	// A repeating prerequisite stops blocking once it has been completed
+	standup := addTask(t, task{title: "Standup", repeat: "d 1"})
+	assert.Equal(t, http.StatusOK, linkTasks(t, announce, standup, http.MethodPost))
+	assert.Equal(t, http.StatusOK, linkTasks(t, announce, build, http.MethodDelete))
+	assert.Equal(t, http.StatusNotFound, linkTasks(t, announce, build, http.MethodDelete))
+	assert.True(t, isBlocked(t, announce))
+
+	ret, err = postJSON("api/task/done?id="+standup, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["unblocked"])
+	assert.False(t, isBlocked(t, announce))
+
+	assert.Equal(t, http.StatusOK, linkTasks(t, announce, standup, http.MethodDelete))
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getETag(t *testing.T, id string) string {
+	resp, _, err := requestWithHeaders("api/task?id="+id, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	return etag
+}
+
+func TestETag(t *testing.T) {
+	id := addTask(t, task{
+		date:  time.Now().Format(`20060102`),
+		title: "Review the pull request",
+	})
+	etag := getETag(t, id)
+
+	update := map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Review the pull request again",
+	}
+
+	// The first tab saves its changes
+	resp, _, err := requestWithHeaders("api/task", update, http.MethodPut, map[string]string{"If-Match": etag})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	newETag := resp.Header.Get("ETag")
+	assert.NotEqual(t, etag, newETag)
+	assert.Equal(t, newETag, getETag(t, id))
+
+	// The second tab still holds the old version and must not overwrite the changes
+	update["title"] = "Stale title"
+	resp, body, err := requestWithHeaders("api/task", update, http.MethodPut, map[string]string{"If-Match": etag})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	assert.Equal(t, newETag, resp.Header.Get("ETag"))
+
+	var m struct {
+		Error string            `json:"error"`
+		Task  map[string]string `json:"task"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m.Error)
+	assert.Equal(t, "Review the pull request again", m.Task["title"])
+
+	// Stale deletion and completion are rejected as well
+	resp, _, err = requestWithHeaders("api/task/done?id="+id, nil, http.MethodPost, map[string]string{"If-Match": etag})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, _, err = requestWithHeaders("api/task?id="+id, nil, http.MethodDelete, map[string]string{"If-Match": etag})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	// The current version is accepted
+	resp, _, err = requestWithHeaders("api/task?id="+id, nil, http.MethodDelete, map[string]string{"If-Match": newETag})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	notFoundTask(t, id)
+}
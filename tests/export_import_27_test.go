@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksImportReport struct {
+	DryRun  bool `json:"dry_run"`
+	Created []struct {
+		ExternalID string         `json:"external_id"`
+		Task       map[string]any `json:"task"`
+	} `json:"created"`
+	Skipped     []map[string]any `json:"skipped"`
+	Unconverted []map[string]any `json:"unconverted_rules"`
+	Warnings    []map[string]any `json:"warnings"`
+}
+
+func exportTasks(t *testing.T, format string) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodGet, getURL("api/export?format="+format), nil)
+	assert.NoError(t, err)
+	resp, body, err := sendRequest(req)
+	assert.NoError(t, err)
+	return resp, body
+}
+
+func importTasks(t *testing.T, query string, data []byte) (int, tasksImportReport) {
+	req, err := http.NewRequest(http.MethodPost, getURL("api/import?"+query), bytes.NewReader(data))
+	assert.NoError(t, err)
+	resp, body, err := sendRequest(req)
+	assert.NoError(t, err)
+
+	var report tasksImportReport
+	if resp.StatusCode == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(body, &report), string(body))
+	}
+	return resp.StatusCode, report
+}
+
+func TestExportImport(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	name := "Export " + suffix
+	project := addProject(t, map[string]any{"name": name})
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date": "20300105", "title": "Ship, \"v2\"", "comment": "two\nlines", "repeat": "d 7",
+		"priority": "high", "tags": []string{"work", "release"}, "project_id": project,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	ship := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task", map[string]any{"date": "20300101", "title": "Build", "project_id": project}, http.MethodPost)
+	assert.NoError(t, err)
+	build := fmt.Sprint(ret["id"])
+
+	assert.Equal(t, http.StatusOK, linkTasks(t, ship, build, http.MethodPost))
+	for _, text := range []string{"Tag the release", "Write notes"} {
+		_, err := postJSON("api/task/checklist?id="+ship, map[string]any{"text": text}, http.MethodPost)
+		assert.NoError(t, err)
+	}
+	items := getChecklist(t, ship)
+	if assert.Len(t, items, 2) {
+		_, err = postJSON("api/task/checklist/toggle?item="+items[0].ID, nil, http.MethodPost)
+		assert.NoError(t, err)
+	}
+	_, err = postJSON("api/task/notes?id="+ship, map[string]any{"text": "Waiting for QA"}, http.MethodPost)
+	assert.NoError(t, err)
+
+	resp, _ := exportTasks(t, "xml")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Only the tasks of this test are re-imported, so other tests' data is left alone
+	exported := map[string][]byte{}
+
+	resp, body := exportTasks(t, "json")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "tasks.json")
+	var all []map[string]any
+	assert.NoError(t, json.Unmarshal(body, &all))
+	var own []map[string]any
+	for _, task := range all {
+		if task["project"] == name {
+			own = append(own, task)
+		}
+	}
+	if assert.Len(t, own, 2) {
+		for _, task := range own {
+			if task["id"] == ship {
+				assert.Equal(t, "high", task["priority"])
+				assert.Equal(t, []any{"release", "work"}, task["tags"])
+				assert.Equal(t, []any{build}, task["depends_on"])
+				assert.Equal(t, []any{"Waiting for QA"}, task["notes"])
+				assert.Equal(t, []any{
+					map[string]any{"text": "Tag the release", "done": true},
+					map[string]any{"text": "Write notes"},
+				}, task["checklist"])
+			}
+		}
+	}
+	exported["json"], err = json.Marshal(own)
+	assert.NoError(t, err)
+
+	resp, body = exportTasks(t, "ndjson")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/x-ndjson")
+	var lines bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"project":"`+name+`"`) {
+			lines.WriteString(scanner.Text() + "\n")
+		}
+	}
+	exported["ndjson"] = lines.Bytes()
+
+	resp, body = exportTasks(t, "csv")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/csv")
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.NoError(t, err)
+	var rows bytes.Buffer
+	writer := csv.NewWriter(&rows)
+	assert.NoError(t, writer.Write(records[0]))
+	for _, record := range records[1:] {
+		if record[8] == name {
+			assert.NoError(t, writer.Write(record))
+		}
+	}
+	writer.Flush()
+	exported["csv"] = rows.Bytes()
+
+	var targets []string
+	for _, format := range []string{"json", "ndjson", "csv"} {
+		target := addProject(t, map[string]any{"name": "Import " + format + " " + suffix})
+		targets = append(targets, target)
+
+		status, report := importTasks(t, "format="+format+"&dry_run=true&project="+target, exported[format])
+		assert.Equal(t, http.StatusOK, status, format)
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Created, 2, format)
+		assert.Empty(t, getFieldTasks(t, "project="+target), format)
+
+		status, report = importTasks(t, "format="+format+"&project="+target, exported[format])
+		assert.Equal(t, http.StatusOK, status, format)
+		assert.Len(t, report.Created, 2, format)
+		assert.Empty(t, report.Skipped, format)
+		assert.Empty(t, report.Warnings, format)
+
+		tasks := getFieldTasks(t, "project="+target)
+		if !assert.Len(t, tasks, 2, format) {
+			continue
+		}
+		var shipped map[string]any
+		for _, task := range tasks {
+			if task["title"] == "Ship, \"v2\"" {
+				shipped = task
+			}
+		}
+		if !assert.NotNil(t, shipped, format) {
+			continue
+		}
+		id := fmt.Sprint(shipped["id"])
+		assert.NotEqual(t, ship, id)
+		assert.Equal(t, "20300105", shipped["date"], format)
+		assert.Equal(t, "two\nlines", shipped["comment"], format)
+		assert.Equal(t, "d 7", shipped["repeat"], format)
+		assert.Equal(t, "high", shipped["priority"], format)
+		assert.Equal(t, []any{"release", "work"}, shipped["tags"], format)
+		assert.True(t, isBlocked(t, id), format)
+
+		items := getChecklist(t, id)
+		if assert.Len(t, items, 2, format) {
+			assert.Equal(t, "Tag the release", items[0].Text)
+			assert.True(t, items[0].Done)
+			assert.False(t, items[1].Done)
+		}
+
+		body, err := requestJSON("api/task/notes?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var notes map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &notes))
+		if format == "csv" {
+			assert.Empty(t, notes["notes"], "Notes are not part of CSV exports")
+		} else if assert.Len(t, notes["notes"], 1, format) {
+			assert.Equal(t, "Waiting for QA", notes["notes"][0]["text"])
+		}
+	}
+
+	// A spreadsheet with its own column names and some invalid rows
+	sheet := addProject(t, map[string]any{"name": "Sheet " + suffix})
+	targets = append(targets, sheet)
+	data := "Name,When,Labels,Importance\n" +
+		"Buy milk " + suffix + ",2030-01-05,\"home, errands\",low\n" +
+		",2030-01-06,,\n" +
+		"Bad date,2030-13-01,,\n" +
+		"Bad priority,,,whenever\n"
+	mapping := "&column.title=Name&column.date=When&column.tags=Labels&column.priority=Importance"
+	status, report := importTasks(t, "format=csv&project="+sheet+mapping, []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	if assert.Len(t, report.Created, 1) {
+		assert.Equal(t, "20300105", report.Created[0].Task["date"])
+		assert.Equal(t, []any{"errands", "home"}, report.Created[0].Task["tags"])
+	}
+	if assert.Len(t, report.Skipped, 3) {
+		for i, row := range []float64{3, 4, 5} {
+			assert.Equal(t, row, report.Skipped[i]["row"])
+			assert.NotEmpty(t, report.Skipped[i]["reason"])
+		}
+	}
+
+	// A dry run checks the records exactly like the import does
+	invalid := addProject(t, map[string]any{"name": "Invalid " + suffix})
+	targets = append(targets, invalid)
+	data = "title,date,repeat,status\n" +
+		"Valid,20301201,,\n" +
+		"Bad rule,20301201,zz 9,\n" +
+		"Bad status,20301201,,frozen\n"
+	status, preview := importTasks(t, "format=csv&dry_run=true&project="+invalid, []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, getFieldTasks(t, "project="+invalid))
+	status, report = importTasks(t, "format=csv&project="+invalid, []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, report.Created, 1)
+	assert.Equal(t, report.Skipped, preview.Skipped)
+	if assert.Len(t, preview.Created, 1) {
+		assert.Equal(t, "Valid", preview.Created[0].Task["title"])
+	}
+	if assert.Len(t, preview.Skipped, 2) {
+		assert.Equal(t, "invalid repeat rule", preview.Skipped[0]["reason"])
+		assert.Equal(t, "Bad status", preview.Skipped[1]["title"])
+	}
+
+	for _, v := range []struct {
+		query string
+		data  string
+	}{
+		{"format=xml", "[]"},
+		{"format=json", `{"title": "not an array"}`},
+		{"format=csv", "Name,When\nBuy milk,20300105\n"},
+		{"format=csv&column.owner=Name", "Name\nBuy milk\n"},
+		{"format=csv&column.title=Missing", "Name\nBuy milk\n"},
+	} {
+		status, _ := importTasks(t, v.query, []byte(v.data))
+		assert.Equal(t, http.StatusBadRequest, status, v.query)
+	}
+	status, _ = importTasks(t, "format=json&project=999999999", []byte(`[{"title": "Orphan"}]`))
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Archive the projects so that their tags do not show up in other tests' task lists
+	for _, id := range append(targets, project) {
+		_, err := postJSON("api/project?id="+url.QueryEscape(id)+"&tasks=archive", nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getCompletions(t *testing.T, apipath string) []map[string]string {
+	body, err := requestJSON(apipath, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["completions"]
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Water the plants",
+		repeat: "d 2",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task marked as done", ret["message"])
+
+	// Backdated completion with a note
+	yesterday := now.AddDate(0, 0, -1)
+	ret, err = postJSON("api/task/done?id="+id, map[string]any{
+		"completed_at": yesterday.Format(`20060102`),
+		"note":         "Forgot to mark it",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task marked as done", ret["message"])
+
+	history := getCompletions(t, "api/task/history?id="+id)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, "Water the plants", history[0]["title"])
+	assert.Equal(t, now.Format(`20060102`), history[0]["scheduled_date"])
+	assert.Equal(t, "Forgot to mark it", history[1]["note"])
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), history[1]["scheduled_date"])
+
+	// Completions in the future are rejected
+	ret, err = postJSON("api/task/done?id="+id, map[string]any{
+		"completed_at": now.AddDate(0, 0, 2).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok)
+
+	// The global log is filtered by the completion date
+	day := yesterday.Format(`20060102`)
+	found := false
+	for _, c := range getCompletions(t, "api/history?from="+day+"&to="+day) {
+		assert.Equal(t, yesterday.Format(`2006-01-02`), c["completed_at"][:10])
+		if c["task_id"] == id {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	ret, err = postJSON("api/history?from=yesterday", nil, http.MethodGet)
+	assert.NoError(t, err)
+	_, ok = ret["error"]
+	assert.True(t, ok)
+}
+
+// failInserts makes inserts into a table that match the condition fail until the returned function is called
+func failInserts(t *testing.T, table, condition string) func() {
+	db := openDB(t)
+	trigger := "test_fail_" + table
+	_, err := db.Exec(`CREATE TRIGGER ` + trigger + ` BEFORE INSERT ON ` + table + ` WHEN ` + condition + `
+        BEGIN SELECT RAISE(ABORT, 'forced failure'); END`)
+	assert.NoError(t, err)
+	return func() {
+		_, err := db.Exec(`DROP TRIGGER IF EXISTS ` + trigger)
+		assert.NoError(t, err)
+		db.Close()
+	}
+}
+
+func TestHistoryRollback(t *testing.T) {
+	note := fmt.Sprint("Rolled back ", time.Now().UnixNano())
+	id := addTask(t, task{date: time.Now().Format(`20060102`), title: "Feed the cat", repeat: "d 1"})
+	before, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	// The task is rolled forward only together with its completion record
+	restore := failInserts(t, "completions", fmt.Sprintf("NEW.note = '%s'", note))
+	ret, err := postJSON("api/task/done?id="+id, map[string]any{"note": note}, http.MethodPost)
+	restore()
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	after, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after, "The failed completion leaves the task unchanged")
+	assert.Empty(t, getCompletions(t, "api/task/history?id="+id))
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	_, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type importReport struct {
+	DryRun  bool `json:"dry_run"`
+	Created []struct {
+		ExternalID string         `json:"external_id"`
+		Task       map[string]any `json:"task"`
+	} `json:"created"`
+	Skipped     []map[string]string `json:"skipped"`
+	Unconverted []map[string]string `json:"unconverted_rules"`
+}
+
+func importICal(t *testing.T, query, data string) (int, importReport) {
+	req, err := http.NewRequest(http.MethodPost, getURL("api/import/ical?"+query), strings.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/calendar")
+	resp, body, err := sendRequest(req)
+	assert.NoError(t, err)
+
+	var report importReport
+	if resp.StatusCode == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(body, &report), string(body))
+	}
+	return resp.StatusCode, report
+}
+
+func TestICalImport(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := addProject(t, map[string]any{"name": "Import " + suffix})
+	future := time.Now().AddDate(0, 0, 10)
+	date := future.Format(`20060102`)
+	weekday := strings.ToUpper(future.Weekday().String()[:2])
+	isoWeekday := int(future.Weekday())
+	if isoWeekday == 0 {
+		isoWeekday = 7
+	}
+
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"BEGIN:VTODO",
+		"UID:water-" + suffix,
+		`SUMMARY:Water\, the plants`,
+		"DESCRIPTION:A long description that is folded over",
+		"  two lines",
+		"DTSTART;VALUE=DATE:" + date,
+		"RRULE:FREQ=WEEKLY;BYDAY=" + weekday,
+		"CATEGORIES:Home,Garden Work",
+		"PRIORITY:1",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:rent-" + suffix,
+		"SUMMARY:Pay rent",
+		"DTSTART;TZID=Europe/Berlin:" + date + "T090000",
+		fmt.Sprintf("RRULE:FREQ=MONTHLY;BYMONTHDAY=%d", future.Day()),
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:rent-" + suffix,
+		"RECURRENCE-ID:" + date,
+		"SUMMARY:Pay rent later",
+		"DTSTART:" + date + "T120000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:club-" + suffix,
+		"SUMMARY:Book club",
+		"DTSTART:" + date + "T180000Z",
+		"RRULE:FREQ=MONTHLY;BYDAY=2TU",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:old-" + suffix,
+		"SUMMARY:Old meeting",
+		"DTSTART:20200101T100000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	// A dry run only reports what would be imported
+	status, report := importICal(t, "dry_run=true&project="+project, calendar)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Created, 3)
+	assert.Len(t, report.Skipped, 2)
+	if assert.Len(t, report.Unconverted, 1) {
+		assert.Equal(t, "club-"+suffix, report.Unconverted[0]["external_id"])
+		assert.Equal(t, "FREQ=MONTHLY;BYDAY=2TU", report.Unconverted[0]["rule"])
+	}
+	assert.Empty(t, getFieldTasks(t, "project="+project))
+
+	status, report = importICal(t, "project="+project, calendar)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, report.DryRun)
+	assert.Len(t, report.Created, 3)
+
+	tasks := make(map[string]map[string]any)
+	for _, created := range report.Created {
+		assert.NotEmpty(t, created.Task["id"])
+		tasks[created.ExternalID] = created.Task
+	}
+	water := tasks["water-"+suffix]
+	assert.Equal(t, "Water, the plants", water["title"])
+	assert.Equal(t, "A long description that is folded over two lines", water["comment"])
+	assert.Equal(t, date, water["date"])
+	assert.Equal(t, fmt.Sprintf("w %d", isoWeekday), water["repeat"])
+	assert.Equal(t, []any{"garden-work", "home"}, water["tags"])
+	assert.Equal(t, "urgent", water["priority"])
+
+	rent := tasks["rent-"+suffix]
+	assert.Equal(t, date, rent["date"])
+	assert.Equal(t, fmt.Sprintf("m %d", future.Day()), rent["repeat"])
+
+	club := tasks["club-"+suffix]
+	assert.Equal(t, date, club["date"])
+	assert.Equal(t, "", club["repeat"], "A rule that cannot be converted is dropped")
+
+	var skipped []string
+	for _, issue := range report.Skipped {
+		skipped = append(skipped, issue["title"])
+	}
+	assert.ElementsMatch(t, []string{"Pay rent later", "Old meeting"}, skipped)
+	assert.Len(t, getFieldTasks(t, "project="+project), 3)
+
+	// Entries imported before are skipped
+	status, report = importICal(t, "project="+project, calendar)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, report.Created)
+	assert.Len(t, report.Skipped, 5)
+	assert.Len(t, getFieldTasks(t, "project="+project), 3)
+
+	status, _ = importICal(t, "", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VCALENDAR\r\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = importICal(t, "project=999999999",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:lost-"+suffix+"\r\nSUMMARY:Lost\r\nEND:VTODO\r\nEND:VCALENDAR\r\n")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// A task is created only together with the link to its UID, so that importing again does not duplicate it
+	linked := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:linked-" + suffix + "\r\nSUMMARY:Linked\r\nDTSTART;VALUE=DATE:" + date +
+		"\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	restore := failInserts(t, "external_ids", "NEW.external_id = 'linked-"+suffix+"'")
+	status, report = importICal(t, "project="+project, linked)
+	restore()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, report.Created)
+	assert.Len(t, report.Skipped, 1)
+	assert.Len(t, getFieldTasks(t, "project="+project), 3)
+
+	for _, created := range []int{1, 0} {
+		status, report = importICal(t, "project="+project, linked)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, report.Created, created)
+		assert.Len(t, getFieldTasks(t, "project="+project), 4)
+	}
+
+	ret, err := postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+}
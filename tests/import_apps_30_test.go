@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importedTitles returns the imported tasks by title, without the suffix that keeps titles unique
+func importedTitles(report tasksImportReport, suffix string) map[string]map[string]any {
+	tasks := map[string]map[string]any{}
+	for _, created := range report.Created {
+		title, _, _ := strings.Cut(fmt.Sprint(created.Task["title"]), " "+suffix)
+		tasks[title] = created.Task
+	}
+	return tasks
+}
+
+// archiveImportedProject archives the project the tasks were imported into, with its tasks
+func archiveImportedProject(t *testing.T, task map[string]any) {
+	if project, ok := task["project_id"]; ok && project != nil {
+		_, err := postJSON(fmt.Sprint("api/project?id=", project, "&tasks=archive"), nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestImportTodoist(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	data := `{
+		"projects": [{"id": "p1", "name": "Todoist ` + suffix + `"}, {"id": "p0", "name": "Inbox", "inbox_project": true}],
+		"sections": [{"id": "s1", "name": "Weekend"}],
+		"items": [
+			{"id": "1", "project_id": "p1", "content": "Water plants ` + suffix + `", "priority": 4, "labels": ["Home Care"],
+			 "due": {"date": "2030-01-07", "is_recurring": true, "string": "every mon, fri at 9am"}},
+			{"id": "2", "project_id": "p1", "section_id": "s1", "content": "Pay rent ` + suffix + `", "description": "Bank transfer",
+			 "due": {"date": "2030-01-15T10:00:00", "is_recurring": true, "string": "every 15th"}},
+			{"id": "3", "project_id": "p1", "parent_id": "2", "content": "Check balance", "checked": true},
+			{"id": "4", "project_id": "p1", "content": "Renew passport ` + suffix + `", "responsible_uid": "42",
+			 "due": {"date": "2030-03-01", "is_recurring": true, "string": "every 2 years"}},
+			{"id": "5", "project_id": "p1", "content": "Removed", "is_deleted": true}
+		],
+		"notes": [{"item_id": "2", "content": "Landlord changed the account"}]
+	}`
+
+	status, report := importTasks(t, "format=todoist&dry_run=true", []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Created, 3)
+	tasks := importedTitles(report, suffix)
+	if water := tasks["Water plants"]; assert.NotNil(t, water) {
+		assert.Equal(t, "20300107", water["date"])
+		assert.Equal(t, "w 1,5", water["repeat"])
+		assert.Equal(t, "urgent", water["priority"])
+		assert.Equal(t, []any{"home-care"}, water["tags"])
+	}
+	if rent := tasks["Pay rent"]; assert.NotNil(t, rent) {
+		assert.Equal(t, "m 15", rent["repeat"])
+		assert.Equal(t, "Bank transfer", rent["comment"])
+	}
+	if assert.Len(t, report.Unconverted, 1) {
+		assert.Equal(t, "every 2 years", report.Unconverted[0]["rule"])
+	}
+	reasons := []string{}
+	for _, warning := range report.Warnings {
+		reasons = append(reasons, fmt.Sprint(warning["reason"]))
+	}
+	assert.ElementsMatch(t, []string{`section "Weekend" has no equivalent`, "the assignee has no equivalent"}, reasons,
+		"The mapping report is part of the dry run")
+
+	status, report = importTasks(t, "format=todoist", []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	tasks = importedTitles(report, suffix)
+	if rent := tasks["Pay rent"]; assert.NotNil(t, rent) {
+		items := getChecklist(t, fmt.Sprint(rent["id"]))
+		if assert.Len(t, items, 1) {
+			assert.Equal(t, "Check balance", items[0].Text)
+			assert.True(t, items[0].Done)
+		}
+		found := searchTasks(t, "Pay rent "+suffix)
+		if assert.Len(t, found, 1) {
+			assert.Equal(t, float64(1), found[0]["notes_count"])
+		}
+		archiveImportedProject(t, rent)
+	}
+
+	// A dry run skips the items the import skips
+	invalid := `{
+		"projects": [{"id": "p2", "name": "Todoist invalid ` + suffix + `"}],
+		"items": [
+			{"id": "1", "project_id": "p2", "content": "Valid ` + suffix + `"},
+			{"id": "2", "project_id": "p2", "content": "Overlabelled ` + suffix + `", "labels": ["` + strings.Repeat("x", 60) + `"]}
+		]
+	}`
+	status, preview := importTasks(t, "format=todoist&dry_run=true", []byte(invalid))
+	assert.Equal(t, http.StatusOK, status)
+	status, report = importTasks(t, "format=todoist", []byte(invalid))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, preview.Created, 1)
+	assert.Len(t, report.Created, 1)
+	if assert.Len(t, preview.Skipped, 1) {
+		assert.Equal(t, report.Skipped, preview.Skipped)
+	}
+	if valid := importedTitles(report, suffix)["Valid"]; assert.NotNil(t, valid) {
+		archiveImportedProject(t, valid)
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		data := strings.Join([]string{
+			"TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE,DURATION,DURATION_UNIT",
+			"section,Errands,,,,,,,,,,",
+			"task,Buy milk " + suffix + " @store,,1,1,Ann (1),,Jan 10 2030,en,UTC,,",
+			"task,Check the date,,4,2,Ann (1),,,en,UTC,,",
+			"note,Semi-skimmed,,,,Ann (1),,,,,,",
+			"task,Stretch " + suffix + ",,4,1,Ann (1),,every weekday,en,UTC,15,minute",
+			"task,Inventory " + suffix + ",,4,1,Ann (1),,someday,en,UTC,,",
+		}, "\n")
+		var archive bytes.Buffer
+		writer := zip.NewWriter(&archive)
+		file, err := writer.Create("Chores " + suffix + " [2203306141].csv")
+		assert.NoError(t, err)
+		_, err = file.Write([]byte(data))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		for _, input := range [][]byte{[]byte(data), archive.Bytes()} {
+			status, report := importTasks(t, "format=todoist-csv&dry_run=true", input)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Len(t, report.Created, 3)
+			if assert.Len(t, report.Skipped, 1) {
+				assert.Equal(t, float64(2), report.Skipped[0]["row"], "Sections are reported")
+			}
+			tasks := importedTitles(report, suffix)
+			if milk := tasks["Buy milk"]; assert.NotNil(t, milk) {
+				assert.Equal(t, "20300110", milk["date"])
+				assert.Equal(t, "urgent", milk["priority"])
+				assert.Equal(t, []any{"store"}, milk["tags"])
+			}
+			if stretch := tasks["Stretch"]; assert.NotNil(t, stretch) {
+				assert.Equal(t, "w 1,2,3,4,5", stretch["repeat"])
+			}
+			assert.Len(t, report.Warnings, 2, "The duration and the unrecognized date are reported")
+		}
+
+		status, report := importTasks(t, "format=todoist-csv", archive.Bytes())
+		assert.Equal(t, http.StatusOK, status)
+		if milk := importedTitles(report, suffix)["Buy milk"]; assert.NotNil(t, milk) {
+			assert.Len(t, getChecklist(t, fmt.Sprint(milk["id"])), 1)
+			archiveImportedProject(t, milk)
+		}
+	})
+}
+
+func TestImportTrello(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	data := `{
+		"name": "Trello ` + suffix + `",
+		"lists": [{"id": "l1", "name": "To Do"}, {"id": "l2", "name": "Old", "closed": true}],
+		"cards": [
+			{"id": "c1", "name": "Plan trip ` + suffix + `", "desc": "Summer", "idList": "l1", "due": "2030-06-01T09:00:00.000Z",
+			 "labels": [{"name": "Travel", "color": "green"}, {"name": "", "color": "red"}], "idMembers": ["m1"], "badges": {"attachments": 2}},
+			{"id": "c2", "name": "Book hotel ` + suffix + `", "idList": "l1", "dueComplete": true, "labels": []},
+			{"id": "c3", "name": "Archived ` + suffix + `", "idList": "l1", "closed": true},
+			{"id": "c4", "name": "Forgotten ` + suffix + `", "idList": "l2"}
+		],
+		"checklists": [
+			{"idCard": "c1", "pos": 2, "checkItems": [{"name": "Tickets", "state": "incomplete", "pos": 1}]},
+			{"idCard": "c1", "pos": 1, "checkItems": [{"name": "Passport", "state": "complete", "pos": 2}, {"name": "Visa", "state": "incomplete", "pos": 1}]}
+		],
+		"actions": [
+			{"type": "commentCard", "data": {"text": "Prices go up in May", "card": {"id": "c1"}}},
+			{"type": "updateCard", "data": {"card": {"id": "c1"}}}
+		]
+	}`
+
+	status, report := importTasks(t, "format=trello&dry_run=true", []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, report.Created, 2)
+	assert.Len(t, report.Skipped, 2)
+	tasks := importedTitles(report, suffix)
+	if trip := tasks["Plan trip"]; assert.NotNil(t, trip) {
+		assert.Equal(t, "Summer", trip["comment"])
+		assert.ElementsMatch(t, []any{"red", "to-do", "travel"}, trip["tags"])
+		assert.Regexp(t, `^2030060[12]$`, trip["date"], "Due dates are converted to local time")
+	}
+	if hotel := tasks["Book hotel"]; assert.NotNil(t, hotel) {
+		assert.Equal(t, "done", hotel["status"])
+	}
+	assert.Len(t, report.Warnings, 2, "Members and attachments are reported")
+
+	status, report = importTasks(t, "format=trello", []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	if trip := importedTitles(report, suffix)["Plan trip"]; assert.NotNil(t, trip) {
+		var texts []string
+		for _, item := range getChecklist(t, fmt.Sprint(trip["id"])) {
+			texts = append(texts, item.Text)
+		}
+		assert.Equal(t, []string{"Visa", "Passport", "Tickets"}, texts)
+		found := searchTasks(t, "Plan trip "+suffix)
+		if assert.Len(t, found, 1) {
+			assert.Equal(t, float64(1), found[0]["notes_count"])
+		}
+		archiveImportedProject(t, trip)
+	}
+
+	status, _ = importTasks(t, "format=trello", []byte(`{"name": "Not a board"}`))
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// checkResponse reports a failed request or an error returned by the API
+func checkResponse(errs chan<- error, step string, ret map[string]any, err error) bool {
+	if err != nil {
+		errs <- fmt.Errorf("%s: %w", step, err)
+		return false
+	}
+	if ret["error"] != nil {
+		errs <- fmt.Errorf("%s: %v", step, ret["error"])
+		return false
+	}
+	return true
+}
+
+func TestConcurrentClients(t *testing.T) {
+	const clients, rounds = 16, 10
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	now := time.Now().Format(`20060102`)
+
+	// Every client writes, reads and writes inside transactions at the same time as the others;
+	// none of the requests may fail with "database is locked"
+	errs := make(chan error, clients*rounds*5)
+	var wg sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				title := fmt.Sprintf("Load %s %d-%d", suffix, c, i)
+				ret, err := postJSON("api/task", map[string]any{"date": now, "title": title, "repeat": "d 1"}, http.MethodPost)
+				if !checkResponse(errs, "create", ret, err) {
+					continue
+				}
+				id := fmt.Sprint(ret["id"])
+
+				ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+				checkResponse(errs, "done", ret, err)
+
+				ret, err = postJSON("api/tasks?search="+url.QueryEscape(title), nil, http.MethodGet)
+				if checkResponse(errs, "search", ret, err) && ret["tasks"] == nil {
+					errs <- fmt.Errorf("search: no tasks in %v", ret)
+				}
+
+				ret, err = postJSON("api/tasks/batch", map[string]any{
+					"operations": []map[string]any{{"op": "delete", "id": id}},
+				}, http.MethodPost)
+				if checkResponse(errs, "batch", ret, err) && ret["committed"] != true {
+					errs <- fmt.Errorf("batch: not committed: %v", ret)
+				}
+
+				ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+				checkResponse(errs, "purge", ret, err)
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
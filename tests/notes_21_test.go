@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTasks(t *testing.T, search string) []map[string]any {
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape(search), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["tasks"]
+}
+
+func TestNotes(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	id := addTask(t, task{title: "Negotiate the contract", comment: "first draft"})
+
+	for _, v := range []struct {
+		path   string
+		method string
+		values map[string]any
+		status int
+	}{
+		{"api/task/notes?id=" + id, http.MethodPost, map[string]any{"text": " "}, http.StatusBadRequest},
+		{"api/task/notes?id=999999999", http.MethodPost, map[string]any{"text": "call back"}, http.StatusNotFound},
+		{"api/task/notes?note=999999999", http.MethodPut, map[string]any{"text": "call back"}, http.StatusNotFound},
+		{"api/task/notes?note=999999999", http.MethodDelete, nil, http.StatusNotFound},
+	} {
+		resp, _, err := requestWithHeaders(v.path, v.values, v.method, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, v.status, resp.StatusCode, v.method+" "+v.path)
+	}
+
+	var notes []string
+	for _, text := range []string{"Called the supplier", "Waiting for legal " + suffix} {
+		ret, err := postJSON("api/task/notes?id="+id, map[string]any{"text": text}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, text, ret["text"])
+		assert.NotEmpty(t, ret["created_at"])
+		assert.Nil(t, ret["updated_at"])
+		notes = append(notes, fmt.Sprint(ret["id"]))
+	}
+
+	// Notes are covered by search and counted in the task list
+	found := searchTasks(t, "legal "+suffix)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, id, found[0]["id"])
+		assert.Equal(t, float64(2), found[0]["notes_count"])
+	}
+
+	// Editing
+	ret, err := postJSON("api/task/notes?note="+notes[1], map[string]any{"text": "Signed by legal"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Signed by legal", ret["text"])
+	assert.NotEmpty(t, ret["updated_at"])
+	assert.Empty(t, searchTasks(t, "legal "+suffix))
+
+	// Deleting
+	ret, err = postJSON("api/task/notes?note="+notes[0], nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	body, err := requestJSON("api/task/notes?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &list))
+	if assert.Len(t, list["notes"], 1) {
+		assert.Equal(t, "Signed by legal", list["notes"][0]["text"])
+	}
+
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, float64(1), m["notes_count"])
+	assert.Equal(t, "first draft", m["comment"], "Notes leave the comment untouched")
+
+	// Cancelling keeps the task with notes out of the other tests' lists
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "cancelled"))
+}
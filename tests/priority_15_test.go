@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func taskTitles(tasks []map[string]string) []string {
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, task["title"])
+	}
+	return titles
+}
+
+func TestPrioritySort(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	now := time.Now()
+	project := addProject(t, map[string]any{"name": "Sorting " + suffix})
+
+	ret, err := postJSON("api/task", map[string]any{"title": "Bad", "priority": "critical"}, http.MethodPost)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok, "Expected error for an unknown priority")
+
+	ids := map[string]string{}
+	for i, v := range []map[string]any{
+		{"title": "b-low", "priority": "low"},
+		{"title": "C-urgent", "priority": "urgent"},
+		{"title": "a-none"},
+		{"title": "d-high", "priority": "high"},
+	} {
+		v["date"] = now.AddDate(0, 0, 3-i).Format(`20060102`)
+		v["project_id"] = project
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		ids[v["title"].(string)] = fmt.Sprint(ret["id"])
+	}
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, ids["C-urgent"]))
+	assert.Equal(t, int64(4), task.Priority)
+	assert.True(t, task.CreatedAt.Valid)
+
+	list := func(query string) []string {
+		return taskTitles(getProjectTasks(t, project+"&"+query))
+	}
+
+	assert.Equal(t, []string{"d-high", "a-none", "C-urgent", "b-low"}, list("sort=date"))
+	assert.Equal(t, []string{"C-urgent", "d-high", "b-low", "a-none"}, list("sort=priority&order=desc"))
+	assert.Equal(t, []string{"a-none", "b-low", "C-urgent", "d-high"}, list("sort=title"))
+	assert.Equal(t, []string{"d-high", "a-none", "C-urgent", "b-low"}, list("sort=created&order=desc"))
+
+	for _, query := range []string{"sort=size", "order=up"} {
+		resp, _, err := requestWithHeaders("api/tasks?"+query, nil, http.MethodGet, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+
+	// Updating without a priority keeps it
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    ids["d-high"],
+		"date":  now.Format(`20060102`),
+		"title": "d-high",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, ids["d-high"]))
+	assert.Equal(t, int64(3), task.Priority)
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addProject(t *testing.T, values map[string]any) string {
+	ret, err := postJSON("api/project", values, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"], "Unexpected response %v", ret)
+	return fmt.Sprint(ret["id"])
+}
+
+func getProjectTasks(t *testing.T, project string) []map[string]string {
+	body, err := requestJSON("api/tasks?project="+project, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["tasks"]
+}
+
+func TestProjects(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	now := time.Now().Format(`20060102`)
+
+	// Validation
+	for _, v := range []map[string]any{
+		{"name": ""},
+		{"name": "Invalid color " + suffix, "color": "red"},
+		{"name": "Invalid repeat " + suffix, "default_repeat": "ooops"},
+	} {
+		ret, err := postJSON("api/project", v, http.MethodPost)
+		assert.NoError(t, err)
+		_, ok := ret["error"]
+		assert.True(t, ok, "Expected error for project %v", v)
+	}
+
+	ops := addProject(t, map[string]any{"name": "Ops " + suffix, "color": "#ff8800", "default_repeat": "d 7"})
+	team := addProject(t, map[string]any{"name": "Team " + suffix})
+
+	ret, err := postJSON("api/project", map[string]any{"name": "Ops " + suffix}, http.MethodPost)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok, "Expected error for a duplicate name")
+
+	// New tasks inherit the default repeat rule of the project
+	ret, err = postJSON("api/task", map[string]any{"date": now, "title": "Rotate logs", "project_id": ops}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]string
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, ops, task["project_id"])
+	assert.Equal(t, "d 7", task["repeat"])
+
+	ret, err = postJSON("api/task", map[string]any{"date": now, "title": "Lost", "project_id": "999999999"}, http.MethodPost)
+	assert.NoError(t, err)
+	_, ok = ret["error"]
+	assert.True(t, ok, "Expected error for a missing project")
+
+	// Project scoping
+	assert.Equal(t, 1, len(getProjectTasks(t, ops)))
+	assert.Equal(t, 0, len(getProjectTasks(t, team)))
+
+	// A project with tasks cannot be deleted without choosing what to do with them
+	resp, _, err := requestWithHeaders("api/project?id="+ops, nil, http.MethodDelete, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Moving the tasks to another project
+	ret, err = postJSON("api/project?id="+ops+"&tasks=move&to="+team, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, "Project deleted successfully", ret["message"])
+	assert.Equal(t, 1, len(getProjectTasks(t, team)))
+
+	resp, _, err = requestWithHeaders("api/project?id="+ops, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Archiving hides the project and its tasks from the default lists
+	ret, err = postJSON("api/project?id="+team+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, "Project archived successfully", ret["message"])
+
+	for _, task := range getTasks(t, "") {
+		assert.NotEqual(t, id, task["id"])
+	}
+	assert.Equal(t, 1, len(getProjectTasks(t, team)))
+
+	listed := func(archived bool) bool {
+		url := "api/projects"
+		if archived {
+			url += "?archived=true"
+		}
+		body, err := requestJSON(url, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string][]map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		for _, p := range m["projects"] {
+			if p["id"] == team {
+				return true
+			}
+		}
+		return false
+	}
+	assert.False(t, listed(false))
+	assert.True(t, listed(true))
+
+	ret, err = postJSON("api/project/restore?id="+team, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "Project restored", ret["message"])
+	assert.True(t, listed(false))
+}
+
+func TestDeleteProjectWithDoneTasks(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := addProject(t, map[string]any{"name": "Finished " + suffix})
+	ret, err := postJSON("api/task", map[string]any{
+		"date": time.Now().Format(`20060102`), "title": "Ship it", "project_id": project,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	assert.Empty(t, getProjectTasks(t, project))
+
+	// Done tasks, and tasks moved to the trash when done, keep the project from being deleted
+	resp, _, err := requestWithHeaders("api/project?id="+project, nil, http.MethodDelete, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _, err = requestWithHeaders("api/project?id="+project, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ret, err = postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+}
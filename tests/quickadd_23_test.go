@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type quickTask struct {
+	Title    string   `json:"title"`
+	Date     string   `json:"date"`
+	Repeat   string   `json:"repeat"`
+	Tags     []string `json:"tags"`
+	Priority string   `json:"priority"`
+}
+
+func quickAdd(t *testing.T, text string, preview bool) (int, string, quickTask) {
+	path := "api/task/quick"
+	if preview {
+		path += "?preview=true"
+	}
+	resp, body, err := requestWithHeaders(path, map[string]any{"text": text}, http.MethodPost, nil)
+	assert.NoError(t, err)
+
+	var ret struct {
+		ID   string    `json:"id"`
+		Task quickTask `json:"task"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &ret))
+	return resp.StatusCode, ret.ID, ret.Task
+}
+
+// nextDay returns the first day, starting today or tomorrow, for which match is true
+func nextDay(fromTomorrow bool, match func(time.Time) bool) string {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if fromTomorrow {
+		day = day.AddDate(0, 0, 1)
+	}
+	for !match(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.Format(`20060102`)
+}
+
+func TestQuickAdd(t *testing.T) {
+	now := time.Now().UTC()
+	inDays := func(days int) string { return now.AddDate(0, 0, days).Format(`20060102`) }
+	weekday := func(d time.Weekday) func(time.Time) bool {
+		return func(day time.Time) bool { return day.Weekday() == d }
+	}
+
+	for _, v := range []struct {
+		text string
+		want quickTask
+	}{
+		{"Pay rent 5th monthly #home !high", quickTask{
+			Title: "Pay rent", Repeat: "m 5", Tags: []string{"home"}, Priority: "high",
+			Date: nextDay(false, func(day time.Time) bool { return day.Day() == 5 }),
+		}},
+		{"Позвонить маме завтра", quickTask{Title: "Позвонить маме", Date: inDays(1)}},
+		{"Submit the report next friday !urgent", quickTask{
+			Title: "Submit the report", Priority: "urgent", Date: nextDay(true, weekday(time.Friday)),
+		}},
+		{"Встреча с командой в пятницу #Работа", quickTask{
+			Title: "Встреча с командой", Tags: []string{"работа"}, Date: nextDay(true, weekday(time.Friday)),
+		}},
+		{"Water the plants every 3 days", quickTask{Title: "Water the plants", Repeat: "d 3"}},
+		{"Gym on mondays", quickTask{Title: "Gym", Repeat: "w 1", Date: nextDay(false, weekday(time.Monday))}},
+		{"Renew the passport in 2 weeks", quickTask{Title: "Renew the passport", Date: inDays(14)}},
+		{"Купить молоко через 3 дня", quickTask{Title: "Купить молоко", Date: inDays(3)}},
+		{"Оплатить интернет каждый месяц 10 числа", quickTask{
+			Title: "Оплатить интернет", Repeat: "m 10",
+			Date: nextDay(false, func(day time.Time) bool { return day.Day() == 10 }),
+		}},
+		{"Отчёт ежедневно !высокий", quickTask{Title: "Отчёт", Repeat: "d 1", Priority: "high"}},
+		{"Call the bank today about the card", quickTask{Title: "Call the bank about the card", Date: inDays(0)}},
+		{"Buy sun cream", quickTask{Title: "Buy sun cream"}},
+	} {
+		status, _, got := quickAdd(t, v.text, true)
+		assert.Equal(t, http.StatusOK, status, v.text)
+		assert.Equal(t, v.want, got, v.text)
+	}
+
+	status, _, _ := quickAdd(t, "tomorrow #home !high", true)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Creating the task
+	status, id, parsed := quickAdd(t, "Поздравить бабушку послезавтра", false)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, quickTask{Title: "Поздравить бабушку", Date: inDays(2)}, parsed)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "Поздравить бабушку", task["title"])
+	assert.Equal(t, inDays(2), task["date"], fmt.Sprint(task))
+}
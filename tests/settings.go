@@ -1,6 +1,9 @@
 package tests
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 // Port - the port on which the application runs.
 var Port = 7540
@@ -21,6 +24,18 @@ var FullNextDate = true
 // Search - flag that enables or disables search functionality.
 var Search = true
 
+// DeleteOnDone - flag that must be enabled when the application runs with TODO_DONE_MODE=delete,
+// which moves completed one-off tasks to the trash instead of keeping them as done.
+var DeleteOnDone = os.Getenv("TODO_DONE_MODE") == "delete"
+
+// TodoTxtFile - todo.txt file the application syncs with, set through TODO_TODOTXT_FILE.
+// The sync tests are skipped when it is empty.
+var TodoTxtFile = os.Getenv("TODO_TODOTXT_FILE")
+
+// VaultDir - directory of Markdown files the application syncs with, set through TODO_VAULT_DIR.
+// The vault sync tests are skipped when it is empty.
+var VaultDir = os.Getenv("TODO_VAULT_DIR")
+
 // Token - authorization token that can be set via the TOKEN environment variable.
 var Token = func() string {
 	if envToken := os.Getenv("TOKEN"); envToken != "" {
@@ -28,3 +43,22 @@ var Token = func() string {
 	}
 	return ""
 }()
+
+// BackupDir - directory the application stores backups in, set through TODO_BACKUP_DIR.
+// The stored backup tests only check that storing is refused when it is empty.
+var BackupDir = os.Getenv("TODO_BACKUP_DIR")
+
+// BackupKeep - number of stored backups the application keeps, set through TODO_BACKUP_KEEP.
+var BackupKeep = func() int {
+	if keep, err := strconv.Atoi(os.Getenv("TODO_BACKUP_KEEP")); err == nil {
+		return keep
+	}
+	return 7
+}()
+
+// BackupEncrypted and BackupCompressed - flags that must match TODO_BACKUP_KEY and TODO_BACKUP_COMPRESS,
+// which decide the format of backups.
+var (
+	BackupEncrypted  = os.Getenv("TODO_BACKUP_KEY") != ""
+	BackupCompressed = os.Getenv("TODO_BACKUP_COMPRESS") == "true"
+)
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setStatus(t *testing.T, id, status string) int {
+	resp, _, err := requestWithHeaders("api/task/status?id="+id, map[string]any{"status": status}, http.MethodPost, nil)
+	assert.NoError(t, err)
+	return resp.StatusCode
+}
+
+func taskStatus(t *testing.T, id string) string {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	status, _ := m["status"].(string)
+	return status
+}
+
+func listedWithStatus(t *testing.T, id, status string) bool {
+	path := "api/tasks?search=migration"
+	if status != "" {
+		path += "&status=" + status
+	}
+	body, err := requestJSON(path, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	for _, task := range m["tasks"] {
+		if task["id"] == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatus(t *testing.T) {
+	id := addTask(t, task{title: "Write the migration guide"})
+	assert.Equal(t, "todo", taskStatus(t, id))
+
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "in_progress"))
+	assert.Equal(t, "in_progress", taskStatus(t, id))
+	assert.True(t, listedWithStatus(t, id, ""))
+
+	assert.Equal(t, http.StatusConflict, setStatus(t, id, "in_progress"))
+	assert.Equal(t, http.StatusBadRequest, setStatus(t, id, "paused"))
+	assert.Equal(t, http.StatusNotFound, setStatus(t, "999999999", "cancelled"))
+
+	// Cancelled tasks are hidden from the default list but can be filtered
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "cancelled"))
+	assert.False(t, listedWithStatus(t, id, ""))
+	assert.True(t, listedWithStatus(t, id, "cancelled"))
+	assert.True(t, listedWithStatus(t, id, "all"))
+	assert.Equal(t, http.StatusConflict, setStatus(t, id, "in_progress"), "Closed tasks can only be reopened")
+
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "todo"))
+	assert.True(t, listedWithStatus(t, id, "todo,in_progress"))
+
+	resp, _, err := requestWithHeaders("api/tasks?status=paused", nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Completing through the status endpoint
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "done"))
+	if !DeleteOnDone {
+		assert.Equal(t, "done", taskStatus(t, id))
+		assert.False(t, listedWithStatus(t, id, ""))
+		assert.True(t, listedWithStatus(t, id, "done"))
+		assert.Equal(t, http.StatusConflict, setStatus(t, id, "done"))
+
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], "A done task cannot be completed again")
+	}
+
+	// Repeating tasks start over as todo
+	id = addTask(t, task{title: "Water the plants", repeat: "d 2"})
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "in_progress"))
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	assert.Equal(t, "todo", taskStatus(t, id))
+}
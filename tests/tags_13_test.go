@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedTask struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+func getTaggedTasks(t *testing.T, query string) []taggedTask {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]taggedTask
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["tasks"]
+}
+
+func TestTags(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now().Format(`20060102`)
+	add := func(title string, tags []string) string {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":  now,
+			"title": title,
+			"tags":  tags,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+		id, _ := ret["id"].(string)
+		return id
+	}
+
+	report := add("Write the report", []string{"#Work", "urgent"})
+	add("Fix the sink", []string{"home", "urgent"})
+	add("Read a book", []string{"home"})
+
+	// Tags are normalized and returned with the task
+	body, err := requestJSON("api/task?id="+report, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task taggedTask
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, []string{"urgent", "work"}, task.Tags)
+
+	// OR semantics by default, AND on request
+	assert.Equal(t, 3, len(getTaggedTasks(t, "tag=work&tag=home&tag_mode=or")))
+	assert.Equal(t, 3, len(getTaggedTasks(t, "tag=work,home")))
+	assert.Equal(t, 2, len(getTaggedTasks(t, "tag=urgent")))
+	and := getTaggedTasks(t, "tag=home&tag=urgent&tag_mode=and")
+	assert.Equal(t, 1, len(and))
+	if len(and) == 1 {
+		assert.Equal(t, "Fix the sink", and[0].Title)
+	}
+
+	ret, err := postJSON("api/tasks?tag=two%20words", nil, http.MethodGet)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok)
+
+	// Updating without tags keeps them, an empty list removes them
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    report,
+		"date":  now,
+		"title": "Write the final report",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task updated successfully", ret["message"])
+	assert.Equal(t, 1, len(getTaggedTasks(t, "tag=work")))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    report,
+		"date":  now,
+		"title": "Write the final report",
+		"tags":  []string{},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task updated successfully", ret["message"])
+	assert.Equal(t, 0, len(getTaggedTasks(t, "tag=work")))
+
+	// Usage counts
+	body, err = requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var tags map[string][]struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &tags))
+	counts := map[string]int{}
+	for _, tag := range tags["tags"] {
+		if tag.Count > 0 {
+			counts[tag.Name] = tag.Count
+		}
+	}
+	assert.Equal(t, map[string]int{"home": 2, "urgent": 1}, counts)
+}
+
+func TestTagsRollback(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	now := time.Now().Format(`20060102`)
+	failing := "broken-" + suffix
+	restore := failInserts(t, "tags", fmt.Sprintf("NEW.name = '%s'", failing))
+	defer restore()
+
+	// A task whose tags cannot be saved is not created
+	title := "Tagged " + suffix
+	ret, err := postJSON("api/task", map[string]any{"date": now, "title": title, "tags": []string{failing}}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+	assert.Empty(t, searchTasks(t, title), "No task is left behind without its tags")
+
+	// An update whose tags cannot be saved leaves the task unchanged
+	ret, err = postJSON("api/task", map[string]any{"date": now, "title": title, "tags": []string{"kept"}}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task", map[string]any{"id": id, "date": now, "title": "Renamed " + suffix, "tags": []string{failing}}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task taggedTask
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, title, task.Title)
+	assert.Equal(t, []string{"kept"}, task.Tags)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	_, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
@@ -20,6 +20,22 @@ func notFoundTask(t *testing.T, id string) {
 	assert.True(t, ok)
 }
 
+// doneTask checks that a completed one-off task is kept as done or, with DeleteOnDone, is gone
+func doneTask(t *testing.T, id string) {
+	if DeleteOnDone {
+		notFoundTask(t, id)
+		return
+	}
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", m["status"])
+}
+
 func TestDone(t *testing.T) {
 	db := openDB(t)
 	defer db.Close()
@@ -35,7 +51,7 @@ func TestDone(t *testing.T) {
 
 	expected := map[string]any{"message": "Task marked as done"}
 	assert.Equal(t, expected, ret) // Check that the return message matches the expected one
-	notFoundTask(t, id)
+	doneTask(t, id)
 
 	id = addTask(t, task{
 		title:  "Check /api/task/done functionality",
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := addProject(t, map[string]any{"name": "Sprints " + suffix})
+	template := map[string]any{
+		"name":        "Sprint review " + suffix,
+		"title":       "Sprint {{sprint}} review on {{date}}",
+		"comment":     "Planned on {{ anchor }}",
+		"priority":    "high",
+		"project_id":  project,
+		"date_offset": 3,
+		"tags":        []string{"sprint-{{sprint}}", "review"},
+		"checklist":   []string{"Collect the demos of sprint {{sprint}}", "Book a room"},
+	}
+
+	ret, err := postJSON("api/templates", template, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+
+	for _, v := range []map[string]any{
+		{"name": template["name"], "title": "Duplicate"},
+		{"name": "No title " + suffix},
+		{"name": "Bad repeat " + suffix, "title": "Bad", "repeat": "x 1"},
+		{"name": "Empty item " + suffix, "title": "Bad", "checklist": []string{" "}},
+	} {
+		ret, err := postJSON("api/templates", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], v)
+	}
+
+	body, err := requestJSON("api/templates/"+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var saved map[string]any
+	assert.NoError(t, json.Unmarshal(body, &saved))
+	assert.Equal(t, template["title"], saved["title"])
+	assert.Equal(t, []any{"review", "sprint-{{sprint}}"}, saved["tags"])
+
+	// Instantiation needs a value for every placeholder
+	resp, _, err := requestWithHeaders("api/templates/"+id+"/instantiate", map[string]any{}, http.MethodPost, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Empty(t, getProjectTasks(t, project), "Nothing is created when a placeholder is missing")
+
+	resp, _, err = requestWithHeaders("api/templates/999999999/instantiate", nil, http.MethodPost, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// A checklist item that cannot be saved leaves no task behind
+	restore := failInserts(t, "checklist_items", "NEW.text = 'Collect the demos of sprint 41'")
+	ret, err = postJSON("api/templates/"+id+"/instantiate", map[string]any{
+		"values": map[string]string{"sprint": "41"},
+	}, http.MethodPost)
+	restore()
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+	assert.Empty(t, getProjectTasks(t, project), "Nothing is created when a checklist item fails")
+
+	anchor := time.Now().AddDate(0, 0, 10)
+	date := anchor.AddDate(0, 0, 3)
+	ret, err = postJSON("api/templates/"+id+"/instantiate", map[string]any{
+		"anchor": anchor.Format("20060102"),
+		"values": map[string]string{"sprint": "42"},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	taskID := fmt.Sprint(ret["id"])
+
+	body, err = requestJSON("api/task?id="+taskID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "Sprint 42 review on "+date.Format("02.01.2006"), task["title"])
+	assert.Equal(t, "Planned on "+anchor.Format("02.01.2006"), task["comment"])
+	assert.Equal(t, date.Format("20060102"), task["date"])
+	assert.Equal(t, "high", task["priority"])
+	assert.Equal(t, project, task["project_id"])
+	assert.Equal(t, []any{"review", "sprint-42"}, task["tags"])
+	assert.Equal(t, []string{"Collect the demos of sprint 42", "Book a room"}, checklistTexts(getChecklist(t, taskID)))
+
+	// Replacing and deleting
+	template["title"] = "Retro {{sprint}}"
+	ret, err = postJSON("api/templates/"+id, template, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	ret, err = postJSON("api/templates/"+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	resp, _, err = requestWithHeaders("api/templates/"+id, nil, http.MethodGet, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Archiving keeps the task with tags out of the other tests' lists
+	ret, err = postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+}
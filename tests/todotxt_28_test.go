@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor polls a condition until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return assert.Fail(t, "condition not met within "+timeout.String())
+}
+
+// todoTxtLine returns the line of the synced file containing text
+func todoTxtLine(text string) string {
+	data, _ := os.ReadFile(TodoTxtFile)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Contains(line, text) {
+			return line
+		}
+	}
+	return ""
+}
+
+// editTodoTxt replaces the synced file the way editors do, so that the application never reads it half-written
+func editTodoTxt(t *testing.T, edit func(lines []string) []string) {
+	data, err := os.ReadFile(TodoTxtFile)
+	assert.NoError(t, err)
+	lines := edit(strings.Split(strings.TrimRight(string(data), "\n"), "\n"))
+
+	temp := filepath.Join(filepath.Dir(TodoTxtFile), "todo.txt.edit")
+	assert.NoError(t, os.WriteFile(temp, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+	assert.NoError(t, os.Rename(temp, TodoTxtFile))
+}
+
+func TestTodoTxt(t *testing.T) {
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := addProject(t, map[string]any{"name": "todo.txt " + suffix})
+
+	data := strings.Join([]string{
+		"(A) 2030-01-01 Call mom " + suffix + " +Family @phone due:2030-01-10 rec:+1w",
+		"Pay rent " + suffix + " due:2030-01-05 rec:1m https://bank.example",
+		"(D) Renew passport " + suffix + " due:2030-03-01 rec:2y",
+		"x 2030-01-02 2030-01-01 Buy milk " + suffix + " @store due:2030-01-02 pri:B",
+		"",
+		"Broken " + suffix + " due:tomorrow",
+	}, "\n")
+	status, report := importTasks(t, "format=todotxt&project="+project, []byte(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, report.Created, 4)
+	if assert.Len(t, report.Skipped, 1) {
+		assert.Equal(t, float64(6), report.Skipped[0]["row"])
+	}
+
+	tasks := map[string]map[string]any{}
+	for _, task := range getFieldTasks(t, "project="+project+"&status=all") {
+		title, _, _ := strings.Cut(fmt.Sprint(task["title"]), " "+suffix)
+		tasks[title] = task
+	}
+	if call := tasks["Call mom"]; assert.NotNil(t, call) {
+		assert.Equal(t, "20300110", call["date"])
+		assert.Equal(t, "d 7", call["repeat"])
+		assert.Equal(t, "urgent", call["priority"])
+		assert.Equal(t, []any{"@phone", "family"}, call["tags"])
+	}
+	if rent := tasks["Pay rent"]; assert.NotNil(t, rent) {
+		assert.Equal(t, "Pay rent "+suffix+" https://bank.example", rent["title"], "Links stay in the title")
+		assert.Equal(t, "m 5", rent["repeat"])
+	}
+	if passport := tasks["Renew passport"]; assert.NotNil(t, passport) {
+		assert.Equal(t, "", passport["repeat"], "Every two years has no equivalent and is dropped")
+		assert.Equal(t, "low", passport["priority"])
+	}
+	if milk := tasks["Buy milk"]; assert.NotNil(t, milk) {
+		assert.Equal(t, "done", milk["status"])
+		assert.Equal(t, "high", milk["priority"])
+	}
+
+	resp, body := exportTasks(t, "todotxt")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "todo.txt")
+	if call := tasks["Call mom"]; call != nil {
+		created := fmt.Sprint(call["created_at"])[:10]
+		assert.Contains(t, string(body),
+			"(A) "+created+" Call mom "+suffix+" @phone +family due:2030-01-10 rec:+1w tid:"+fmt.Sprint(call["id"])+"\n")
+	}
+	assert.Contains(t, string(body), " Renew passport "+suffix+" due:2030-03-01 tid:")
+	assert.Regexp(t, `x 2030-01-02 \d{4}-\d\d-\d\d Buy milk `+suffix+` @store due:2030-01-02 pri:B tid:\d+`, string(body))
+
+	// Importing the export again gives the same lines
+	status, report = importTasks(t, "format=todotxt&dry_run=true", body)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, report.Skipped)
+
+	_, err := postJSON("api/project?id="+project+"&tasks=archive", nil, http.MethodDelete)
+	assert.NoError(t, err)
+
+	t.Run("Sync", func(t *testing.T) {
+		if TodoTxtFile == "" {
+			t.Skip("TODO_TODOTXT_FILE is not set")
+		}
+		waitFor(t, 10*time.Second, func() bool {
+			_, err := os.Stat(TodoTxtFile)
+			return err == nil
+		})
+
+		// A new line becomes a task
+		title := "Water plants " + suffix
+		editTodoTxt(t, func(lines []string) []string {
+			return append(lines, "(B) "+title+" @home due:2030-02-01 rec:1w")
+		})
+		var id string
+		if !waitFor(t, 10*time.Second, func() bool {
+			_, id, _ = strings.Cut(todoTxtLine(title), " tid:")
+			return id != ""
+		}) {
+			return
+		}
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]any
+		assert.NoError(t, json.Unmarshal(body, &task))
+		assert.Equal(t, title, task["title"])
+		assert.Equal(t, "20300201", task["date"])
+		assert.Equal(t, "d 7", task["repeat"])
+		assert.Equal(t, "high", task["priority"])
+		assert.Equal(t, []any{"@home"}, task["tags"])
+
+		// Changes in the scheduler are written to the file
+		_, err = postJSON("api/task", map[string]any{
+			"id": id, "date": "20300201", "title": title + " twice", "comment": "", "repeat": "d 7",
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		waitFor(t, 10*time.Second, func() bool {
+			return strings.Contains(todoTxtLine("tid:"+id), title+" twice @home")
+		})
+
+		// Completing the task in the file moves it to the next date
+		editTodoTxt(t, func(lines []string) []string {
+			for i, line := range lines {
+				if strings.HasSuffix(line, " tid:"+id) {
+					lines[i] = "x " + line
+				}
+			}
+			return lines
+		})
+		waitFor(t, 10*time.Second, func() bool {
+			return strings.Contains(todoTxtLine("tid:"+id), "due:2030-02-08")
+		})
+		assert.Regexp(t, `^\(B\) \d{4}-\d\d-\d\d `+title+` twice @home`, todoTxtLine("tid:"+id))
+
+		// A file read without any lines, e.g. while it is being saved, deletes nothing and is written again
+		editTodoTxt(t, func(lines []string) []string { return nil })
+		waitFor(t, 10*time.Second, func() bool {
+			return todoTxtLine("tid:"+id) != ""
+		})
+		resp, _, err := requestWithHeaders("api/task?id="+id, nil, http.MethodGet, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Removing the line moves the task to the trash
+		editTodoTxt(t, func(lines []string) []string {
+			var kept []string
+			for _, line := range lines {
+				if !strings.HasSuffix(line, " tid:"+id) {
+					kept = append(kept, line)
+				}
+			}
+			return kept
+		})
+		waitFor(t, 10*time.Second, func() bool {
+			resp, _, err := requestWithHeaders("api/task?id="+id, nil, http.MethodGet, nil)
+			return err == nil && resp.StatusCode == http.StatusNotFound
+		})
+
+		// Purge the task so that its tags do not show up in other tests' trash listings
+		_, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTrash(t *testing.T) []map[string]string {
+	body, err := requestJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["tasks"]
+}
+
+func inTrash(t *testing.T, id string) bool {
+	for _, task := range getTrash(t) {
+		if task["id"] == id {
+			assert.NotEmpty(t, task["deleted_at"])
+			return true
+		}
+	}
+	return false
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		date:  time.Now().Format(`20060102`),
+		title: "Renew the passport",
+	})
+
+	// A deleted task disappears from the list but stays in the database
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task deleted successfully", ret["message"])
+	notFoundTask(t, id)
+	assert.True(t, inTrash(t, id))
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.True(t, task.DeletedAt.Valid)
+
+	// A restored task is available again
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task restored", ret["message"])
+	assert.False(t, inTrash(t, id))
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "Renew the passport", m["title"])
+
+	// Restoring an active task is an error
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	_, ok := ret["error"]
+	assert.True(t, ok)
+
+	// A completed one-off task goes to the trash (or is kept as done until deleted) and can be purged permanently
+	_, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	if !DeleteOnDone {
+		assert.False(t, inTrash(t, id))
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+	assert.True(t, inTrash(t, id))
+
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, "Task deleted permanently", ret["message"])
+	assert.False(t, inTrash(t, id))
+
+	var count int
+	err = db.Get(&count, `SELECT count(*) FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
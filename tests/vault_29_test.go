@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vaultLine returns the line of the synced Markdown files containing text
+func vaultLine(text string) string {
+	var found string
+	_ = filepath.WalkDir(VaultDir, func(path string, _ os.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		data, _ := os.ReadFile(path)
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.Contains(line, text) {
+				found = line
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// editVaultFile replaces a Markdown file the way editors do, so that the application never reads it half-written
+func editVaultFile(t *testing.T, path string, edit func(data string) string) {
+	data, _ := os.ReadFile(path)
+	temp := filepath.Join(filepath.Dir(path), ".edit")
+	assert.NoError(t, os.WriteFile(temp, []byte(edit(string(data))), 0o644))
+	assert.NoError(t, os.Rename(temp, path))
+}
+
+func TestVaultSync(t *testing.T) {
+	if VaultDir == "" {
+		t.Skip("TODO_VAULT_DIR is not set")
+	}
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	note := filepath.Join(VaultDir, "Garden "+suffix+".md")
+	assert.NoError(t, os.MkdirAll(VaultDir, 0o755))
+
+	editVaultFile(t, note, func(string) string {
+		return strings.Join([]string{
+			"# Garden",
+			"",
+			"```",
+			"- [ ] Example " + suffix,
+			"```",
+			"- [ ] Water plants " + suffix + " #home 🔼 🔁 every week 📅 2030-02-01",
+			"- [x] Buy soil " + suffix,
+			"  * [ ] Repot cactus " + suffix + " ⏳ 2030-01-30 📅 2030-02-03",
+			"",
+		}, "\n")
+	})
+
+	idPattern := regexp.MustCompile(`🆔 ([a-z0-9]+)$`)
+	var water, cactus string
+	if !waitFor(t, 10*time.Second, func() bool {
+		water, cactus = vaultLine("Water plants "+suffix), vaultLine("Repot cactus "+suffix)
+		return idPattern.MatchString(water) && idPattern.MatchString(cactus)
+	}) {
+		return
+	}
+	assert.Equal(t, "- [ ] Water plants "+suffix+" #home 🔼 🔁 every week 📅 2030-02-01 🆔 "+idPattern.FindStringSubmatch(water)[1], water)
+	assert.Regexp(t, `^  \* \[ \] Repot cactus `+suffix+` ⏳ 2030-01-30 📅 2030-02-03 🆔 [a-z0-9]{6}$`, cactus)
+	assert.Equal(t, "- [x] Buy soil "+suffix, vaultLine("Buy soil "+suffix), "Items closed before the sync are left alone")
+	assert.Equal(t, "- [ ] Example "+suffix, vaultLine("Example "+suffix), "Items in code blocks are left alone")
+
+	tasks := map[string]map[string]any{}
+	for _, task := range getFieldTasks(t, "search="+suffix+"&status=all") {
+		title, _, _ := strings.Cut(fmt.Sprint(task["title"]), " "+suffix)
+		tasks[title] = task
+	}
+	assert.Len(t, tasks, 2)
+	waterTask, cactusTask := tasks["Water plants"], tasks["Repot cactus"]
+	if !assert.NotNil(t, waterTask) || !assert.NotNil(t, cactusTask) {
+		return
+	}
+	assert.Equal(t, "20300201", waterTask["date"])
+	assert.Equal(t, "d 7", waterTask["repeat"])
+	assert.Equal(t, "medium", waterTask["priority"])
+	assert.Equal(t, []any{"home"}, waterTask["tags"])
+	waterID, cactusID := fmt.Sprint(waterTask["id"]), fmt.Sprint(cactusTask["id"])
+
+	// Renaming the file does not create duplicates
+	renamed := filepath.Join(VaultDir, "Plants "+suffix+".md")
+	assert.NoError(t, os.Rename(note, renamed))
+	time.Sleep(2500 * time.Millisecond)
+	assert.Len(t, getFieldTasks(t, "search="+suffix+"&status=all"), 2)
+
+	// Completion in the scheduler is written to the file
+	_, err := postJSON("api/task/done?id="+waterID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	_, err = postJSON("api/task/done?id="+cactusID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	waitFor(t, 10*time.Second, func() bool {
+		return strings.HasPrefix(vaultLine("Water plants "+suffix), "- [ ] ") &&
+			strings.Contains(vaultLine("Water plants "+suffix), "📅 2030-02-08")
+	})
+	if !DeleteOnDone {
+		waitFor(t, 10*time.Second, func() bool {
+			return strings.HasPrefix(vaultLine("Repot cactus "+suffix), "  * [x] ") &&
+				strings.Contains(vaultLine("Repot cactus "+suffix), "✅ "+time.Now().Format("2006-01-02"))
+		})
+	}
+
+	// Edits in the file are applied to the task
+	editVaultFile(t, renamed, func(data string) string {
+		return strings.Replace(data, "Water plants "+suffix, "Water the plants "+suffix, 1)
+	})
+	waitFor(t, 10*time.Second, func() bool {
+		task := getFieldTasks(t, "search=Water+the+plants+"+suffix)
+		return len(task) == 1 && task[0]["id"] == waterID
+	})
+
+	// Tasks created in the scheduler are added to the inbox
+	inboxID := addTask(t, task{date: "20300301", title: "Sow seeds " + suffix})
+	waitFor(t, 10*time.Second, func() bool {
+		data, _ := os.ReadFile(filepath.Join(VaultDir, "Scheduler.md"))
+		return regexp.MustCompile(`(?m)^- \[ \] Sow seeds ` + suffix + ` 📅 2030-03-01 🆔 [a-z0-9]{6}$`).Match(data)
+	})
+
+	// Removing the file moves its tasks to the trash
+	assert.NoError(t, os.Remove(renamed))
+	waitFor(t, 10*time.Second, func() bool {
+		resp, _, err := requestWithHeaders("api/task?id="+waterID, nil, http.MethodGet, nil)
+		return err == nil && resp.StatusCode == http.StatusNotFound
+	})
+
+	// Purge the tasks so that their tags do not show up in other tests' trash listings
+	for _, id := range []string{waterID, cactusID} {
+		_, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+	_, err = postJSON("api/task?id="+inboxID, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}